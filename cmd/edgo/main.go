@@ -5,19 +5,25 @@ import (
 	. "edgo/internal/highlighter"
 	. "edgo/internal/logger"
 	. "edgo/internal/ui"
+	"flag"
 	"fmt"
 	"runtime"
 )
 
 func main() {
+	noSession := flag.Bool("no-session", false, "start without restoring or saving the session of the directory")
+	flag.Parse()
+
 	Log.Start()
 	Conf := GetConfig()
 	HighlighterGlobal.SetTheme(Conf.Theme)
 	editor := Editor{}
 	editor.Config = Conf
+	editor.NoSession = *noSession
 
 	defer func() {
 		if r := recover(); r != nil {
+			editor.WriteSwaps()
 			editor.Exit()
 			errMsg := fmt.Sprintf("Recovered from panic. Error: %v\n", r)
 			stackTrace := make([]byte, 4096)
@@ -26,5 +32,5 @@ func main() {
 		}
 	}()
 
-	editor.Start()
+	editor.Start(flag.Args())
 }
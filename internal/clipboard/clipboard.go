@@ -0,0 +1,134 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	system "github.com/atotto/clipboard"
+	"io"
+	"os"
+	"strings"
+)
+
+/*
+	Copied text goes to a backend and into the history ring of the editor.
+	System backend runs pbcopy, xclip, xsel or wl-copy. OSC 52 backend asks the terminal to set its clipboard,
+	it works over ssh and in headless tmux, but can not read, so paste takes the latest text of the history.
+	Auto mode takes osc52 over ssh or if there are no clipboard utilities, the system one otherwise,
+	falling back to osc52 if the utility fails, like xclip without display.
+*/
+
+const (
+	Auto     = "auto"
+	System   = "system"
+	OSC52    = "osc52"
+	Internal = "internal" // history of the editor only
+)
+
+const MaxHistory = 50
+
+const maxOSC52 = 1 << 20 // terminals drop longer sequences
+
+var ErrNoRead = errors.New("clipboard can not be read")
+
+type Backend interface {
+	Read() (string, error)
+	Write(text string) error
+}
+
+type systemBackend struct{}
+
+func (systemBackend) Read() (string, error)   { return system.ReadAll() }
+func (systemBackend) Write(text string) error { return system.WriteAll(text) }
+
+// osc52Backend writes the escape sequence to the terminal, out is the tty if nil
+type osc52Backend struct {
+	out  io.Writer
+	tmux bool // sequence is passed through tmux to the outer terminal
+}
+
+func (osc52Backend) Read() (string, error) { return "", ErrNoRead }
+
+func (b osc52Backend) Write(text string) error {
+	sequence := Sequence(text, b.tmux)
+	if len(sequence) > maxOSC52 { return errors.New("text is too long for osc 52") }
+	if b.out != nil { _, err := io.WriteString(b.out, sequence); return err }
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil { _, err = io.WriteString(os.Stdout, sequence); return err }
+	defer tty.Close()
+	_, err = io.WriteString(tty, sequence)
+	return err
+}
+
+type internalBackend struct{}
+
+func (internalBackend) Read() (string, error)   { return "", ErrNoRead }
+func (internalBackend) Write(text string) error { return nil }
+
+// Sequence is OSC 52 setting the clipboard to text, wrapped for tmux passthrough if needed
+func Sequence(text string, tmux bool) string {
+	sequence := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if tmux { return "\x1bPtmux;\x1b" + sequence + "\x1b\\" }
+	return sequence
+}
+
+// fallbackBackend writes to the second backend if the first one fails
+type fallbackBackend struct { first, second Backend }
+
+func (b fallbackBackend) Read() (string, error) { return b.first.Read() }
+
+func (b fallbackBackend) Write(text string) error {
+	if err := b.first.Write(text); err == nil { return nil }
+	return b.second.Write(text)
+}
+
+// NewBackend returns backend of the mode, auto mode is resolved by the environment
+func NewBackend(mode string) Backend {
+	osc52 := osc52Backend{tmux: os.Getenv("TMUX") != ""}
+	switch mode {
+	case System: return systemBackend{}
+	case OSC52: return osc52
+	case Internal: return internalBackend{}
+	}
+
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" || system.Unsupported { return osc52 }
+	return fallbackBackend{systemBackend{}, osc52}
+}
+
+// Clipboard is a backend with history of copied texts
+type Clipboard struct {
+	Backend Backend
+	History []string // the latest first
+}
+
+func New(mode string) *Clipboard {
+	return &Clipboard{Backend: NewBackend(mode)}
+}
+
+// Copy writes text to the backend, the text is kept in history even if writing failed
+func (c *Clipboard) Copy(text string) error {
+	c.remember(text)
+	return c.Backend.Write(text)
+}
+
+// Paste reads the backend, text copied outside of the editor is added to history,
+// the latest text of history is returned if the backend can not be read
+func (c *Clipboard) Paste() string {
+	text, err := c.Backend.Read()
+	if err != nil || text == "" && len(c.History) > 0 {
+		if len(c.History) == 0 { return "" }
+		return c.History[0]
+	}
+	c.remember(text)
+	return text
+}
+
+// moves text to the top of history
+func (c *Clipboard) remember(text string) {
+	if strings.TrimSpace(text) == "" { return }
+	history := []string{text}
+	for _, old := range c.History {
+		if old != text && len(history) < MaxHistory { history = append(history, old) }
+	}
+	c.History = history
+}
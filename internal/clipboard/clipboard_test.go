@@ -0,0 +1,56 @@
+package clipboard
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeBackend struct {
+	text string
+	err  error
+}
+
+func (b *fakeBackend) Read() (string, error)   { return b.text, b.err }
+func (b *fakeBackend) Write(text string) error { b.text = text; return b.err }
+
+func TestSequence(t *testing.T) {
+	if s := Sequence("hello", false); s != "\x1b]52;c;aGVsbG8=\a" { t.Errorf("unexpected %q", s) }
+	if s := Sequence("hello", true); s != "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8=\a\x1b\\" { t.Errorf("unexpected tmux %q", s) }
+}
+
+func TestOSC52Write(t *testing.T) {
+	var out strings.Builder
+	c := &Clipboard{Backend: osc52Backend{out: &out}}
+	if err := c.Copy("text"); err != nil { t.Fatal(err) }
+	if out.String() != Sequence("text", false) { t.Errorf("unexpected %q", out.String()) }
+	if c.Paste() != "text" { t.Errorf("paste should take the copied text from history") }
+}
+
+func TestHistory(t *testing.T) {
+	c := &Clipboard{Backend: &fakeBackend{}}
+	c.Copy("a"); c.Copy("b"); c.Copy("a"); c.Copy("  ")
+	if fmt.Sprint(c.History) != "[a b]" { t.Errorf("unexpected history %v", c.History) }
+
+	for i := 0; i < MaxHistory+5; i++ { c.Copy(fmt.Sprint(i)) }
+	if len(c.History) != MaxHistory || c.History[0] != fmt.Sprint(MaxHistory+4) { t.Errorf("history should be limited, got %d", len(c.History)) }
+}
+
+func TestPasteFallback(t *testing.T) {
+	backend := &fakeBackend{}
+	c := &Clipboard{Backend: backend}
+	c.Copy("copied")
+
+	backend.text = "outside"
+	if c.Paste() != "outside" || c.History[0] != "outside" { t.Errorf("text copied outside should be pasted and remembered") }
+
+	backend.err = errors.New("no clipboard")
+	c.Copy("again")
+	if c.Paste() != "again" { t.Errorf("failed backend should fall back to history") }
+}
+
+func TestFallbackWrite(t *testing.T) {
+	first, second := &fakeBackend{err: errors.New("no display")}, &fakeBackend{}
+	if err := (fallbackBackend{first, second}).Write("text"); err != nil || second.text != "text" { t.Errorf("failed write should go to the second backend") }
+}
@@ -3,50 +3,95 @@ package config
 import (
 	"gopkg.in/yaml.v3"
 	"os"
+	"path/filepath"
 )
 
 
 type Lang struct {
 	Name     string `yaml:"name,omitempty"`
 	Lsp      string `yaml:"lsp,omitempty"`
-	Comment  string `yaml:"comment,omitempty"`
+	Comment  string `yaml:"comment,omitempty"` // line comment, or block comment pair like "<!-- -->" if there are no line comments
+	BlockComment string `yaml:"blockcomment,omitempty"` // block comment pair like "/* */"
 	TabWidth int    `yaml:"tabwidth,omitempty"`
 	Cmd      string `yaml:"cmd,omitempty"`
 	CmdArgs  string `yaml:"cmdargs,omitempty"`
+	Wrap     bool   `yaml:"wrap,omitempty"` // soft wrap files of the language
 }
 
 
+// thresholds for big files, expensive features are turned off beyond them
+type LargeFile struct {
+	Size            int64 `yaml:"size,omitempty"`            // bytes, bigger files are loaded by chunks in background
+	HighlightLines  int   `yaml:"highlightlines,omitempty"`  // no syntax highlighting for files with more lines
+	IdentifierLines int   `yaml:"identifierlines,omitempty"` // no same identifiers highlighting for files with more lines
+	AutoSaveLines   int   `yaml:"autosavelines,omitempty"`   // files with more lines are saved only explicitly
+}
+
+// autosave policies
+const (
+	AutoSaveOff   = "off"   // only explicit save
+	AutoSaveEdit  = "edit"  // after every edit
+	AutoSaveIdle  = "idle"  // after no edits for idlems
+	AutoSaveFocus = "focus" // when switching to another buffer or quitting
+)
+
+type Save struct {
+	AutoSave string `yaml:"autosave,omitempty"` // off, edit, idle or focus
+	IdleMs   int    `yaml:"idlems,omitempty"`   // delay of idle autosave
+	Backup   bool   `yaml:"backup,omitempty"`   // keep previous content in file~
+}
+
+// soft wrap of long lines
+type Wrap struct {
+	Enabled bool `yaml:"enabled,omitempty"` // for every language
+	Column  int  `yaml:"column,omitempty"`  // wrap at this column, or at the window width if it is narrower or 0
+	Words   bool `yaml:"words,omitempty"`   // break lines at spaces, not inside words
+	Indent  bool `yaml:"indent,omitempty"`  // continuation rows keep indentation of the line
+}
+
 type Config struct {
-	Langs map[string]Lang `yaml:"langs"`
-	Theme string          `yaml:"theme"`
+	Langs     map[string]Lang `yaml:"langs"`
+	Theme     string          `yaml:"theme"`
+	LargeFile LargeFile       `yaml:"largefile"`
+	Save      Save            `yaml:"save"`
+	Wrap      Wrap            `yaml:"wrap"`
+	Vim       bool            `yaml:"vim"` // modal editing
+	Rainbow   bool            `yaml:"rainbow"` // brackets colored by nesting level
+	Clipboard string          `yaml:"clipboard"` // auto (default), system, osc52 or internal
+	Keymap    map[string]map[string]string `yaml:"keymap"` // context -> keys -> command, overrides default bindings
 }
 
 var DefaultConfig = Config { Langs:
 	map[string]Lang{
-		"go":         { Lsp: "gopls", TabWidth: 4, Cmd: "go", CmdArgs: "run" },
+		"go":         { Lsp: "gopls", TabWidth: 4, Cmd: "go", CmdArgs: "run", BlockComment: "/* */" },
 		//"python":     { Lsp: "pylsp", Comment: "#", TabWidth: 4, Cmd: "python3" },
 		"python":     { Lsp: "pyright-langserver --stdio", Comment: "#", TabWidth: 4, Cmd: "python3" },
-		"typescript": { Lsp: "typescript-language-server --stdio", Cmd: "tsx" },
-		"javascript": { Lsp: "typescript-language-server --stdio", Cmd: "tsx" },
-		"html":       { Lsp: "vscode-html-language-server --stdio" },
-		"vue":        { Lsp: "vscode-html-language-server --stdio" },
-		"rust":       { Lsp: "rust-analyzer", TabWidth: 4},
-		"c":          { Lsp: "clangd" },
-		"c++":        { Lsp: "clangd" },
-		"d":          { Lsp: "serve-d", Cmd: "dmd", CmdArgs: "-run"},
-		"java":       { Lsp: "jdtls", TabWidth: 4, Cmd: "java" },
-		"swift":      { Lsp: "xcrun sourcekit-lsp", Cmd: "swift" },
-		"haskell":    { Lsp: "haskell-language-server-wrapper --lsp", Comment: "--" },
+		"typescript": { Lsp: "typescript-language-server --stdio", Cmd: "tsx", BlockComment: "/* */" },
+		"javascript": { Lsp: "typescript-language-server --stdio", Cmd: "tsx", BlockComment: "/* */" },
+		"html":       { Lsp: "vscode-html-language-server --stdio", Comment: "<!-- -->" },
+		"css":        { Comment: "/* */" },
+		"vue":        { Lsp: "vscode-html-language-server --stdio", Comment: "<!-- -->" },
+		"rust":       { Lsp: "rust-analyzer", TabWidth: 4, BlockComment: "/* */" },
+		"c":          { Lsp: "clangd", BlockComment: "/* */" },
+		"c++":        { Lsp: "clangd", BlockComment: "/* */" },
+		"d":          { Lsp: "serve-d", Cmd: "dmd", CmdArgs: "-run", BlockComment: "/* */" },
+		"java":       { Lsp: "jdtls", TabWidth: 4, Cmd: "java", BlockComment: "/* */" },
+		"swift":      { Lsp: "xcrun sourcekit-lsp", Cmd: "swift", BlockComment: "/* */" },
+		"haskell":    { Lsp: "haskell-language-server-wrapper --lsp", Comment: "--", BlockComment: "{- -}" },
 		"zig":        { Lsp: "zls", TabWidth: 4, Cmd: "zig", CmdArgs: "run" },
-		"lua":        { Lsp: "lua-language-server", Cmd: "lua" },
+		"lua":        { Lsp: "lua-language-server", Cmd: "lua", Comment: "--", BlockComment: "--[[ ]]" },
 		"yaml":       { Comment: "#", TabWidth: 4 },
-		"ocaml":      { Lsp: "ocamllsp", },
-		"nim":        { Lsp: "nimlangserver", },
+		"ocaml":      { Lsp: "ocamllsp", Comment: "(* *)" },
+		"nim":        { Lsp: "nimlangserver", Comment: "#", BlockComment: "#[ ]#" },
 		"bash":       { Lsp: "bash-language-server start", Cmd: "bash", Comment: "#", TabWidth: 2 },
 		"shell":       { Lsp: "bash-language-server start", Cmd: "bash", Comment: "#", TabWidth: 2 },
 	},
 }
 
+var DefaultLargeFile = LargeFile{ Size: 1024 * 1024, HighlightLines: 50000, IdentifierLines: 20000, AutoSaveLines: 10000 }
+
+var DefaultSave = Save{ AutoSave: AutoSaveEdit, IdleMs: 1000 }
+
 var DefaultLangConfig = Lang{ Name: "", Lsp: "", Comment: "//", TabWidth: 2 }
 
 func GetConfig() Config {
@@ -60,6 +105,8 @@ func GetConfig() Config {
 	}
 
 	DefaultConfig.Theme = "edgo"
+	DefaultConfig.LargeFile = DefaultLargeFile
+	DefaultConfig.Save = DefaultSave
 
 	conffilename, exists := os.LookupEnv("EDGO_CONF")
 	if !exists { conffilename = "config.yaml" }
@@ -81,5 +128,32 @@ func GetConfig() Config {
 
 	if yamlConfig.Theme != "" { DefaultConfig.Theme = yamlConfig.Theme }
 
+	largeFile := yamlConfig.LargeFile
+	if largeFile.Size != 0 { DefaultConfig.LargeFile.Size = largeFile.Size }
+	if largeFile.HighlightLines != 0 { DefaultConfig.LargeFile.HighlightLines = largeFile.HighlightLines }
+	if largeFile.IdentifierLines != 0 { DefaultConfig.LargeFile.IdentifierLines = largeFile.IdentifierLines }
+	if largeFile.AutoSaveLines != 0 { DefaultConfig.LargeFile.AutoSaveLines = largeFile.AutoSaveLines }
+
+	save := yamlConfig.Save
+	switch save.AutoSave {
+	case AutoSaveOff, AutoSaveEdit, AutoSaveIdle, AutoSaveFocus: DefaultConfig.Save.AutoSave = save.AutoSave
+	}
+	if save.IdleMs > 0 { DefaultConfig.Save.IdleMs = save.IdleMs }
+	DefaultConfig.Save.Backup = save.Backup
+
+	DefaultConfig.Wrap = yamlConfig.Wrap
+	DefaultConfig.Vim = yamlConfig.Vim
+	DefaultConfig.Rainbow = yamlConfig.Rainbow
+	DefaultConfig.Clipboard = yamlConfig.Clipboard
+	DefaultConfig.Keymap = yamlConfig.Keymap
+
 	return DefaultConfig
 }
+
+// StateDir is the directory for data kept between runs, EDGO_STATE or ~/.edgo
+func StateDir() string {
+	if dir, exists := os.LookupEnv("EDGO_STATE"); exists { return dir }
+	home, err := os.UserHomeDir()
+	if err != nil { return ".edgo" }
+	return filepath.Join(home, ".edgo")
+}
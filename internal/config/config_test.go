@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -26,3 +28,67 @@ func TestReadConfig(t *testing.T) {
 		t.Errorf("Go lang lsp should be gopls")
 	}
 }
+
+func TestLargeFileConfig(t *testing.T) {
+	conffile := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(conffile, []byte("largefile:\n  highlightlines: 100\n"), 0644)
+	t.Setenv("EDGO_CONF", conffile)
+
+	conf := GetConfig()
+
+	if conf.LargeFile.HighlightLines != 100 {
+		t.Errorf("highlight lines should be overridden, got %d", conf.LargeFile.HighlightLines)
+	}
+	if conf.LargeFile.Size != DefaultLargeFile.Size || conf.LargeFile.IdentifierLines != DefaultLargeFile.IdentifierLines || conf.LargeFile.AutoSaveLines != DefaultLargeFile.AutoSaveLines {
+		t.Errorf("not specified thresholds should be default, got %+v", conf.LargeFile)
+	}
+}
+
+func TestVimConfig(t *testing.T) {
+	conffile := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(conffile, []byte("vim: true\n"), 0644)
+	t.Setenv("EDGO_CONF", conffile)
+
+	if !GetConfig().Vim { t.Errorf("vim mode should be enabled") }
+}
+
+func TestKeymapConfig(t *testing.T) {
+	conffile := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(conffile, []byte("keymap:\n  editor:\n    \"ctrl+k ctrl+c\": comment\n"), 0644)
+	t.Setenv("EDGO_CONF", conffile)
+
+	if GetConfig().Keymap["editor"]["ctrl+k ctrl+c"] != "comment" { t.Errorf("keymap should be read") }
+}
+
+func TestStateDir(t *testing.T) {
+	t.Setenv("EDGO_STATE", "/tmp/edgo-state")
+	if StateDir() != "/tmp/edgo-state" { t.Errorf("state dir should be taken from EDGO_STATE, got %s", StateDir()) }
+}
+
+func TestSaveConfig(t *testing.T) {
+	conffile := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(conffile, []byte("save:\n  autosave: idle\n  backup: true\n"), 0644)
+	t.Setenv("EDGO_CONF", conffile)
+
+	conf := GetConfig()
+
+	if conf.Save.AutoSave != AutoSaveIdle || !conf.Save.Backup {
+		t.Errorf("save config should be overridden, got %+v", conf.Save)
+	}
+	if conf.Save.IdleMs != DefaultSave.IdleMs {
+		t.Errorf("idle delay should be default, got %d", conf.Save.IdleMs)
+	}
+
+	os.WriteFile(conffile, []byte("save:\n  autosave: sometimes\n"), 0644)
+	if conf := GetConfig(); conf.Save.AutoSave != AutoSaveEdit {
+		t.Errorf("unknown autosave should be default, got %s", conf.Save.AutoSave)
+	}
+}
+
+func TestClipboardConfig(t *testing.T) {
+	conffile := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(conffile, []byte("clipboard: osc52\n"), 0644)
+	t.Setenv("EDGO_CONF", conffile)
+
+	if GetConfig().Clipboard != "osc52" { t.Errorf("clipboard backend should be read") }
+}
@@ -0,0 +1,188 @@
+package editorconfig
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+	EditorConfig (https://editorconfig.org) properties of a file are collected from .editorconfig files
+	in its directory and every parent up to the one with root = true.
+	Nearer files override farther ones, later sections override earlier ones.
+	Keys and values are lowercased, "unset" removes a property.
+*/
+
+const FileName = ".editorconfig"
+
+type Properties map[string]string
+
+type Section struct {
+	Glob       string
+	Properties Properties
+}
+
+type File struct {
+	Root     bool
+	Sections []Section
+}
+
+// Parse reads ini-like .editorconfig content, broken lines are skipped
+func Parse(r io.Reader) (*File, error) {
+	file := &File{}
+	var section *Section
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' { continue }
+
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			file.Sections = append(file.Sections, Section{Glob: line[1 : len(line)-1], Properties: Properties{}})
+			section = &file.Sections[len(file.Sections)-1]
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found { key, value, found = strings.Cut(line, ":") }
+		if !found { continue }
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		if section == nil {
+			if key == "root" { file.Root = value == "true" } // preamble
+			continue
+		}
+		section.Properties[key] = value
+	}
+	return file, scanner.Err()
+}
+
+// Resolve returns properties of the file at path, nothing if there are no .editorconfig files
+func Resolve(path string) Properties {
+	path, err := filepath.Abs(path)
+	if err != nil { return Properties{} }
+
+	// nearest first
+	files, dirs := []*File{}, []string{}
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if f, err := os.Open(filepath.Join(dir, FileName)); err == nil {
+			parsed, err := Parse(f)
+			f.Close()
+			if err == nil {
+				files, dirs = append(files, parsed), append(dirs, dir)
+				if parsed.Root { break }
+			}
+		}
+		if filepath.Dir(dir) == dir { break }
+	}
+
+	properties := Properties{}
+	for i := len(files) - 1; i >= 0; i-- {
+		relative, err := filepath.Rel(dirs[i], path)
+		if err != nil { continue }
+		relative = filepath.ToSlash(relative)
+
+		for _, section := range files[i].Sections {
+			if !Match(section.Glob, relative) { continue }
+			for key, value := range section.Properties { properties[key] = value }
+		}
+	}
+
+	for key, value := range properties {
+		if value == "unset" { delete(properties, key) }
+	}
+	return properties
+}
+
+// Match tells if path relative to the .editorconfig directory matches section glob.
+// Glob without slash matches file name in any directory
+func Match(glob, path string) bool {
+	if strings.HasPrefix(glob, "/") { glob = glob[1:] } else if !strings.Contains(glob, "/") { glob = "**/" + glob }
+	re, err := regexp.Compile("^" + globToRegexp(glob) + "$")
+	if err != nil { return false }
+	return re.MatchString(path)
+}
+
+var numericRange = regexp.MustCompile(`^([+-]?\d+)\.\.([+-]?\d+)$`)
+
+// converts *, **, ?, [chars], [!chars], {a,b} and {num1..num2} to regexp
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case ch == '\\' && i+1 < len(runes):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(runes[i])))
+
+		case ch == '*' && i+1 < len(runes) && runes[i+1] == '*':
+			i++
+			if i+1 < len(runes) && runes[i+1] == '/' { i++; re.WriteString("(?:.*/)?") } else { re.WriteString(".*") }
+
+		case ch == '*': re.WriteString("[^/]*")
+		case ch == '?': re.WriteString("[^/]")
+
+		case ch == '[':
+			end := strings.IndexRune(string(runes[i+1:]), ']')
+			if end < 0 { re.WriteString(`\[`); continue }
+			chars := string(runes[i+1 : i+1+end])
+			i += end + 1
+			if strings.HasPrefix(chars, "!") { chars = "^" + chars[1:] }
+			re.WriteString("[" + strings.ReplaceAll(chars, `\`, `\\`) + "]")
+
+		case ch == '{':
+			end := closingBrace(runes, i)
+			if end < 0 { re.WriteString(`\{`); continue }
+			inner := string(runes[i+1 : end])
+			i = end
+
+			if m := numericRange.FindStringSubmatch(inner); m != nil {
+				from, _ := strconv.Atoi(m[1])
+				to, _ := strconv.Atoi(m[2])
+				if from > to { from, to = to, from }
+				numbers := []string{}
+				for n := from; n <= to && len(numbers) < 10000; n++ { numbers = append(numbers, strconv.Itoa(n)) }
+				re.WriteString("(?:" + strings.Join(numbers, "|") + ")")
+				continue
+			}
+
+			alternatives := splitAlternatives(inner)
+			if len(alternatives) < 2 { re.WriteString(`\{` + globToRegexp(inner) + `\}`); continue }
+			for j, alternative := range alternatives { alternatives[j] = globToRegexp(alternative) }
+			re.WriteString("(?:" + strings.Join(alternatives, "|") + ")")
+
+		default: re.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	return re.String()
+}
+
+func closingBrace(runes []rune, open int) int {
+	depth := 0
+	for i := open; i < len(runes); i++ {
+		if runes[i] == '\\' { i++; continue }
+		if runes[i] == '{' { depth++ }
+		if runes[i] == '}' { depth--; if depth == 0 { return i } }
+	}
+	return -1
+}
+
+// splits by commas outside of nested braces
+func splitAlternatives(s string) []string {
+	parts, depth, start := []string{}, 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\': i++
+		case '{': depth++
+		case '}': depth--
+		case ',': if depth == 0 { parts = append(parts, s[start:i]); start = i + 1 }
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,75 @@
+package editorconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	content := `
+root = true
+# comment
+[*]
+indent_style = Space
+indent_size=4
+
+[*.{go,mod}]
+indent_style = tab
+`
+	file, err := Parse(strings.NewReader(content))
+	if err != nil { t.Fatal(err) }
+	if !file.Root || len(file.Sections) != 2 { t.Fatalf("unexpected %+v", file) }
+	if file.Sections[0].Properties["indent_style"] != "space" || file.Sections[0].Properties["indent_size"] != "4" {
+		t.Error("unexpected properties", file.Sections[0].Properties)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct { glob, path string; match bool }{
+		{"*", "a.go", true},
+		{"*", "dir/a.go", true},
+		{"*.go", "dir/a.go", true},
+		{"*.go", "a.py", false},
+		{"*.{js,ts}", "src/a.ts", true},
+		{"*.{js,ts}", "src/a.go", false},
+		{"/Makefile", "Makefile", true},
+		{"/Makefile", "dir/Makefile", false},
+		{"lib/*.js", "lib/a.js", true},
+		{"lib/*.js", "lib/sub/a.js", false},
+		{"lib/**.js", "lib/sub/a.js", true},
+		{"{package.json,.travis.yml}", "package.json", true},
+		{"file[0-9].txt", "file5.txt", true},
+		{"file[!0-9].txt", "file5.txt", false},
+		{"file{1..3}.txt", "file2.txt", true},
+		{"file{1..3}.txt", "file4.txt", false},
+		{"a?c", "abc", true},
+		{"a?c", "a/c", false},
+	}
+	for _, test := range tests {
+		if Match(test.glob, test.path) != test.match { t.Errorf("%q and %q, expected %v", test.glob, test.path, test.match) }
+	}
+}
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "project", "src")
+	os.MkdirAll(sub, 0755)
+
+	os.WriteFile(filepath.Join(root, FileName), []byte("[*]\ncharset = latin1\n"), 0644)
+	os.WriteFile(filepath.Join(root, "project", FileName), []byte("root = true\n[*]\nindent_style = space\nindent_size = 2\nend_of_line = crlf\n[src/*.go]\nindent_style = tab\n"), 0644)
+	os.WriteFile(filepath.Join(sub, FileName), []byte("[*.go]\nindent_size = 8\nend_of_line = unset\n"), 0644)
+
+	properties := Resolve(filepath.Join(sub, "main.go"))
+
+	expected := Properties{"indent_style": "tab", "indent_size": "8"}
+	if len(properties) != len(expected) { t.Fatalf("unexpected %v", properties) }
+	for key, value := range expected {
+		if properties[key] != value { t.Errorf("%s is %q, expected %q", key, properties[key], value) }
+	}
+
+	if properties := Resolve(filepath.Join(root, "project", "a.txt")); properties["indent_style"] != "space" {
+		t.Error("unexpected", properties)
+	}
+}
@@ -0,0 +1,47 @@
+package fileformat
+
+/*
+	Endings keep line endings of a file with mixed endings. Buffer lines have no \r,
+	on write the lines are matched with the lines as they were read or last written:
+	lines before and after the changed ones keep their endings, changed lines take endings of
+	the replaced lines in order, added lines take the ending of the line before them.
+	Endings are matched after every save, so the changed part is small.
+*/
+type Endings struct {
+	lines []string
+	crlf  []bool // true for crlf, the last line has no ending without final newline
+}
+
+func NewEndings(lines [][]rune, crlf []bool) *Endings {
+	en := &Endings{crlf: crlf}
+	for _, line := range lines { en.lines = append(en.lines, string(line)) }
+	return en
+}
+
+// Match returns endings of lines, true for crlf, first is the ending of lines with no line before
+func (en *Endings) Match(lines [][]rune, first string) []bool {
+	prefix := 0
+	for prefix < len(lines) && prefix < len(en.lines) && string(lines[prefix]) == en.lines[prefix] { prefix++ }
+	suffix := 0
+	for suffix < len(lines)-prefix && suffix < len(en.lines)-prefix && string(lines[len(lines)-1-suffix]) == en.lines[len(en.lines)-1-suffix] { suffix++ }
+
+	crlf := make([]bool, 0, len(lines))
+	add := func(line int) {
+		switch {
+		case line >= 0 && line < len(en.crlf): crlf = append(crlf, en.crlf[line])
+		case len(crlf) > 0: crlf = append(crlf, crlf[len(crlf)-1])
+		default: crlf = append(crlf, first == CRLF)
+		}
+	}
+
+	replaced := len(en.lines) - prefix - suffix
+	for i := 0; i < len(lines); i++ {
+		switch {
+		case i < prefix: add(i)
+		case i >= len(lines)-suffix: add(i - len(lines) + len(en.lines)) // the same line from the end
+		case i-prefix < replaced: add(i)
+		default: add(-1)
+		}
+	}
+	return crlf
+}
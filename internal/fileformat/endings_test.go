@@ -0,0 +1,27 @@
+package fileformat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEndingsMatch(t *testing.T) {
+	read := [][]rune{[]rune("a"), []rune("b"), []rune("c"), []rune("d")}
+	endings := NewEndings(read, []bool{true, false, true, false})
+
+	tests := map[string]struct { lines, expected string }{
+		"same":          {"a b c d", "[true false true false]"},
+		"changed":       {"a B c d", "[true false true false]"},
+		"added":         {"a b x c d", "[true false false true false]"},
+		"added first":   {"x a b c d", "[true true false true false]"},
+		"deleted":       {"a c d", "[true true false]"},
+		"replaced":      {"a x y z d", "[true false true true false]"},
+		"appended":      {"a b c d e", "[true false true false false]"},
+	}
+	for name, test := range tests {
+		lines := [][]rune{}
+		for _, line := range strings.Fields(test.lines) { lines = append(lines, []rune(line)) }
+		if crlf := fmt.Sprint(endings.Match(lines, CRLF)); crlf != test.expected { t.Errorf("%s: got %s, expected %s", name, crlf, test.expected) }
+	}
+}
@@ -0,0 +1,157 @@
+package fileformat
+
+import (
+	"bytes"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+	Format is how the file is stored on disk: encoding, byte order mark, line ending
+	and newline at the end. Buffer content is always utf-8 lines, the format is detected
+	on read and applied on write, so the file is written back the way it was read.
+*/
+
+const (
+	UTF8    = "utf-8"
+	UTF16LE = "utf-16le"
+	UTF16BE = "utf-16be"
+	Latin1  = "latin-1"
+)
+
+var Encodings = []string{UTF8, UTF16LE, UTF16BE, Latin1}
+
+const (
+	LF   = "\n"
+	CRLF = "\r\n"
+)
+
+type Format struct {
+	Encoding     string
+	BOM          bool
+	LineEnding   string
+	FinalNewline bool
+	Mixed        bool // both line endings are found, every line is written with its own, see Endings
+}
+
+// Default is the format of new files
+var Default = Format{Encoding: UTF8, LineEnding: LF, FinalNewline: true}
+
+var boms = map[string][]byte{
+	UTF8:    {0xEF, 0xBB, 0xBF},
+	UTF16LE: {0xFF, 0xFE},
+	UTF16BE: {0xFE, 0xFF},
+}
+
+// Detect guesses encoding by the head of the file, truncated if it is not the whole file.
+// Line ending and final newline are found by Reader
+func Detect(head []byte, truncated bool) Format {
+	format := Default
+	for _, enc := range []string{UTF8, UTF16LE, UTF16BE} {
+		if bytes.HasPrefix(head, boms[enc]) { format.Encoding, format.BOM = enc, true; return format }
+	}
+
+	if isUTF16(head, 1) { format.Encoding = UTF16LE; return format }
+	if isUTF16(head, 0) { format.Encoding = UTF16BE; return format }
+
+	// truncated head may end in the middle of a rune
+	for i := 1; truncated && i < utf8.UTFMax && i <= len(head); i++ {
+		if !utf8.RuneStart(head[len(head)-i]) { continue }
+		if !utf8.FullRune(head[len(head)-i:]) { head = head[:len(head)-i] }
+		break
+	}
+	if !utf8.Valid(head) { format.Encoding = Latin1 }
+	return format
+}
+
+// text without bom looks like utf-16 if mostly every second byte is zero, as in ascii
+func isUTF16(head []byte, zeroAt int) bool {
+	if len(head) < 4 { return false }
+	zeros, pairs := 0, len(head)/2
+	for i := 0; i < pairs; i++ {
+		if head[2*i+zeroAt] == 0 && head[2*i+1-zeroAt] != 0 { zeros++ }
+	}
+	return zeros*10 >= pairs*9
+}
+
+func (f Format) encoding() encoding.Encoding {
+	switch f.Encoding {
+	case UTF16LE: return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case UTF16BE: return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)
+	case Latin1: return charmap.ISO8859_1
+	}
+	return encoding.Nop
+}
+
+func (f Format) String() string {
+	s := f.Encoding
+	if f.BOM { s += " bom" }
+	if f.Mixed { s += " mixed" } else if f.LineEnding == CRLF { s += " crlf" } else { s += " lf" }
+	if !f.FinalNewline { s += " noeol" }
+	return s
+}
+
+// Reader decodes the file to utf-8, skipping bom, and finds its line ending and final newline
+type Reader struct {
+	r      io.Reader
+	format Format
+	prev     byte   // last byte read, line ending may be split between reads
+	crlf, lf bool   // line endings found
+	endings  []bool // true for every line ended by crlf
+}
+
+func NewReader(r io.Reader, format Format) *Reader {
+	if format.BOM { io.CopyN(io.Discard, r, int64(len(boms[format.Encoding]))) }
+	return &Reader{r: transform.NewReader(r, format.encoding().NewDecoder()), format: format}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for _, b := range p[:n] {
+		if b == '\n' {
+			if !r.crlf && !r.lf && r.prev == '\r' { r.format.LineEnding = CRLF } // the first one is used for new lines
+			if r.prev == '\r' { r.crlf = true } else { r.lf = true }
+			r.endings = append(r.endings, r.prev == '\r')
+		}
+		r.prev = b
+	}
+	return n, err
+}
+
+// Format is complete when everything is read, an empty file has no final newline to stay empty on save
+func (r *Reader) Format() Format {
+	format := r.format
+	format.FinalNewline = r.prev == '\n'
+	format.Mixed = r.crlf && r.lf
+	return format
+}
+
+// Endings of lines read so far, true if the line is ended by crlf
+func (r *Reader) Endings() []bool {
+	return r.endings
+}
+
+// Encode joins lines and encodes them as the format says, fails if a char can not be encoded.
+// Lines of mixed format are ended as crlf says, true for crlf, see Endings.Match
+func Encode(lines [][]rune, format Format, crlf []bool) ([]byte, error) {
+	var text strings.Builder
+	for i, line := range lines {
+		text.WriteString(string(line))
+		if i == len(lines)-1 && !format.FinalNewline { break }
+		if format.Mixed && i < len(crlf) {
+			if crlf[i] { text.WriteString(CRLF) } else { text.WriteString(LF) }
+		} else {
+			text.WriteString(format.LineEnding)
+		}
+	}
+
+	data, err := format.encoding().NewEncoder().Bytes([]byte(text.String()))
+	if err != nil { return nil, err }
+	if format.BOM { data = append(append([]byte{}, boms[format.Encoding]...), data...) }
+	return data, nil
+}
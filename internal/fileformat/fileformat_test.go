@@ -0,0 +1,65 @@
+package fileformat
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// reads the file as editor does and writes it back
+func roundTrip(t *testing.T, data []byte) (Format, []byte) {
+	format := Detect(data, false)
+	reader := NewReader(bytes.NewReader(data), format)
+	text, err := io.ReadAll(bufio.NewReader(reader))
+	if err != nil { t.Fatal(err) }
+	format = reader.Format()
+
+	lines := [][]rune{}
+	endings := reader.Endings()
+	for i, line := range strings.Split(strings.TrimSuffix(string(text), "\n"), "\n") {
+		if i < len(endings) && endings[i] { line = strings.TrimSuffix(line, "\r") }
+		lines = append(lines, []rune(line))
+	}
+	written, err := Encode(lines, format, endings)
+	if err != nil { t.Fatal(err) }
+	return format, written
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := map[string]struct { data []byte; format string }{
+		"utf-8":     {[]byte("a\nб\n"), "utf-8 lf"},
+		"crlf":      {[]byte("a\r\nb\r\n"), "utf-8 crlf"},
+		"no eol":    {[]byte("a\nb"), "utf-8 lf noeol"},
+		"bom":       {[]byte("\xEF\xBB\xBFa\r\nb"), "utf-8 bom crlf noeol"},
+		"latin-1":   {[]byte("caf\xE9\n"), "latin-1 lf"},
+		"utf-16le":  {[]byte("\xFF\xFEa\x00\r\x00\n\x00b\x00"), "utf-16le bom crlf noeol"},
+		"utf-16be":  {[]byte("\x00a\x00b\x00c\x00\n"), "utf-16be lf"},
+		"empty":     {[]byte(""), "utf-8 lf noeol"},
+		"newline":   {[]byte("\n"), "utf-8 lf"},
+		"mixed":     {[]byte("a\r\nb\nc\r\n"), "utf-8 mixed"},
+		"mixed lf":  {[]byte("a\nb\r\nc"), "utf-8 mixed noeol"},
+	}
+
+	for name, test := range tests {
+		format, written := roundTrip(t, test.data)
+		if format.String() != test.format { t.Errorf("%s: format %q, expected %q", name, format, test.format) }
+		if !bytes.Equal(written, test.data) { t.Errorf("%s: written %q, expected %q", name, written, test.data) }
+	}
+}
+
+func TestDetectTruncated(t *testing.T) {
+	if format := Detect([]byte("ab\xD0"), true); format.Encoding != UTF8 { t.Error("rune split by head", format) }
+	if format := Detect([]byte("ab\xD0"), false); format.Encoding != Latin1 { t.Error("invalid at the end", format) }
+}
+
+func TestEncodeConverted(t *testing.T) {
+	lines := [][]rune{[]rune("é")}
+	data, _ := Encode(lines, Format{Encoding: UTF16LE, BOM: true, LineEnding: CRLF, FinalNewline: true}, nil)
+	if !bytes.Equal(data, []byte("\xFF\xFE\xE9\x00\r\x00\n\x00")) { t.Errorf("unexpected %q", data) }
+
+	if _, err := Encode([][]rune{[]rune("б")}, Format{Encoding: Latin1, LineEnding: LF}, nil); err == nil {
+		t.Error("latin-1 can not encode cyrillic")
+	}
+}
@@ -0,0 +1,91 @@
+package highlighter
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+	"sort"
+)
+
+/*
+	Brackets are anonymous ( ) [ ] { } tokens of the tree, so brackets inside strings and comments are not seen.
+	A pair is an opening and a closing token among children of one node, level counts pairs around it.
+	Only nodes crossing the asked byte range are walked, siblings are paired whole, so a match may lie outside it.
+*/
+
+var bracketPairs = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+var closingBrackets = map[string]bool{")": true, "]": true, "}": true}
+
+// Bracket is a bracket token by byte offsets, Match is the offset of its pair or -1
+type Bracket struct {
+	Start int
+	Match int
+	Level int
+}
+
+func (b Bracket) IsOpen() bool { return b.Match > b.Start }
+
+// Brackets returns bracket tokens starting in bytes from..to, ordered by offset
+func (h *TreeSitterHighlighter) Brackets(from, to int) []Bracket {
+	if h.tree == nil { return nil }
+	brackets := []Bracket{}
+	walkBrackets(h.tree.RootNode(), 0, from, to, func(b Bracket) {
+		if from <= b.Start && b.Start < to { brackets = append(brackets, b) }
+	})
+	sort.Slice(brackets, func(i, j int) bool { return brackets[i].Start < brackets[j].Start })
+	return brackets
+}
+
+// MatchBracket returns the bracket starting at the offset
+func (h *TreeSitterHighlighter) MatchBracket(offset int) (Bracket, bool) {
+	brackets := h.Brackets(offset, offset+1)
+	if len(brackets) == 0 { return Bracket{}, false }
+	return brackets[0], true
+}
+
+// EnclosingBrackets returns the opening bracket of the innermost pair around the offset,
+// offset right after the opening bracket and at the closing one is inside
+func (h *TreeSitterHighlighter) EnclosingBrackets(offset int) (Bracket, bool) {
+	if h.tree == nil { return Bracket{}, false }
+	inner, found := Bracket{}, false
+	walkBrackets(h.tree.RootNode(), 0, offset, offset+1, func(b Bracket) {
+		if b.IsOpen() && b.Start < offset && offset <= b.Match && (!found || b.Start > inner.Start) { inner, found = b, true }
+	})
+	return inner, found
+}
+
+// visits brackets among children of the node and of those crossing from..to
+func walkBrackets(node *sitter.Node, level, from, to int, visit func(Bracket)) {
+	count := int(node.ChildCount())
+	brackets, closers := []Bracket{}, []string{}
+	open := []int{} // indexes of unclosed brackets
+	levels := make([]int, count)
+
+	for i := 0; i < count; i++ {
+		child := node.Child(i)
+		levels[i] = level + len(open)
+		if child.IsNamed() { continue }
+
+		kind := child.Type()
+		if closer, opening := bracketPairs[kind]; opening {
+			open = append(open, len(brackets))
+			brackets = append(brackets, Bracket{int(child.StartByte()), -1, level + len(open) - 1})
+			closers = append(closers, closer)
+		} else if closingBrackets[kind] {
+			b := Bracket{int(child.StartByte()), -1, level}
+			if n := len(open); n > 0 && closers[open[n-1]] == kind {
+				top := &brackets[open[n-1]]
+				top.Match, b.Match, b.Level = b.Start, top.Start, top.Level
+				open = open[:n-1]
+			}
+			brackets = append(brackets, b)
+			closers = append(closers, "")
+		}
+	}
+	for _, b := range brackets { visit(b) }
+
+	for i := 0; i < count; i++ {
+		child := node.Child(i)
+		if child.ChildCount() == 0 || int(child.EndByte()) < from || int(child.StartByte()) >= to { continue }
+		walkBrackets(child, levels[i], from, to, visit)
+	}
+}
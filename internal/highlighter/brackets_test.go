@@ -0,0 +1,63 @@
+package highlighter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchBracket(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("go")
+	code := "package main\n\nfunc f(a []int) {\n\ts := \"(}\" // {\n\tg(a[0], (1))\n}\n"
+	h.Parse(&code)
+
+	tests := []struct {
+		at, match string
+		offset      int // occurrence of at
+	}{
+		{"f(", ")", 0},
+		{"{\n\ts", "}\n", 0},
+		{"g(", "))", 1},
+		{"[0", "], ", 0},
+		{"(1", "))", 0},
+	}
+	for _, test := range tests {
+		start := strings.Index(code, test.at)
+		if test.at == "f(" || test.at == "g(" { start++ }
+		match := strings.Index(code, test.match) + test.offset
+		b, found := h.MatchBracket(start)
+		if !found || b.Match != match || !b.IsOpen() { t.Errorf("%q: %v %v, expected match at %d", test.at, b, found, match) }
+		back, found := h.MatchBracket(match)
+		if !found || back.Match != start || back.IsOpen() { t.Errorf("%q back: %v %v", test.at, back, found) }
+	}
+
+	for _, inside := range []string{"(}\"", "{\n\n"} {
+		if i := strings.Index(code, inside); i >= 0 {
+			if b, found := h.MatchBracket(i); found { t.Errorf("bracket %v in string or comment", b) }
+		}
+	}
+}
+
+func TestBracketLevels(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("javascript")
+	code := "f(a[b(1)], {x: [2]})"
+	h.Parse(&code)
+
+	levels := ""
+	for _, b := range h.Brackets(0, len(code)) { levels += string(rune('0' + b.Level)) }
+	if levels != "0122112210" { t.Errorf("levels %s", levels) }
+}
+
+func TestEnclosingBrackets(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("python")
+	code := "x = [1, (2, 3), \"(\"]\n"
+	h.Parse(&code)
+
+	b, found := h.EnclosingBrackets(strings.Index(code, "3"))
+	if !found || b.Start != strings.Index(code, "(") { t.Errorf("inner %v %v", b, found) }
+	b, found = h.EnclosingBrackets(strings.Index(code, "\"("))
+	if !found || b.Start != strings.Index(code, "[") { t.Errorf("outer %v %v", b, found) }
+	if _, found = h.EnclosingBrackets(1); found { t.Errorf("found outside brackets") }
+}
@@ -0,0 +1,77 @@
+package highlighter
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+	"regexp"
+)
+
+/*
+	Queries read the text of captured nodes only, by byte ranges of the document,
+	so highlighting and test search never copy the whole document to a single slice.
+*/
+
+// Document is a text read by chunks for parsing and by byte ranges for queries
+type Document interface {
+	Source
+	Slice(start, end int) []byte
+}
+
+// Bytes is a document kept in a single slice
+type Bytes []byte
+
+func (b Bytes) Chunk(offset int) []byte {
+	if offset < 0 || offset >= len(b) { return nil }
+	return b[offset:]
+}
+
+func (b Bytes) Slice(start, end int) []byte { return b[start:end] }
+
+// NodeText returns the text of the node in the document
+func NodeText(node *sitter.Node, doc Document) string {
+	return string(doc.Slice(int(node.StartByte()), int(node.EndByte())))
+}
+
+// FilterPredicates is sitter.QueryCursor.FilterPredicates reading captured nodes from the document by ranges,
+// the match keeps no captures if its eq?, not-eq?, match? or not-match? predicates fail
+func FilterPredicates(q *sitter.Query, m *sitter.QueryMatch, doc Document) *sitter.QueryMatch {
+	filtered := &sitter.QueryMatch{ID: m.ID, PatternIndex: m.PatternIndex}
+
+	for _, steps := range q.PredicatesForPattern(uint32(m.PatternIndex)) {
+		if len(steps) < 3 { continue }
+		operator := q.StringValueForId(steps[0].ValueId)
+		left := q.CaptureNameForId(steps[1].ValueId)
+
+		switch operator {
+		case "eq?", "not-eq?":
+			positive := operator == "eq?"
+			if steps[2].Type == sitter.QueryPredicateStepTypeCapture {
+				leftNode, rightNode := captured(q, m, left), captured(q, m, q.CaptureNameForId(steps[2].ValueId))
+				if leftNode != nil && rightNode != nil && (NodeText(leftNode, doc) == NodeText(rightNode, doc)) != positive { return filtered }
+				continue
+			}
+			right := q.StringValueForId(steps[2].ValueId)
+			for _, c := range m.Captures {
+				if q.CaptureNameForId(c.Index) == left && (NodeText(c.Node, doc) == right) != positive { return filtered }
+			}
+
+		case "match?", "not-match?":
+			positive := operator == "match?"
+			regex := regexp.MustCompile(q.StringValueForId(steps[2].ValueId))
+			for _, c := range m.Captures {
+				if q.CaptureNameForId(c.Index) != left { continue }
+				if regex.Match(doc.Slice(int(c.Node.StartByte()), int(c.Node.EndByte()))) != positive { return filtered }
+			}
+		}
+	}
+
+	filtered.Captures = m.Captures
+	return filtered
+}
+
+// the first node of the capture in the match
+func captured(q *sitter.Query, m *sitter.QueryMatch, name string) *sitter.Node {
+	for _, c := range m.Captures {
+		if q.CaptureNameForId(c.Index) == name { return c.Node }
+	}
+	return nil
+}
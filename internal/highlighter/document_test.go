@@ -0,0 +1,37 @@
+package highlighter
+
+import (
+	"context"
+	"fmt"
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+	"testing"
+)
+
+func TestFilterPredicates(t *testing.T) {
+	code := Bytes("package main\nfunc TestA() {}\nfunc helper() {}\nfunc main() {}\n")
+	parser := sitter.NewParser()
+	parser.SetLanguage(golang.GetLanguage())
+	tree, _ := parser.ParseCtx(context.Background(), nil, code)
+
+	tests := map[string]string{
+		`((function_declaration name: (identifier) @name) (#match? @name "^Test"))`:     "[TestA]",
+		`((function_declaration name: (identifier) @name) (#not-match? @name "^Test"))`: "[helper main]",
+		`((function_declaration name: (identifier) @name) (#eq? @name "main"))`:         "[main]",
+		`((function_declaration name: (identifier) @name) (#not-eq? @name "main"))`:     "[TestA helper]",
+	}
+	for query, expected := range tests {
+		q, err := sitter.NewQuery([]byte(query), golang.GetLanguage())
+		if err != nil { t.Fatal(err) }
+		qc := sitter.NewQueryCursor()
+		qc.Exec(q, tree.RootNode())
+
+		names := []string{}
+		for {
+			m, ok := qc.NextMatch()
+			if !ok { break }
+			for _, c := range FilterPredicates(q, m, code).Captures { names = append(names, NodeText(c.Node, code)) }
+		}
+		if fmt.Sprint(names) != expected { t.Errorf("%s: %v, expected %s", query, names, expected) }
+	}
+}
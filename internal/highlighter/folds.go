@@ -0,0 +1,96 @@
+package highlighter
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+	"sort"
+)
+
+/*
+	Foldable regions are multiline nodes of known kinds: functions, classes, blocks, literals.
+	Neighbour comments and imports have no common parent node, so their runs are joined.
+	Region keeps its first line visible, when folded lines after it up to Sey are hidden.
+*/
+
+var foldNodes = map[string]bool{
+	// functions and methods
+	"function_declaration": true, "method_declaration": true, "func_literal": true,
+	"function_definition": true, "function_item": true, "method_definition": true,
+	"function_expression": true, "arrow_function": true, "function": true,
+	"constructor_declaration": true, "generator_function_declaration": true,
+
+	// classes and types
+	"class_declaration": true, "class_definition": true, "class": true, "interface_declaration": true,
+	"enum_declaration": true, "type_declaration": true, "struct_item": true, "enum_item": true,
+	"impl_item": true, "trait_item": true, "mod_item": true, "struct_specifier": true,
+	"namespace_definition": true,
+
+	// imports
+	"import_declaration": true, "import_spec_list": true, "use_list": true,
+
+	// blocks
+	"block": true, "statement_block": true, "compound_statement": true, "declaration_list": true,
+	"field_declaration_list": true, "class_body": true, "interface_body": true, "enum_body": true,
+	"if_statement": true, "for_statement": true, "while_statement": true, "switch_statement": true,
+	"expression_switch_statement": true, "type_switch_statement": true, "select_statement": true,
+	"try_statement": true, "match_expression": true, "with_statement": true,
+
+	// literals
+	"object": true, "array": true, "dictionary": true, "list": true, "literal_value": true,
+	"composite_literal": true, "template_string": true, "raw_string_literal": true,
+
+	// markup and configs
+	"element": true, "rule_set": true, "block_mapping_pair": true, "heredoc_body": true,
+}
+
+// nodes joined with the next ones on adjacent lines
+var foldRuns = map[string]string{
+	"comment": "comment", "line_comment": "comment", "block_comment": "comment",
+	"import_statement": "import", "import_from_statement": "import", "use_declaration": "import",
+	"preproc_include": "import",
+}
+
+// FoldRanges returns foldable regions ordered by the first line, one per line, the longest
+func (h *TreeSitterHighlighter) FoldRanges() []NodeRange {
+	if h.tree == nil { return nil }
+	byLine := map[int]NodeRange{}
+	add := func(r NodeRange) {
+		if r.Sey <= r.Ssy { return }
+		if old, found := byLine[r.Ssy]; !found || old.Sey < r.Sey { byLine[r.Ssy] = r }
+	}
+	collectFolds(h.tree.RootNode(), add)
+
+	ranges := make([]NodeRange, 0, len(byLine))
+	for _, r := range byLine { ranges = append(ranges, r) }
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Ssy < ranges[j].Ssy })
+	return ranges
+}
+
+func collectFolds(node *sitter.Node, add func(NodeRange)) {
+	var run *NodeRange
+	runKind := ""
+	for i := 0; i < int(node.NamedChildCount()); i++ {
+		child := node.NamedChild(i)
+		r := foldRange(child)
+
+		kind := foldRuns[child.Type()]
+		if kind != "" && kind == runKind && r.Ssy <= run.Sey+1 {
+			run.Sey, run.Sex = r.Sey, r.Sex
+		} else {
+			if run != nil { add(*run) }
+			run, runKind = nil, kind
+			if kind != "" { run = &r }
+		}
+
+		if foldNodes[child.Type()] { add(r) }
+		collectFolds(child, add)
+	}
+	if run != nil { add(*run) }
+}
+
+// node range without the line it ends at the start of
+func foldRange(node *sitter.Node) NodeRange {
+	r := NodeRange{int(node.StartPoint().Row), int(node.StartPoint().Column),
+		int(node.EndPoint().Row), int(node.EndPoint().Column)}
+	if r.Sex == 0 && r.Sey > r.Ssy { r.Sey-- }
+	return r
+}
@@ -0,0 +1,45 @@
+package highlighter
+
+import (
+	"testing"
+)
+
+func TestFoldRanges(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("go")
+	code := `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// first
+// second
+func main() {
+	if len(os.Args) > 1 {
+		fmt.Println(os.Args)
+	}
+}
+`
+	h.Parse(&code)
+
+	expected := [][2]int{{2, 5}, {7, 8}, {9, 13}, {10, 12}}
+	ranges := h.FoldRanges()
+	if len(ranges) != len(expected) { t.Fatalf("unexpected %v", ranges) }
+	for i, r := range ranges {
+		if r.Ssy != expected[i][0] || r.Sey != expected[i][1] { t.Errorf("%d: %v, expected %v", i, r, expected[i]) }
+	}
+}
+
+func TestFoldRangesImportRun(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("python")
+	code := "import os\nimport sys\nfrom a import b\n\ndef f():\n    return 1\n"
+	h.Parse(&code)
+
+	ranges := h.FoldRanges()
+	if len(ranges) != 2 || ranges[0].Ssy != 0 || ranges[0].Sey != 2 || ranges[1].Ssy != 4 || ranges[1].Sey != 5 {
+		t.Errorf("unexpected %v", ranges)
+	}
+}
@@ -40,6 +40,7 @@ var OverlayColor = -1    // transparent
 var AccentColor = 303    // pink
 var AccentColor2 = 30    // aqua
 var AccentColor3 = -1    // aqua
+var RainbowColors = []int{220, 170, 39} // gold, orchid, blue for nesting levels of brackets
 
 var SeparatorStyle = tcell.StyleDefault.Foreground(tcell.ColorDimGray)
 var DimmedStyle = tcell.StyleDefault.Foreground(tcell.ColorDimGray)
@@ -0,0 +1,88 @@
+package highlighter
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+	"strings"
+)
+
+/*
+	Indentation of a line is found relative to an anchor line, see langs.Indenter for captures:
+	1. a line starting with @outdent token is aligned with the first line of the token node,
+	2. a line after the one ending with @indent.immediate token is one level deeper than it,
+	3. otherwise the innermost @indent node, started on a line above, makes it one level deeper than its first line.
+	Blank line is looked up from the last char of the previous text, so it continues the node it is typed in.
+*/
+
+type indentCapture struct {
+	name string
+	node *sitter.Node
+}
+
+// Indent finds indentation of the row: as the anchor row, and one level deeper if deeper is set.
+// Anchor is -1 for top level lines, ok is false if the language has no indent query
+func (h *TreeSitterHighlighter) Indent(lines [][]rune, row int) (anchor int, deeper bool, ok bool) {
+	if h.indentQuery == nil || h.tree == nil || row < 0 || row >= len(lines) { return -1, false, false }
+
+	line := lines[row]
+	col := 0
+	for col < len(line) && (line[col] == ' ' || line[col] == '\t') { col++ }
+	at := sitter.Point{Row: uint32(row), Column: uint32(len(string(line[:col])))}
+	blank := col == len(line)
+
+	prev := row - 1
+	for prev >= 0 && strings.TrimSpace(string(lines[prev])) == "" { prev-- }
+	prevEnd := sitter.Point{}
+	if prev >= 0 {
+		prevEnd = sitter.Point{Row: uint32(prev), Column: uint32(len(strings.TrimRight(string(lines[prev]), " \t")))}
+	}
+
+	captures := h.indentCaptures(max(prev, 0), row)
+	for _, c := range captures {
+		if c.name == "outdent" && !blank && c.node.StartPoint() == at {
+			for n := c.node.Parent(); n != nil; n = n.Parent() {
+				if int(n.StartPoint().Row) < row { return int(n.StartPoint().Row), false, true }
+			}
+			return -1, false, true
+		}
+	}
+	for _, c := range captures {
+		if c.name == "indent.immediate" && prev >= 0 && c.node.EndPoint() == prevEnd { return prev, true, true }
+	}
+
+	indents := map[[2]uint32]string{}
+	for _, c := range captures {
+		if strings.HasPrefix(c.name, "indent") { indents[[2]uint32{c.node.StartByte(), c.node.EndByte()}] = c.name }
+	}
+
+	from := at
+	if blank && prev >= 0 && prevEnd.Column > 0 { from = sitter.Point{Row: prevEnd.Row, Column: prevEnd.Column - 1} } // the last char
+	root := h.tree.RootNode()
+	for n := root.NamedDescendantForPointRange(from, from); n != nil; n = n.Parent() {
+		name, found := indents[[2]uint32{n.StartByte(), n.EndByte()}]
+		if !found || int(n.StartPoint().Row) >= row { continue }
+		if name == "indent.open" || !pointBefore(n.EndPoint(), at) { return int(n.StartPoint().Row), true, true }
+	}
+	return -1, false, true
+}
+
+// captures of nodes crossing rows from..to
+func (h *TreeSitterHighlighter) indentCaptures(from, to int) []indentCapture {
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	cursor.Exec(h.indentQuery, h.tree.RootNode())
+	cursor.SetPointRange(sitter.Point{Row: uint32(from)}, sitter.Point{Row: uint32(to + 1)})
+
+	captures := []indentCapture{}
+	for {
+		m, ok := cursor.NextMatch()
+		if !ok { break }
+		for _, c := range m.Captures {
+			captures = append(captures, indentCapture{h.indentQuery.CaptureNameForId(c.Index), c.Node})
+		}
+	}
+	return captures
+}
+
+func pointBefore(a, b sitter.Point) bool {
+	return a.Row < b.Row || a.Row == b.Row && a.Column < b.Column
+}
@@ -0,0 +1,58 @@
+package highlighter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIndentQueriesCompile(t *testing.T) {
+	for _, lang := range []string{"go", "javascript", "typescript", "python", "rust", "c", "c++", "java", "css", "bash"} {
+		h := NewTreeSitter()
+		h.SetLang(lang)
+		if h.indentQuery == nil { t.Error("no indent query for", lang) }
+	}
+}
+
+// code has | at the start of the line to indent, expected is its indentation with tabs
+func TestIndent(t *testing.T) {
+	tests := []struct { lang, code, expected string }{
+		{"go", "func f() {\n|\n}", "\t"},
+		{"go", "func f() {\n|}", ""},
+		{"go", "func f() {\n\tif x {\n|", "\t\t"},
+		{"go", "func f() {\n\tif x {\n\t\ty()\n\t}\n|\n}", "\t"},
+		{"go", "func f() {\n\tswitch x {\n|case 1:\n\t}\n}", "\t"},
+		{"go", "func f() {\n\tswitch x {\n\tcase 1:\n|\n\t}\n}", "\t\t"},
+		{"go", "func f() {\n\tswitch x {\n\tcase 1:\n\t\ty()\n|}\n}", "\t"},
+		{"go", "func f() {\n\tg(a,\n|b)\n}", "\t\t"},
+		{"javascript", "function f() {\n\tconst a = [\n|", "\t\t"},
+		{"javascript", "function f() {\n\tconst a = {\n\t\tb: 1,\n|}\n}", "\t"},
+		{"python", "def f():\n|", "\t"},
+		{"python", "def f():\n\tx = 1\n|", "\t"},
+		{"python", "def f():\n\tif x:\n\t\ty()\n|\telse:\n\t\tz()", "\t"},
+		{"rust", "fn main() {\n\tlet v = vec![\n|", "\t\t"},
+		{"c", "int main() {\n\tswitch (x) {\n\tcase 1:\n|", "\t\t"},
+		{"bash", "if x; then\n\ty\n|fi", ""},
+	}
+	for _, test := range tests {
+		lines, row := [][]rune{}, -1
+		for i, line := range strings.Split(test.code, "\n") {
+			if strings.HasPrefix(line, "|") { row, line = i, line[1:] }
+			lines = append(lines, []rune(line))
+		}
+		code := strings.ReplaceAll(test.code, "|", "")
+
+		h := NewTreeSitter()
+		h.SetLang(test.lang)
+		h.Parse(&code)
+
+		anchor, deeper, ok := h.Indent(lines, row)
+		if !ok { t.Errorf("%s: no indent", test.lang); continue }
+		indent := ""
+		if anchor >= 0 {
+			line := string(lines[anchor])
+			indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		}
+		if deeper { indent += "\t" }
+		if indent != test.expected { t.Errorf("%s %q: indent %q, expected %q", test.lang, test.code, indent, test.expected) }
+	}
+}
@@ -78,3 +78,22 @@ func (this *Bash) Query() string {
 
 `
 }
+
+func (this *Bash) Indents() string {
+	return `
+[
+  (compound_statement)
+  (do_group)
+  (if_statement)
+  (elif_clause)
+  (else_clause)
+  (case_statement)
+  (case_item)
+] @indent
+
+["then" "do" "{"] @indent.immediate
+(else_clause "else" @indent.immediate)
+
+["fi" "done" "esac" "elif" "else" "}"] @outdent
+`
+}
@@ -104,3 +104,22 @@ func (this *C) Query() string {
 `
 }
 
+
+func (this *C) Indents() string {
+	return `
+[
+  (compound_statement)
+  (field_declaration_list)
+  (enumerator_list)
+  (initializer_list)
+  (argument_list)
+  (parameter_list)
+  (case_statement)
+] @indent
+
+["{" "(" "["] @indent.immediate
+(case_statement ":" @indent.immediate)
+
+["}" ")" "]"] @outdent
+`
+}
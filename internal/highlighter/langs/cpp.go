@@ -172,3 +172,23 @@ func (this *Cpp) Query() string {
 `
 }
 
+
+func (this *Cpp) Indents() string {
+	return `
+[
+  (compound_statement)
+  (field_declaration_list)
+  (enumerator_list)
+  (initializer_list)
+  (argument_list)
+  (parameter_list)
+  (case_statement)
+  (declaration_list)
+] @indent
+
+["{" "(" "["] @indent.immediate
+(case_statement ":" @indent.immediate)
+
+["}" ")" "]"] @outdent
+`
+}
@@ -45,3 +45,13 @@ func (this *Css) Query() string {
 ] @keyword
 `
 }
+
+func (this *Css) Indents() string {
+	return `
+(block) @indent
+
+"{" @indent.immediate
+
+"}" @outdent
+`
+}
@@ -148,3 +148,40 @@ func (this *Go) Query() string {
 
 `
 }
+
+func (this *Go) Indents() string {
+	return `
+[
+  (block)
+  (literal_value)
+  (argument_list)
+  (parameter_list)
+  (field_declaration_list)
+  (interface_type)
+  (import_spec_list)
+  (var_declaration)
+  (const_declaration)
+  (expression_case)
+  (default_case)
+  (type_case)
+  (communication_case)
+] @indent
+
+(block "{" @indent.immediate)
+(literal_value "{" @indent.immediate)
+(field_declaration_list "{" @indent.immediate)
+(interface_type "{" @indent.immediate)
+(argument_list "(" @indent.immediate)
+(parameter_list "(" @indent.immediate)
+(import_spec_list "(" @indent.immediate)
+(var_declaration "(" @indent.immediate)
+(const_declaration "(" @indent.immediate)
+(expression_case ":" @indent.immediate)
+(default_case ":" @indent.immediate)
+(type_case ":" @indent.immediate)
+(communication_case ":" @indent.immediate)
+(ERROR ["{" "(" "["] @indent.immediate)
+
+["}" ")" "]"] @outdent
+`
+}
@@ -136,3 +136,24 @@ func (this *Java) Query() string {
   name: (identifier) @attribute)
 `
 }
+
+func (this *Java) Indents() string {
+	return `
+[
+  (block)
+  (class_body)
+  (interface_body)
+  (enum_body)
+  (constructor_body)
+  (argument_list)
+  (formal_parameters)
+  (array_initializer)
+  (switch_block)
+  (switch_block_statement_group)
+] @indent
+
+["{" "(" "["] @indent.immediate
+
+["}" ")" "]"] @outdent
+`
+}
@@ -215,3 +215,32 @@ query :=`
 `
 	return query
 }
+
+func (this *Javascript) Indents() string {
+	return `
+[
+  (statement_block)
+  (class_body)
+  (object)
+  (array)
+  (arguments)
+  (formal_parameters)
+  (object_pattern)
+  (array_pattern)
+  (named_imports)
+  (export_clause)
+  (switch_body)
+  (switch_case)
+  (switch_default)
+  (parenthesized_expression)
+  (jsx_element)
+] @indent
+
+["{" "(" "["] @indent.immediate
+(switch_case ":" @indent.immediate)
+(switch_default ":" @indent.immediate)
+
+["}" ")" "]"] @outdent
+(jsx_closing_element) @outdent
+`
+}
@@ -4,6 +4,15 @@ type Language interface {
 	Query() string
 }
 
+// Indenter is a language with indent query, its captures are
+// @indent - lines inside the node are one level deeper than its first line,
+// @indent.open - the same, and the node takes the lines after it, as it has no closing token,
+// @indent.immediate - the line after the one ending with the token is one level deeper, for unfinished code,
+// @outdent - the line starting with the token is aligned with the first line of its node
+type Indenter interface {
+	Indents() string
+}
+
 var languages = map[string]Language{
 	"javascript": &Javascript{},
 	"typescript": &Typescript{},
@@ -20,9 +29,21 @@ var languages = map[string]Language{
 	"bash":       &Bash{},
 }
 
+// IsSupported tells if the language has its own query, others are highlighted as javascript
+func IsSupported(lang string) bool {
+	_, exists := languages[lang]
+	return exists
+}
+
 func MatchQueryLang(lang string) string {
 	if l, exists := languages[lang]; exists {
 		return l.Query()
 	}
 	return languages["javascript"].Query()
 }
+
+// MatchIndentQuery returns indent query of the language, empty if there is none
+func MatchIndentQuery(lang string) string {
+	if l, exists := languages[lang].(Indenter); exists { return l.Indents() }
+	return ""
+}
@@ -233,3 +233,42 @@ func (this *Python) Query() string {
 
 }
 
+
+func (this *Python) Indents() string {
+	return `
+[
+  (function_definition)
+  (class_definition)
+  (if_statement)
+  (elif_clause)
+  (else_clause)
+  (for_statement)
+  (while_statement)
+  (try_statement)
+  (except_clause)
+  (finally_clause)
+  (with_statement)
+] @indent.open
+
+[
+  (list)
+  (dictionary)
+  (set)
+  (tuple)
+  (argument_list)
+  (parameters)
+  (parenthesized_expression)
+  (list_comprehension)
+  (dictionary_comprehension)
+] @indent
+
+":" @indent.immediate
+["{" "(" "["] @indent.immediate
+
+["}" ")" "]"] @outdent
+(elif_clause "elif" @outdent)
+(else_clause "else" @outdent)
+(except_clause "except" @outdent)
+(finally_clause "finally" @outdent)
+`
+}
@@ -312,3 +312,26 @@ func (this *Rust) Query() string {
 `
 }
 
+
+func (this *Rust) Indents() string {
+	return `
+[
+  (block)
+  (declaration_list)
+  (field_declaration_list)
+  (enum_variant_list)
+  (field_initializer_list)
+  (match_block)
+  (use_list)
+  (arguments)
+  (parameters)
+  (array_expression)
+  (tuple_expression)
+  (token_tree)
+] @indent
+
+["{" "(" "["] @indent.immediate
+
+["}" ")" "]"] @outdent
+`
+}
@@ -164,3 +164,32 @@ func (this *Typescript) Query() string {
 `
 }
 
+
+func (this *Typescript) Indents() string {
+	return `
+[
+  (statement_block)
+  (class_body)
+  (enum_body)
+  (object_type)
+  (object)
+  (array)
+  (arguments)
+  (formal_parameters)
+  (object_pattern)
+  (array_pattern)
+  (named_imports)
+  (export_clause)
+  (switch_body)
+  (switch_case)
+  (switch_default)
+  (parenthesized_expression)
+] @indent
+
+["{" "(" "["] @indent.immediate
+(switch_case ":" @indent.immediate)
+(switch_default ":" @indent.immediate)
+
+["}" ")" "]"] @outdent
+`
+}
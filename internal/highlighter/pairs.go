@@ -0,0 +1,125 @@
+package highlighter
+
+import (
+	sitter "github.com/smacker/go-tree-sitter"
+	"strings"
+)
+
+/*
+	Syntax context of a byte offset for auto pairs, surround and comments:
+	strings, comments and markup elements around it, and the language injected at it.
+	A line comment ends before the line break, so the end of its line is still inside it.
+*/
+
+// string literals of supported grammars, with their delimiters
+var stringNodes = map[string]bool{
+	"string": true, "string_literal": true, "interpreted_string_literal": true, "raw_string_literal": true,
+	"template_string": true, "char_literal": true, "rune_literal": true, "character_literal": true,
+	"raw_string": true, "string_value": true, "double_quote_scalar": true, "single_quote_scalar": true,
+	"quoted_attribute_value": true,
+}
+
+var blockComments = []string{"/*", "<!--"}
+
+// opening and closing tags of an element
+var elementTags = map[string][2]string{
+	"element":     {"start_tag", "end_tag"},
+	"jsx_element": {"jsx_opening_element", "jsx_closing_element"},
+}
+
+// nodes containing the byte at the offset, from the root to the innermost
+func (h *TreeSitterHighlighter) nodesAt(offset int) []*sitter.Node {
+	if h.tree == nil || offset < 0 { return nil }
+	path := []*sitter.Node{}
+	for node := h.tree.RootNode(); node != nil; {
+		if int(node.StartByte()) > offset || offset >= int(node.EndByte()) { break }
+		path = append(path, node)
+		var next *sitter.Node
+		for i := 0; i < int(node.ChildCount()); i++ {
+			child := node.Child(i)
+			if int(child.StartByte()) <= offset && offset < int(child.EndByte()) { next = child; break }
+		}
+		node = next
+	}
+	return path
+}
+
+// NodeTypesAt returns types of nodes containing the byte at the offset, the innermost last
+func (h *TreeSitterHighlighter) NodeTypesAt(offset int) []string {
+	types := []string{}
+	for _, node := range h.nodesAt(offset) { types = append(types, node.Type()) }
+	return types
+}
+
+// SyntaxAt returns "string" or "comment" if text typed at the offset gets inside such node, "" otherwise
+func (h *TreeSitterHighlighter) SyntaxAt(offset int, doc Document) string {
+	for _, node := range h.nodesAt(offset) {
+		if int(node.StartByte()) == offset { continue }
+		if stringNodes[node.Type()] { return "string" }
+		if strings.Contains(node.Type(), "comment") { return "comment" }
+	}
+
+	if offset == 0 { return "" }
+	path := h.nodesAt(offset - 1)
+	for i := len(path) - 1; i >= 0; i-- {
+		node := path[i]
+		if !strings.Contains(node.Type(), "comment") || int(node.EndByte()) != offset { continue }
+		text := NodeText(node, doc)
+		block := false
+		for _, prefix := range blockComments { block = block || strings.HasPrefix(text, prefix) }
+		if !block { return "comment" }
+	}
+	return ""
+}
+
+// StringAround returns byte range of the innermost string literal with the offset
+func (h *TreeSitterHighlighter) StringAround(offset int) (int, int, bool) {
+	path := h.nodesAt(offset)
+	for i := len(path) - 1; i >= 0; i-- {
+		if stringNodes[path[i].Type()] { return int(path[i].StartByte()), int(path[i].EndByte()), true }
+	}
+	return 0, 0, false
+}
+
+// CommentAround returns byte range of the comment with the offset
+func (h *TreeSitterHighlighter) CommentAround(offset int) (int, int, bool) {
+	path := h.nodesAt(offset)
+	for i := len(path) - 1; i >= 0; i-- {
+		if strings.Contains(path[i].Type(), "comment") { return int(path[i].StartByte()), int(path[i].EndByte()), true }
+	}
+	return 0, 0, false
+}
+
+// TagsAround returns byte ranges of the opening and closing tags of the innermost element with the offset
+func (h *TreeSitterHighlighter) TagsAround(offset int) ([2]int, [2]int, bool) {
+	path := h.nodesAt(offset)
+	for i := len(path) - 1; i >= 0; i-- {
+		tags, found := elementTags[path[i].Type()]
+		count := int(path[i].ChildCount())
+		if !found || count < 2 { continue }
+		open, close := path[i].Child(0), path[i].Child(count-1)
+		if open.Type() != tags[0] || close.Type() != tags[1] { continue }
+		return [2]int{int(open.StartByte()), int(open.EndByte())}, [2]int{int(close.StartByte()), int(close.EndByte())}, true
+	}
+	return [2]int{}, [2]int{}, false
+}
+
+// LanguageAt returns the language injected at the offset of the row, like css in a style element, "" for the file language
+func (h *TreeSitterHighlighter) LanguageAt(row, offset int) string {
+	if h.tree == nil || h.query == nil { return "" }
+	cursor := sitter.NewQueryCursor()
+	defer cursor.Close()
+	cursor.Exec(h.query, h.tree.RootNode())
+	cursor.SetPointRange(sitter.Point{Row: uint32(row)}, sitter.Point{Row: uint32(row + 1)})
+
+	for {
+		m, ok := cursor.NextMatch()
+		if !ok { break }
+		for _, c := range m.Captures {
+			name := h.query.CaptureNameForId(c.Index)
+			if !strings.HasPrefix(name, "injection.content.") { continue }
+			if int(c.Node.StartByte()) <= offset && offset <= int(c.Node.EndByte()) { return strings.TrimPrefix(name, "injection.content.") }
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package highlighter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSyntaxAt(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("go")
+	code := "package main\n\n// line comment\nvar s = \"text\" /* block */\n"
+	h.Parse(&code)
+
+	tests := []struct {
+		at     string
+		shift  int
+		syntax string
+	}{
+		{"line comment", 4, "comment"},
+		{"\nvar", 0, "comment"}, // end of the line comment
+		{"var", 0, ""},
+		{"\"text", 0, ""},
+		{"text", 2, "string"},
+		{"text\"", 4, "string"}, // before the closing quote
+		{"text\"", 5, ""},
+		{"block", 0, "comment"},
+		{"\n", 0, ""},
+	}
+	for _, test := range tests {
+		offset := strings.Index(code, test.at) + test.shift
+		if test.at == "\n" { offset = len(code) - 1 }
+		if syntax := h.SyntaxAt(offset, Bytes(code)); syntax != test.syntax { t.Errorf("%q+%d: %q, expected %q", test.at, test.shift, syntax, test.syntax) }
+	}
+}
+
+func TestStringAround(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("python")
+	code := "x = f(\"a\", 'bc')\n"
+	h.Parse(&code)
+
+	start, end, found := h.StringAround(strings.Index(code, "c"))
+	if !found || code[start:end] != "'bc'" { t.Errorf("unexpected %d %d %v", start, end, found) }
+	if _, _, found = h.StringAround(strings.Index(code, "f")); found { t.Errorf("string outside of literals") }
+}
+
+func TestTagsAround(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("html")
+	code := "<div class=\"a\"><p>text</p></div>\n"
+	h.Parse(&code)
+
+	open, close, found := h.TagsAround(strings.Index(code, "ext"))
+	if !found || code[open[0]:open[1]] != "<p>" || code[close[0]:close[1]] != "</p>" { t.Errorf("inner %v %v %v", open, close, found) }
+	open, close, found = h.TagsAround(strings.Index(code, "class"))
+	if !found || code[open[0]:open[1]] != "<div class=\"a\">" || code[close[0]:close[1]] != "</div>" { t.Errorf("outer %v %v %v", open, close, found) }
+}
+
+func TestLanguageAt(t *testing.T) {
+	h := NewTreeSitter()
+	h.SetLang("html")
+	code := "<style>\np { color: red; }\n</style>\n<script>let a = 1</script>\n<p>text</p>\n"
+	h.Parse(&code)
+
+	tests := map[string]string{"color": "css", "let": "javascript", "text": ""}
+	for at, lang := range tests {
+		offset := strings.Index(code, at)
+		row := strings.Count(code[:offset], "\n")
+		if found := h.LanguageAt(row, offset); found != lang { t.Errorf("%s: %q, expected %q", at, found, lang) }
+	}
+}
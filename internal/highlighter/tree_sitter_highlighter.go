@@ -32,6 +32,7 @@ type TreeSitterHighlighter struct {
 	lang           string
 	language       *sitter.Language
 	query          *sitter.Query
+	indentQuery    *sitter.Query // nil if the language has no indent query
 	colorsMap      map[string]string
 	themePath      string
 	injectionLangs map[string]*TreeSitterHighlighter
@@ -132,6 +133,12 @@ func (h *TreeSitterHighlighter) SetLang(lang string) {
 	q, err := sitter.NewQuery([]byte(queryLang), h.language)
 	if err!= nil { panic(err) }
 	h.query = q
+
+	h.indentQuery = nil
+	if indents := MatchIndentQuery(h.lang); indents != "" {
+		h.indentQuery, err = sitter.NewQuery([]byte(indents), h.language)
+		if err != nil { panic(err) }
+	}
 }
 
 
@@ -222,7 +229,38 @@ func (h *TreeSitterHighlighter) ReParseBytes(codeBytes []byte) {
 }
 
 
-func (h *TreeSitterHighlighter) ColorRanges(from, to int, codeBytes []byte) []ColoredByteRange {
+// Source is a document tree-sitter reads by chunks, so it is never copied to a single string
+type Source interface {
+	Chunk(offset int) []byte
+}
+
+// Edit tells the tree which range was changed, should be followed by ParseSource
+func (h *TreeSitterHighlighter) Edit(edit sitter.EditInput) {
+	if h.tree == nil { return }
+	h.tree.Edit(edit)
+}
+
+// ParseSource parses incrementally, reusing the edited tree
+func (h *TreeSitterHighlighter) ParseSource(source Source) {
+	h.parseSource(source, h.tree)
+}
+
+func (h *TreeSitterHighlighter) ReParseSource(source Source) {
+	h.parseSource(source, nil)
+}
+
+func (h *TreeSitterHighlighter) parseSource(source Source, oldTree *sitter.Tree) {
+	input := sitter.Input{
+		Encoding: sitter.InputEncodingUTF8,
+		Read: func(offset uint32, position sitter.Point) []byte { return source.Chunk(int(offset)) },
+	}
+	tree, err := h.parser.ParseInputCtx(context.Background(), oldTree, input)
+	if err != nil { fmt.Println(err) }
+	h.tree = tree
+}
+
+// ColorRanges returns colors of the rows range, the document is read only by the captured nodes
+func (h *TreeSitterHighlighter) ColorRanges(from, to int, doc Document) []ColoredByteRange {
 
 	queryCursor := sitter.NewQueryCursor()
 	queryCursor.Exec(h.query, h.tree.RootNode())
@@ -236,14 +274,12 @@ func (h *TreeSitterHighlighter) ColorRanges(from, to int, codeBytes []byte) []Co
 	for {
 		m, ok := queryCursor.NextMatch()
 		if !ok { break }
-		m = queryCursor.FilterPredicates(m, codeBytes)
+		m = FilterPredicates(h.query, m, doc)
 		for _, c := range m.Captures {
 			name := h.query.CaptureNameForId(c.Index)
 			split := strings.Split(name, ".")
 			color := h.matchExpression(split[0], name)
 
-			contentstr := string(codeBytes[c.Node.StartByte():c.Node.EndByte()]); Use(contentstr) // for debug
-
 			if !strings.Contains(name, "injection") {
 				colors = append(colors, ColoredByteRange{
 					StartByte: int(c.Node.StartByte()),
@@ -262,7 +298,7 @@ func (h *TreeSitterHighlighter) ColorRanges(from, to int, codeBytes []byte) []Co
 					h.injectionLangs[injLang] = injectionHighlighter
 				}
 
-				contentInjection := codeBytes[c.Node.StartByte():c.Node.EndByte()]
+				contentInjection := doc.Slice(int(c.Node.StartByte()), int(c.Node.EndByte()))
 				injectionHighlighter.ReParseBytes(contentInjection)
 
 				injectionLength := int(c.Node.EndPoint().Row) - int(c.Node.StartPoint().Row)
@@ -272,7 +308,7 @@ func (h *TreeSitterHighlighter) ColorRanges(from, to int, codeBytes []byte) []Co
 				if fromInj < 0 { fromInj = 0 }
 				toInj := to - int(c.Node.EndPoint().Row)
 				if toInj < to { toInj = to }
-				colorsInjection := injectionHighlighter.ColorRanges(fromInj, toInj, contentInjection)
+				colorsInjection := injectionHighlighter.ColorRanges(fromInj, toInj, Bytes(contentInjection))
 
 				startByte := int(c.Node.StartByte())
 				for _, colorsInj := range colorsInjection {
@@ -302,6 +338,9 @@ func (h *TreeSitterHighlighter) GetLang() *sitter.Language {
 func (h *TreeSitterHighlighter) GetLangStr() string {
 	return h.lang
 }
+func (h *TreeSitterHighlighter) HasGrammar() bool {
+	return IsSupported(h.lang)
+}
 
 
 type NodeRange struct {
@@ -0,0 +1,74 @@
+package history
+
+import (
+	"crypto/sha256"
+	"edgo/internal/undo"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+const MaxUndo = 1000 // older undo states are not saved
+
+// History is undo tree of a file, valid only for content with the same hash
+type History struct {
+	Hash string
+	Tree *undo.Tree
+}
+
+// Hash identifies content as it is written to disk
+func Hash(content [][]rune) string {
+	h := sha256.New()
+	for _, line := range content {
+		h.Write([]byte(string(line)))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ProjectDir is the directory for histories of files of the project in cwd
+func ProjectDir(stateDir, cwd string) string {
+	return filepath.Join(stateDir, "undo", filepath.Base(cwd)+"-"+shortHash(cwd))
+}
+
+// Path is the history file of the file, keyed by its absolute path
+func Path(dir, file string) string {
+	return filepath.Join(dir, shortHash(file)+".json")
+}
+
+func Save(dir, file string, h History) error {
+	tree := *h.Tree // pruned copy, the tree in use is not changed
+	tree.Prune(MaxUndo)
+	h.Tree = &tree
+
+	data, err := json.Marshal(h)
+	if err != nil { return err }
+	if err := os.MkdirAll(dir, 0750); err != nil { return err }
+
+	// written to temp file first, concurrent reader never gets half written history
+	path := Path(dir, file)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil { return err }
+	return os.Rename(tmp, path)
+}
+
+// Load returns history of the file if it was saved for content with the hash,
+// history of the changed file is removed
+func Load(dir, file, hash string) (History, bool) {
+	path := Path(dir, file)
+	data, err := os.ReadFile(path)
+	if err != nil { return History{}, false }
+
+	var h History
+	if err := json.Unmarshal(data, &h); err != nil || h.Hash != hash || h.Tree == nil || len(h.Tree.Nodes) == 0 {
+		os.Remove(path)
+		return History{}, false
+	}
+	return h, true
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
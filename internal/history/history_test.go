@@ -0,0 +1,50 @@
+package history
+
+import (
+	. "edgo/internal/operations"
+	"edgo/internal/undo"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir := ProjectDir(t.TempDir(), "/home/user/project")
+	content := [][]rune{[]rune("hello"), []rune("world")}
+	tree := undo.New()
+	tree.Push(EditOperation{{MoveCursor, ' ', 0, 0}, {Insert, 'o', 0, 4}})
+	tree.Push(EditOperation{{Delete, 'x', 1, 5}})
+	tree.Undo()
+	h := History{Hash: Hash(content), Tree: tree}
+
+	if err := Save(dir, "/home/user/project/main.go", h); err != nil { t.Fatal(err) }
+
+	loaded, ok := Load(dir, "/home/user/project/main.go", Hash(content))
+	if !ok || loaded.Tree.Current != 1 || !reflect.DeepEqual(loaded.Tree.Nodes[2].Ops, tree.Nodes[2].Ops) {
+		t.Errorf("expected %+v, got %+v", h.Tree, loaded.Tree)
+	}
+
+	if _, ok := Load(dir, "/home/user/project/other.go", Hash(content)); ok { t.Errorf("other file has no history") }
+}
+
+func TestChangedContentDropsHistory(t *testing.T) {
+	dir := t.TempDir()
+	content := [][]rune{[]rune("hello")}
+	tree := undo.New()
+	tree.Push(EditOperation{{Insert, 'o', 0, 4}})
+	Save(dir, "/a.go", History{Hash: Hash(content), Tree: tree})
+
+	if _, ok := Load(dir, "/a.go", Hash([][]rune{[]rune("hello!")})); ok { t.Errorf("history of changed file should not be loaded") }
+	if _, err := os.Stat(Path(dir, "/a.go")); !os.IsNotExist(err) { t.Errorf("history of changed file should be removed") }
+}
+
+func TestSaveLimit(t *testing.T) {
+	dir := t.TempDir()
+	tree := undo.New()
+	for i := 0; i < MaxUndo+10; i++ { tree.Push(EditOperation{{Insert, 'a', 0, i}}) }
+	Save(dir, "/a.go", History{Hash: "h", Tree: tree})
+
+	h, _ := Load(dir, "/a.go", "h")
+	if len(h.Tree.Nodes) != MaxUndo+1 || h.Tree.Nodes[1].Ops[0].Column != 10 { t.Errorf("only the last %d undo states should be saved", MaxUndo) }
+	if len(tree.Nodes) != MaxUndo+11 { t.Errorf("tree in use should not be pruned") }
+}
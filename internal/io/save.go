@@ -0,0 +1,52 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+/*
+	SaveFile never leaves a half written file: data goes to a temp file in the same directory,
+	it is synced and renamed over the target. Mode and owner of the target are kept,
+	symlink is followed, so the link stays and its target is replaced.
+*/
+
+// SaveFile writes data to path atomically, with backup the previous content is kept in path~
+func SaveFile(path string, data []byte, backup bool) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil { path = resolved }
+
+	mode := os.FileMode(0644)
+	info, err := os.Stat(path)
+	exists := err == nil
+	if exists { mode = info.Mode().Perm() }
+
+	if backup && exists {
+		previous, err := os.ReadFile(path)
+		if err != nil { return err }
+		if err := SaveFile(path+"~", previous, false); err != nil { return err }
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil { return err }
+	defer os.Remove(tmp.Name()) // no-op after rename
+
+	if _, err := tmp.Write(data); err != nil { tmp.Close(); return err }
+	if err := tmp.Sync(); err != nil { tmp.Close(); return err }
+	if err := tmp.Close(); err != nil { return err }
+
+	if err := os.Chmod(tmp.Name(), mode); err != nil { return err }
+	if exists {
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			// only root can give a file away, other users keep their own files anyway
+			if err := os.Chown(tmp.Name(), int(stat.Uid), int(stat.Gid)); err != nil && os.Geteuid() == 0 { return err }
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil { return err }
+
+	// rename itself is durable only after the directory is synced
+	if d, err := os.Open(dir); err == nil { d.Sync(); d.Close() }
+	return nil
+}
@@ -0,0 +1,46 @@
+package io
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("old\n"), 0600); err != nil { t.Fatal(err) }
+
+	if err := SaveFile(path, []byte("new\n"), true); err != nil { t.Fatal(err) }
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "new\n" { t.Error("unexpected content", string(data)) }
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 { t.Error("mode is not kept", info.Mode()) }
+
+	backup, _ := os.ReadFile(path + "~")
+	if string(backup) != "old\n" { t.Error("unexpected backup", string(backup)) }
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 { t.Error("temp file is left", entries) }
+}
+
+func TestSaveFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	link := filepath.Join(dir, "link.txt")
+	os.WriteFile(target, []byte("old\n"), 0644)
+	if err := os.Symlink(target, link); err != nil { t.Skip(err) }
+
+	if err := SaveFile(link, []byte("new\n"), false); err != nil { t.Fatal(err) }
+
+	if info, _ := os.Lstat(link); info.Mode()&os.ModeSymlink == 0 { t.Error("symlink is replaced") }
+	data, _ := os.ReadFile(target)
+	if string(data) != "new\n" { t.Error("unexpected content", string(data)) }
+}
+
+func TestSaveFileNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "new.txt")
+	if err := SaveFile(path, []byte("x\n"), true); err != nil { t.Fatal(err) }
+	if _, err := os.Stat(path + "~"); err == nil { t.Error("backup of not existing file") }
+}
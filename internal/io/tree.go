@@ -209,3 +209,31 @@ func FindByFullName(node *FileInfo, fileName string) *FileInfo {
 }
 
 
+// OpenDirs returns full names of opened directories under the node
+func OpenDirs(node FileInfo) []string {
+	dirs := []string{}
+	for _, child := range node.Childs {
+		if child.IsDir && child.IsDirOpen {
+			dirs = append(dirs, child.FullName)
+			dirs = append(dirs, OpenDirs(child)...)
+		}
+	}
+	return dirs
+}
+
+// IndexOf returns the index of the visible node with the full name, as counted by GetSelected, -1 if hidden
+func IndexOf(root FileInfo, fileName string) int {
+	var i = 0
+	if indexOf(root, fileName, &i) { return i }
+	return -1
+}
+
+func indexOf(node FileInfo, fileName string, i *int) bool {
+	if node.FullName == fileName { return true }
+	*i++
+	if !node.IsDirOpen { return false }
+	for _, child := range node.Childs {
+		if indexOf(child, fileName, i) { return true }
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package io
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -42,3 +43,24 @@ func TestGetSelected(t *testing.T) {
 	found, fi := GetSelected(tree, 13)
 	fmt.Println("selected", found, fi)
 }
+
+func TestOpenDirsIndexOf(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0750)
+	os.MkdirAll(filepath.Join(dir, "c"), 0750)
+	os.WriteFile(filepath.Join(dir, "a", "b", "file.go"), []byte(""), 0644)
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte(""), 0644)
+
+	tree, _ := ReadDirTree(dir, "", false, 0)
+	SetDirOpenFlag(&tree, filepath.Join(dir, "a", "b", "file.go"))
+
+	dirs := OpenDirs(tree)
+	if fmt.Sprint(dirs) != fmt.Sprint([]string{filepath.Join(dir, "a"), filepath.Join(dir, "a", "b")}) { t.Errorf("unexpected open dirs %v", dirs) }
+
+	for _, name := range []string{dir, filepath.Join(dir, "a", "b", "file.go"), filepath.Join(dir, "c"), filepath.Join(dir, "main.go")} {
+		index := IndexOf(tree, name)
+		found, fi := GetSelected(tree, index)
+		if !found || fi.FullName != name { t.Errorf("index %d of %s selects %s", index, name, fi.FullName) }
+	}
+	if IndexOf(tree, filepath.Join(dir, "missing")) != -1 { t.Errorf("missing node should have no index") }
+}
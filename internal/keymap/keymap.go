@@ -0,0 +1,204 @@
+package keymap
+
+import (
+	"errors"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// contexts, every context has its own bindings
+const (
+	Editor  = "editor"
+	Tree    = "tree"
+	Process = "process"
+	Debug   = "debug"
+)
+
+// Chord is one key press with modifiers, like ctrl+s, alt+/ or shift+f6
+type Chord struct {
+	Key  Key
+	Rune rune    // for KeyRune only
+	Mod  ModMask // ctrl is not kept for control keys, shift is not kept for runes
+}
+
+type Binding struct {
+	Keys    []Chord
+	Command string
+}
+
+// Keymap maps key sequences to command names in every context
+type Keymap struct {
+	bindings map[string][]Binding
+}
+
+func New() *Keymap {
+	return &Keymap{bindings: map[string][]Binding{}}
+}
+
+// Bind adds binding, replacing bindings with the same keys or conflicting by prefix
+func (k *Keymap) Bind(context string, keys []Chord, command string) {
+	k.Unbind(context, keys)
+	k.bindings[context] = append(k.bindings[context], Binding{keys, command})
+}
+
+// Unbind removes bindings with the same keys or conflicting by prefix
+func (k *Keymap) Unbind(context string, keys []Chord) {
+	bindings := k.bindings[context][:0]
+	for _, b := range k.bindings[context] {
+		if !conflicts(b.Keys, keys) { bindings = append(bindings, b) }
+	}
+	k.bindings[context] = bindings
+}
+
+// Conflicts returns bindings which would be replaced by binding keys
+func (k *Keymap) Conflicts(context string, keys []Chord) []Binding {
+	bindings := []Binding{}
+	for _, b := range k.bindings[context] {
+		if conflicts(b.Keys, keys) { bindings = append(bindings, b) }
+	}
+	return bindings
+}
+
+// Lookup returns command bound to keys, or true if keys are the beginning of a longer sequence
+func (k *Keymap) Lookup(context string, keys []Chord) (string, bool) {
+	prefix := false
+	for _, b := range k.bindings[context] {
+		if len(b.Keys) == len(keys) && isPrefix(keys, b.Keys) { return b.Command, false }
+		if len(b.Keys) > len(keys) && isPrefix(keys, b.Keys) { prefix = true }
+	}
+	return "", prefix
+}
+
+// Bindings returns bindings of the context sorted by keys
+func (k *Keymap) Bindings(context string) []Binding {
+	bindings := append([]Binding{}, k.bindings[context]...)
+	sort.Slice(bindings, func(i, j int) bool { return FormatSequence(bindings[i].Keys) < FormatSequence(bindings[j].Keys) })
+	return bindings
+}
+
+// KeysOf returns the first key sequence bound to command, nil if not bound
+func (k *Keymap) KeysOf(context string, command string) []Chord {
+	for _, b := range k.Bindings(context) {
+		if b.Command == command { return b.Keys }
+	}
+	return nil
+}
+
+// one sequence can not be a beginning of another, the longer one would never be reached
+func conflicts(a, b []Chord) bool {
+	return isPrefix(a, b) || isPrefix(b, a)
+}
+
+func isPrefix(prefix, keys []Chord) bool {
+	if len(prefix) > len(keys) { return false }
+	for i := range prefix {
+		if prefix[i] != keys[i] { return false }
+	}
+	return true
+}
+
+// ChordOf converts key event to chord
+func ChordOf(ev *EventKey) Chord {
+	key, mod := ev.Key(), ev.Modifiers()
+	if key == KeyRune { return Chord{Key: KeyRune, Rune: ev.Rune(), Mod: mod &^ ModShift} }
+	if key < ' ' || key == KeyDEL { mod &^= ModCtrl }
+	return Chord{Key: key, Mod: mod}
+}
+
+var keyNames = map[string]Key{
+	"enter": KeyEnter, "tab": KeyTab, "backtab": KeyBacktab, "esc": KeyEscape, "escape": KeyEscape,
+	"backspace": KeyBackspace2, "delete": KeyDelete, "insert": KeyInsert,
+	"up": KeyUp, "down": KeyDown, "left": KeyLeft, "right": KeyRight,
+	"home": KeyHome, "end": KeyEnd, "pgup": KeyPgUp, "pgdn": KeyPgDn,
+}
+
+var modNames = map[string]ModMask{
+	"ctrl": ModCtrl, "control": ModCtrl, "alt": ModAlt, "option": ModAlt, "shift": ModShift, "meta": ModMeta,
+}
+
+// ParseChord parses chord like "ctrl+s", "alt+/", "ctrl+shift+up", "f2", "space" or "x"
+func ParseChord(s string) (Chord, error) {
+	if s == "" { return Chord{}, errors.New("empty key") }
+
+	// the last part is the key, it can be '+' itself
+	name, mods := s, []string{}
+	if i := strings.LastIndex(s[:len(s)-1], "+"); i >= 0 { name, mods = s[i+1:], strings.Split(s[:i], "+") }
+
+	var mod ModMask
+	for _, m := range mods {
+		flag, found := modNames[strings.ToLower(m)]
+		if !found { return Chord{}, fmt.Errorf("unknown modifier %q in %q", m, s) }
+		mod |= flag
+	}
+
+	lower := strings.ToLower(name)
+
+	if utf8.RuneCountInString(name) == 1 || lower == "space" {
+		ch, _ := utf8.DecodeRuneInString(name)
+		if lower == "space" { ch = ' ' }
+		if mod&ModCtrl != 0 { return ctrlChord(ch, mod, s) }
+		if mod&ModShift != 0 { ch = []rune(strings.ToUpper(string(ch)))[0] }
+		return Chord{Key: KeyRune, Rune: ch, Mod: mod &^ ModShift}, nil
+	}
+
+	if key, found := keyNames[lower]; found {
+		if key < ' ' || key == KeyDEL { mod &^= ModCtrl }
+		return Chord{Key: key, Mod: mod}, nil
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(lower, "f%d", &n); err == nil && n >= 1 && n <= 64 && lower == fmt.Sprintf("f%d", n) {
+		return Chord{Key: KeyF1 + Key(n-1), Mod: mod}, nil
+	}
+	return Chord{}, fmt.Errorf("unknown key %q in %q", name, s)
+}
+
+// ctrl with a char is sent by terminals as a control code
+func ctrlChord(ch rune, mod ModMask, s string) (Chord, error) {
+	if ch == ' ' { return Chord{Key: KeyCtrlSpace, Mod: mod &^ ModCtrl}, nil }
+	upper := []rune(strings.ToUpper(string(ch)))[0]
+	if upper < '@' || upper > '_' { return Chord{}, fmt.Errorf("%q can not be used with ctrl in %q", ch, s) }
+	return Chord{Key: Key(upper - '@'), Mod: mod &^ (ModCtrl | ModShift)}, nil
+}
+
+// ParseSequence parses space separated chords, like "ctrl+k ctrl+c"
+func ParseSequence(s string) ([]Chord, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 { return nil, errors.New("empty key sequence") }
+	keys := []Chord{}
+	for _, field := range fields {
+		chord, err := ParseChord(field)
+		if err != nil { return nil, err }
+		keys = append(keys, chord)
+	}
+	return keys, nil
+}
+
+func (c Chord) String() string {
+	mods := ""
+	if c.Mod&ModCtrl != 0 { mods += "ctrl+" }
+	if c.Mod&ModAlt != 0 { mods += "alt+" }
+	if c.Mod&ModMeta != 0 { mods += "meta+" }
+	if c.Mod&ModShift != 0 { mods += "shift+" }
+
+	switch {
+	case c.Key == KeyRune && c.Rune == ' ': return mods + "space"
+	case c.Key == KeyRune: return mods + string(c.Rune)
+	case c.Key == KeyCtrlSpace: return "ctrl+" + mods + "space"
+	case c.Key >= KeyF1 && c.Key <= KeyF64: return fmt.Sprintf("%sf%d", mods, c.Key-KeyF1+1)
+	}
+	for name, key := range keyNames {
+		if key == c.Key && name != "escape" { return mods + name }
+	}
+	if c.Key > 0 && c.Key < ' ' { return "ctrl+" + mods + strings.ToLower(string(rune(c.Key+'@'))) }
+	return mods + KeyNames[c.Key]
+}
+
+func FormatSequence(keys []Chord) string {
+	names := []string{}
+	for _, chord := range keys { names = append(names, chord.String()) }
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,74 @@
+package keymap
+
+import (
+	. "github.com/gdamore/tcell"
+	"testing"
+)
+
+func TestParseChord(t *testing.T) {
+	chords := map[string]Chord{
+		"ctrl+s":        {Key: KeyCtrlS},
+		"Ctrl+]":        {Key: KeyCtrlRightSq},
+		"ctrl+space":    {Key: KeyCtrlSpace},
+		"alt+/":         {Key: KeyRune, Rune: '/', Mod: ModAlt},
+		"alt++":         {Key: KeyRune, Rune: '+', Mod: ModAlt},
+		"ctrl+shift+up": {Key: KeyUp, Mod: ModCtrl | ModShift},
+		"option+right":  {Key: KeyRight, Mod: ModAlt},
+		"shift+f6":      {Key: KeyF6, Mod: ModShift},
+		"f18":           {Key: KeyF18},
+		"esc":           {Key: KeyEscape},
+		"space":         {Key: KeyRune, Rune: ' '},
+		"÷":             {Key: KeyRune, Rune: '÷'},
+	}
+	for s, expected := range chords {
+		chord, err := ParseChord(s)
+		if err != nil { t.Error(s, err); continue }
+		if chord != expected { t.Errorf("%s: got %+v, expected %+v", s, chord, expected) }
+	}
+
+	for _, s := range []string{"", "hyper+a", "ctrl+f99", "ctrl+backspacex", "ctrl+1", "ctrl++"} {
+		if _, err := ParseChord(s); err == nil { t.Error("expected error for", s) }
+	}
+}
+
+func TestChordOfEvent(t *testing.T) {
+	expected, _ := ParseChord("ctrl+s")
+	if ChordOf(NewEventKey(KeyRune, 0x13, ModNone)) != expected { t.Error("control code must match ctrl chord") }
+
+	expected, _ = ParseChord("alt+F")
+	if ChordOf(NewEventKey(KeyRune, 'F', ModAlt|ModShift)) != expected { t.Error("shift is a part of rune") }
+
+	expected, _ = ParseChord("ctrl+shift+down")
+	if ChordOf(NewEventKey(KeyDown, 0, ModCtrl|ModShift)) != expected { t.Error("modifiers of special keys must be kept") }
+}
+
+func TestSequences(t *testing.T) {
+	k := New()
+	save, _ := ParseSequence("ctrl+s")
+	bottom, _ := ParseSequence("ctrl+k")
+	comment, _ := ParseSequence("ctrl+k ctrl+c")
+	k.Bind(Editor, save, "save")
+	k.Bind(Editor, bottom, "go-bottom")
+
+	if command, _ := k.Lookup(Editor, save); command != "save" { t.Error("unexpected command", command) }
+	if command, _ := k.Lookup(Tree, save); command != "" { t.Error("contexts must be separate", command) }
+
+	k.Bind(Editor, comment, "comment")
+	if command, prefix := k.Lookup(Editor, bottom); command != "" || !prefix { t.Error("ctrl+k must become a prefix", command) }
+	if command, _ := k.Lookup(Editor, comment); command != "comment" { t.Error("unexpected command", command) }
+	if FormatSequence(k.KeysOf(Editor, "comment")) != "ctrl+k ctrl+c" { t.Error("unexpected keys", k.KeysOf(Editor, "comment")) }
+}
+
+func TestConflicts(t *testing.T) {
+	k := New()
+	comment, _ := ParseSequence("ctrl+k ctrl+c")
+	bottom, _ := ParseSequence("ctrl+k")
+	k.Bind(Editor, comment, "comment")
+
+	conflicts := k.Conflicts(Editor, bottom)
+	if len(conflicts) != 1 || conflicts[0].Command != "comment" { t.Error("ctrl+k must conflict with ctrl+k ctrl+c", conflicts) }
+	if len(k.Conflicts(Process, bottom)) != 0 { t.Error("contexts must be separate") }
+
+	k.Unbind(Editor, bottom)
+	if command, prefix := k.Lookup(Editor, bottom); command != "" || prefix { t.Error("sequence must be unbound") }
+}
@@ -12,6 +12,7 @@ import (
 	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -38,6 +39,11 @@ type LspClient struct {
 
 	id              int
 	file2diagnostic map[string]DiagnosticParams
+
+	sync     TextDocumentSync // how server wants documents to be synchronized
+	versions map[string]int   // opened documents versions
+	mu       sync.Mutex       // guards versions
+	sendMu   sync.Mutex       // keeps messages from interleaving
 }
 
 
@@ -118,6 +124,8 @@ func (this *LspClient) send(o interface{})  {
 
 	Log.Info("->", string(m))
 	message := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(m), m)
+	this.sendMu.Lock()
+	defer this.sendMu.Unlock()
 	_, err = this.stdin.Write([]byte(message))
 	if err != nil { Log.Error(err.Error()) }
 }
@@ -269,11 +277,11 @@ func (l *LspClient) Init(dir string) {
 	l.message2chan[id] = l.otherMessages
 	l.send(initializeRequest)
 
-	response, err := WaitForRequest[interface{}](l.otherMessages, 3000)
+	response, err := WaitForRequest[InitializeResponse](l.otherMessages, 3000)
 
 	delete(l.message2chan, id)
 
-	if response == "" || err != nil {
+	if err != nil {
 		Log.Info("cant get initialize response from lsp server")
 		l.IsReady = false
 		return
@@ -284,11 +292,18 @@ func (l *LspClient) Init(dir string) {
 	}
 	l.send(initializedRequest)
 
+	l.sync = response.Result.Capabilities.TextDocumentSync
+
 	Log.Info("lsp initialized")
 	l.IsReady = true
 }
 
 func (this *LspClient) DidOpen(file string, text *string) {
+	this.mu.Lock()
+	if this.versions == nil { this.versions = make(map[string]int) }
+	this.versions[file] = 1
+	this.mu.Unlock()
+
 	didOpenRequest := DidOpenRequest{
 		JSONRPC: "2.0",  Method:  "textDocument/didOpen",
 		Params: DidOpenParams{
@@ -305,6 +320,10 @@ func (this *LspClient) DidOpen(file string, text *string) {
 }
 
 func (this *LspClient) DidClose(file string) {
+	this.mu.Lock()
+	delete(this.versions, file)
+	this.mu.Unlock()
+
 	request := DidOpenRequest{
 		JSONRPC: "2.0",  Method:  "textDocument/didClose",
 		Params: DidOpenParams{
@@ -320,6 +339,53 @@ func (this *LspClient) DidClose(file string) {
 }
 
 
+func (this *LspClient) IsOpen(file string) bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	_, opened := this.versions[file]
+	return opened
+}
+
+// IsIncremental is true if server accepts changed ranges, otherwise whole text must be sent
+func (this *LspClient) IsIncremental() bool {
+	return this.sync.Change == SyncIncremental
+}
+
+// DidChange sends changes of an opened document, must be called in the same order edits are made
+func (this *LspClient) DidChange(file string, changes []ContentChange) {
+	if this.sync.Change == SyncNone { return }
+
+	this.mu.Lock()
+	version, opened := this.versions[file]
+	if opened { version++; this.versions[file] = version }
+	this.mu.Unlock()
+	if !opened { return }
+
+	request := DidChangeRequest{
+		Jsonrpc: "2.0", Method: "textDocument/didChange",
+		Params: DidChangeParams{
+			TextDocument: VersionedTextDocumentIdentifier{ URI: "file://" + file, Version: version },
+			ContentChanges: changes,
+		},
+	}
+
+	this.send(request)
+}
+
+// DidSave notifies that document was written, text is sent only if server asked for it
+func (this *LspClient) DidSave(file string, text func() string) {
+	if !this.sync.Save { return }
+
+	request := DidSaveRequest{
+		Jsonrpc: "2.0", Method: "textDocument/didSave",
+		Params: DidSaveParams{ TextDocument: TextDocument{ URI: "file://" + file } },
+	}
+	if this.sync.IncludeText { code := text(); request.Params.Text = &code }
+
+	this.send(request)
+}
+
+
 func (this *LspClient) Hover(file string, line int, character int) (HoverResponse, error) {
 	this.id++
 	id := this.id
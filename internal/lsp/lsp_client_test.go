@@ -3,6 +3,7 @@ package lsp
 import (
 	. "edgo/internal/logger"
 	"fmt"
+	"github.com/goccy/go-json"
 	"os"
 	"path"
 	"strings"
@@ -191,3 +192,18 @@ func TestLspClientReferences(t *testing.T) {
 	fmt.Println(response, err)
 	// todo fix, something wrong with base dir
 }
+
+func TestTextDocumentSync(t *testing.T) {
+	var kind InitializeResponse
+	json.Unmarshal([]byte(`{"result":{"capabilities":{"textDocumentSync":2}}}`), &kind)
+	if kind.Result.Capabilities.TextDocumentSync.Change != SyncIncremental {
+		t.Errorf("Expected incremental sync, got %+v", kind.Result.Capabilities.TextDocumentSync)
+	}
+
+	var options InitializeResponse
+	json.Unmarshal([]byte(`{"result":{"capabilities":{"textDocumentSync":{"change":1,"save":{"includeText":true}}}}}`), &options)
+	sync := options.Result.Capabilities.TextDocumentSync
+	if sync.Change != SyncFull || !sync.Save || !sync.IncludeText {
+		t.Errorf("Expected full sync with saved text, got %+v", sync)
+	}
+}
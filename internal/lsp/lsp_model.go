@@ -32,6 +32,60 @@ type InitializeRequest struct {
 	Params  InitializeParams `json:"params"`
 }
 
+type InitializeResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	Result  InitializeResult `json:"result"`
+	ID      int              `json:"id"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+}
+
+type ServerCapabilities struct {
+	TextDocumentSync TextDocumentSync `json:"textDocumentSync"`
+}
+
+// text document sync kinds
+const (
+	SyncNone        = 0
+	SyncFull        = 1
+	SyncIncremental = 2
+)
+
+// TextDocumentSync is sent by server either as a sync kind number or as options object
+type TextDocumentSync struct {
+	Change      int
+	Save        bool
+	IncludeText bool
+}
+
+func (s *TextDocumentSync) UnmarshalJSON(data []byte) error {
+	var kind int
+	if err := json.Unmarshal(data, &kind); err == nil {
+		s.Change = kind
+		return nil
+	}
+
+	var options struct {
+		Change int             `json:"change"`
+		Save   json.RawMessage `json:"save"`
+	}
+	if err := json.Unmarshal(data, &options); err != nil { return err }
+	s.Change = options.Change
+
+	// save is either boolean or {includeText: bool}
+	var save bool
+	var saveOptions struct { IncludeText bool `json:"includeText"` }
+	if json.Unmarshal(options.Save, &save) == nil {
+		s.Save = save
+	} else if json.Unmarshal(options.Save, &saveOptions) == nil {
+		s.Save = true
+		s.IncludeText = saveOptions.IncludeText
+	}
+	return nil
+}
+
 type Position struct {
 	Line      int `json:"line"`
 	Character int `json:"character"`
@@ -228,12 +282,17 @@ type Capabilities struct {
 }
 
 type CapabilitiesTextDocument struct {
+	Synchronization    Synchronization    `json:"synchronization"`
 	Hover              Hover              `json:"hover"`
 	PublishDiagnostics PublishDiagnostics `json:"publishDiagnostics"`
 	SignatureHelp      SignatureHelp      `json:"signatureHelp"`
 	Completion         Completion         `json:"completion"`
 }
 
+type Synchronization struct {
+	DidSave bool `json:"didSave"`
+}
+
 type Hover struct {
 	ContentFormat []string `json:"contentFormat"`
 }
@@ -271,6 +330,7 @@ type ResolveSupport struct {
 
 var capabilities = Capabilities{
 	CapabilitiesTextDocument: CapabilitiesTextDocument{
+		Synchronization: Synchronization{ DidSave: true },
 		Hover: Hover{
 			ContentFormat: []string{"plaintext", "markdown"},
 		},
@@ -394,15 +454,15 @@ type ChangeRange struct {
 	End   Character `json:"end"`
 }
 
+// ContentChange without range replaces the whole document
 type ContentChange struct {
-	Range ChangeRange  `json:"range"`
-	Text  string `json:"text"`
-	RangeLength int   `json:"rangeLength"`
+	Range *ChangeRange `json:"range,omitempty"`
+	Text  string       `json:"text"`
 }
 
 type DidChangeParams struct {
-	ContentChanges []ContentChange `json:"contentChanges"`
-	TextDocument   TextDocument    `json:"textDocument"`
+	ContentChanges []ContentChange                `json:"contentChanges"`
+	TextDocument   VersionedTextDocumentIdentifier `json:"textDocument"`
 }
 
 type DidChangeRequest struct {
@@ -412,7 +472,8 @@ type DidChangeRequest struct {
 }
 
 type DidSaveParams struct {
-	TextDocument   TextDocument    `json:"textDocument"`
+	TextDocument TextDocument `json:"textDocument"`
+	Text         *string      `json:"text,omitempty"`
 }
 
 type DidSaveRequest struct {
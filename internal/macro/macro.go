@@ -0,0 +1,43 @@
+package macro
+
+import (
+	"edgo/internal/keymap"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Macros are recorded key sequences by register name
+type Macros map[string][]keymap.Chord
+
+// Load reads macros saved by Save, missing file gives no macros
+func Load(path string) (Macros, error) {
+	macros := Macros{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) { return macros, nil }
+	if err != nil { return macros, err }
+
+	saved := map[string]string{}
+	if err := json.Unmarshal(data, &saved); err != nil { return macros, err }
+
+	for register, keys := range saved {
+		sequence, err := keymap.ParseSequence(keys)
+		if err != nil { return macros, fmt.Errorf("macro %s: %v", register, err) }
+		macros[register] = sequence
+	}
+	return macros, nil
+}
+
+// Save writes macros as readable key sequences, like {"a": "ctrl+f x enter"}
+func (m Macros) Save(path string) error {
+	saved := map[string]string{}
+	for register, keys := range m {
+		if len(keys) > 0 { saved[register] = keymap.FormatSequence(keys) }
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil { return err }
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil { return err }
+	return os.WriteFile(path, data, 0644)
+}
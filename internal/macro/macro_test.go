@@ -0,0 +1,35 @@
+package macro
+
+import (
+	"edgo/internal/keymap"
+	. "github.com/gdamore/tcell"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "macros.json")
+	keys := []keymap.Chord{
+		keymap.ChordOf(NewEventKey(KeyRune, 'A', ModShift)),
+		keymap.ChordOf(NewEventKey(KeyRune, ' ', ModNone)),
+		keymap.ChordOf(NewEventKey(KeyRune, '+', ModNone)),
+		keymap.ChordOf(NewEventKey(KeyCtrlS, 0, ModCtrl)),
+		keymap.ChordOf(NewEventKey(KeyDown, 0, ModShift)),
+		keymap.ChordOf(NewEventKey(KeyEnter, 0, ModNone)),
+		keymap.ChordOf(NewEventKey(KeyBackspace2, 0, ModNone)),
+		keymap.ChordOf(NewEventKey(KeyEscape, 0, ModNone)),
+	}
+
+	if err := (Macros{"a": keys, "b": nil}).Save(path); err != nil { t.Fatal(err) }
+	macros, err := Load(path)
+	if err != nil { t.Fatal(err) }
+
+	if !reflect.DeepEqual(macros["a"], keys) { t.Errorf("expected %v, got %v", keys, macros["a"]) }
+	if _, found := macros["b"]; found { t.Errorf("empty macro should not be saved") }
+}
+
+func TestLoadMissing(t *testing.T) {
+	macros, err := Load(filepath.Join(t.TempDir(), "macros.json"))
+	if err != nil || len(macros) != 0 { t.Errorf("missing file should give no macros, got %v %v", macros, err) }
+}
@@ -0,0 +1,140 @@
+package marks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+/*
+	Bookmarks are lines of files placed by the user, a named mark has a name unique in the project.
+	Marks of a project are kept in one file of the state dir.
+	Lines of marks follow edits of the file, a mark at a merged line goes to the line it merged into.
+*/
+
+type Mark struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Col  int    `json:"col,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type Marks []Mark
+
+// Path is the marks file of the project in cwd
+func Path(stateDir, cwd string) string {
+	sum := sha256.Sum256([]byte(cwd))
+	return filepath.Join(stateDir, "marks", filepath.Base(cwd)+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load reads marks saved by Save, missing file gives no marks
+func Load(path string) (Marks, error) {
+	marks := Marks{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) { return marks, nil }
+	if err != nil { return marks, err }
+	if err := json.Unmarshal(data, &marks); err != nil { return Marks{}, err }
+	return marks, nil
+}
+
+func (m Marks) Save(path string) error {
+	data, err := json.MarshalIndent(m.Sorted(), "", "  ")
+	if err != nil { return err }
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil { return err }
+	return os.WriteFile(path, data, 0644)
+}
+
+// Sorted returns a copy ordered by file and line
+func (m Marks) Sorted() Marks {
+	sorted := append(Marks{}, m...)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	return sorted
+}
+
+func less(a, b Mark) bool {
+	if a.File != b.File { return a.File < b.File }
+	return a.Line < b.Line
+}
+
+// At returns the mark at the line of the file, a named one first
+func (m Marks) At(file string, line int) (Mark, bool) {
+	found, ok := Mark{}, false
+	for _, mark := range m {
+		if mark.File != file || mark.Line != line { continue }
+		if mark.Name != "" { return mark, true }
+		found, ok = mark, true
+	}
+	return found, ok
+}
+
+func (m Marks) Named(name string) (Mark, bool) {
+	for _, mark := range m {
+		if mark.Name == name { return mark, true }
+	}
+	return Mark{}, false
+}
+
+// Toggle removes marks at the line, or adds a bookmark if there is none
+func (m Marks) Toggle(file string, line, col int) Marks {
+	kept := Marks{}
+	for _, mark := range m {
+		if mark.File != file || mark.Line != line { kept = append(kept, mark) }
+	}
+	if len(kept) == len(m) { kept = append(kept, Mark{File: file, Line: line, Col: col}) }
+	return kept
+}
+
+// Set places the named mark, it moves from where it was and replaces a bookmark at the line
+func (m Marks) Set(name, file string, line, col int) Marks {
+	kept := Marks{}
+	for _, mark := range m {
+		if mark.Name == name || mark.Name == "" && mark.File == file && mark.Line == line { continue }
+		kept = append(kept, mark)
+	}
+	return append(kept, Mark{File: file, Line: line, Col: col, Name: name})
+}
+
+// Remove deletes the mark equal to mark
+func (m Marks) Remove(mark Mark) Marks {
+	kept := Marks{}
+	for _, other := range m {
+		if other != mark { kept = append(kept, other) }
+	}
+	return kept
+}
+
+// Next returns the first mark after the line of the file, or before it if not forward, wrapping around
+func (m Marks) Next(file string, line int, forward bool) (Mark, bool) {
+	sorted := m.Sorted()
+	if len(sorted) == 0 { return Mark{}, false }
+	at := Mark{File: file, Line: line}
+	if forward {
+		for _, mark := range sorted {
+			if less(at, mark) { return mark, true }
+		}
+		return sorted[0], true
+	}
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if less(sorted[i], at) { return sorted[i], true }
+	}
+	return sorted[len(sorted)-1], true
+}
+
+// Shift moves marks of the file after count line breaks were inserted (or deleted if negative) at row and col
+func (m Marks) Shift(file string, row, col, count int) {
+	for i := range m {
+		mark := &m[i]
+		if mark.File != file || count == 0 { continue }
+		switch {
+		case count > 0 && (mark.Line > row || mark.Line == row && col == 0):
+			mark.Line += count
+		case count < 0 && mark.Line > row-count:
+			mark.Line += count
+		case count < 0 && mark.Line > row:
+			mark.Line = row
+		}
+	}
+}
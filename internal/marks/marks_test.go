@@ -0,0 +1,69 @@
+package marks
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := Path(t.TempDir(), "/home/user/project")
+	marks := Marks{{File: "/b.go", Line: 3}, {File: "/a.go", Line: 10, Col: 2, Name: "a"}}
+
+	if err := marks.Save(path); err != nil { t.Fatal(err) }
+	loaded, err := Load(path)
+	if err != nil { t.Fatal(err) }
+	if !reflect.DeepEqual(loaded, marks.Sorted()) { t.Errorf("expected %v, got %v", marks.Sorted(), loaded) }
+}
+
+func TestLoadMissing(t *testing.T) {
+	marks, err := Load(filepath.Join(t.TempDir(), "marks.json"))
+	if err != nil || len(marks) != 0 { t.Errorf("missing file should give no marks, got %v %v", marks, err) }
+}
+
+func TestToggleSet(t *testing.T) {
+	marks := Marks{}.Toggle("/a.go", 1, 0).Toggle("/a.go", 5, 0)
+	if len(marks) != 2 { t.Fatalf("expected 2 marks, got %v", marks) }
+	if marks = marks.Toggle("/a.go", 1, 4); len(marks) != 1 || marks[0].Line != 5 { t.Errorf("toggle should remove the mark, got %v", marks) }
+
+	marks = marks.Set("x", "/a.go", 5, 0).Set("y", "/b.go", 2, 0)
+	if len(marks) != 2 { t.Errorf("named mark should replace the bookmark, got %v", marks) }
+	marks = marks.Set("x", "/b.go", 7, 1)
+	if mark, found := marks.Named("x"); !found || mark.File != "/b.go" || mark.Line != 7 { t.Errorf("named mark should move, got %v", marks) }
+	if mark, found := marks.At("/b.go", 7); !found || mark.Name != "x" { t.Errorf("mark at line, got %v %v", mark, found) }
+}
+
+func TestNext(t *testing.T) {
+	marks := Marks{{File: "/b.go", Line: 1}, {File: "/a.go", Line: 8}, {File: "/a.go", Line: 2}}
+	tests := []struct {
+		file    string
+		line    int
+		forward bool
+		next    Mark
+	}{
+		{"/a.go", 0, true, marks[2]},
+		{"/a.go", 2, true, marks[1]},
+		{"/a.go", 9, true, marks[0]},
+		{"/b.go", 5, true, marks[2]}, // wraps around
+		{"/a.go", 8, false, marks[2]},
+		{"/a.go", 1, false, marks[0]},
+		{"/b.go", 1, false, marks[1]},
+	}
+	for _, test := range tests {
+		if next, _ := marks.Next(test.file, test.line, test.forward); next != test.next {
+			t.Errorf("%s:%d forward %v: %v, expected %v", test.file, test.line, test.forward, next, test.next)
+		}
+	}
+}
+
+func TestShift(t *testing.T) {
+	marks := Marks{{File: "/a.go", Line: 2}, {File: "/a.go", Line: 5}, {File: "/a.go", Line: 9}, {File: "/b.go", Line: 5}}
+	lines := func() []int { return []int{marks[0].Line, marks[1].Line, marks[2].Line, marks[3].Line} }
+
+	marks.Shift("/a.go", 3, 4, 2) // lines inserted below the first mark
+	if got := lines(); !reflect.DeepEqual(got, []int{2, 7, 11, 5}) { t.Errorf("insert: %v", got) }
+	marks.Shift("/a.go", 2, 0, 1) // line break at the start of the marked line
+	if got := lines(); !reflect.DeepEqual(got, []int{3, 8, 12, 5}) { t.Errorf("insert at line start: %v", got) }
+	marks.Shift("/a.go", 6, 3, -3) // lines 7..9 merged into 6
+	if got := lines(); !reflect.DeepEqual(got, []int{3, 6, 9, 5}) { t.Errorf("delete: %v", got) }
+}
@@ -7,10 +7,12 @@ type Selection struct {
 	Sex        int  // selection end x
 	Sey        int  // selection end y
 	IsSelected bool // true if selection is active
+	IsBlock    bool // rectangular selection, Ssx and Sex are visual columns
 }
 
 func (this *Selection) CleanSelection() {
 	this.IsSelected = false
+	this.IsBlock = false
 	this.Ssx, this.Ssy, this.Sex, this.Sey = -1, -1, -1, -1
 }
 
@@ -186,3 +188,61 @@ func (this *Selection) GetSelectedLines(content [][]rune)  []int {
 	}
 	return lineNumbers.GetKeys()
 }
+
+
+// visual column of rune index col, tab takes tabWidth columns as it is drawn
+func VisualColumn(line []rune, col int, tabWidth int) int {
+	visual := 0
+	for i := 0; i < col && i < len(line); i++ {
+		if line[i] == '\t' { visual += tabWidth } else { visual++ }
+	}
+	return visual
+}
+
+// rune index of the char covering visual column, len(line) if the line is shorter
+func RuneColumn(line []rune, visual int, tabWidth int) int {
+	position := 0
+	for i, ch := range line {
+		if ch == '\t' { position += tabWidth } else { position++ }
+		if position > visual { return i }
+	}
+	return len(line)
+}
+
+// first and last rows of the block selection
+func (this *Selection) BlockRows() (int, int) {
+	return Min(this.Ssy, this.Sey), Max(this.Ssy, this.Sey)
+}
+
+// left and right (exclusive) visual columns of the block selection
+func (this *Selection) BlockVisualColumns() (int, int) {
+	return Min(this.Ssx, this.Sex), Max(this.Ssx, this.Sex)
+}
+
+// rune range [from, to) of the line covered by block selection,
+// a tab partially covered by the block is included
+func (this *Selection) BlockColumns(line []rune, tabWidth int) (int, int) {
+	left, right := this.BlockVisualColumns()
+	from := RuneColumn(line, left, tabWidth)
+	to := from
+	for to < len(line) && VisualColumn(line, to, tabWidth) < right { to++ }
+	return from, to
+}
+
+func (this *Selection) IsUnderBlock(line []rune, x, y int, tabWidth int) bool {
+	top, bottom := this.BlockRows()
+	if y < top || y > bottom { return false }
+	from, to := this.BlockColumns(line, tabWidth)
+	return x >= from && x < to
+}
+
+// text of every block row
+func (this *Selection) GetBlockLines(content [][]rune, tabWidth int) []string {
+	lines := []string{}
+	top, bottom := this.BlockRows()
+	for j := top; j <= bottom && j < len(content); j++ {
+		from, to := this.BlockColumns(content[j], tabWidth)
+		lines = append(lines, string(content[j][from:to]))
+	}
+	return lines
+}
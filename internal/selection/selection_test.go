@@ -0,0 +1,30 @@
+package selection
+
+import (
+	"testing"
+)
+
+func TestVisualColumns(t *testing.T) {
+	line := []rune("\tab\tc")
+	if VisualColumn(line, 1, 4) != 4 { t.Error("tab must take tab width", VisualColumn(line, 1, 4)) }
+	if VisualColumn(line, 4, 4) != 10 { t.Error("unexpected visual column", VisualColumn(line, 4, 4)) }
+
+	if RuneColumn(line, 2, 4) != 0 { t.Error("column inside tab belongs to tab", RuneColumn(line, 2, 4)) }
+	if RuneColumn(line, 5, 4) != 2 { t.Error("unexpected rune column", RuneColumn(line, 5, 4)) }
+	if RuneColumn(line, 20, 4) != len(line) { t.Error("column after the end", RuneColumn(line, 20, 4)) }
+}
+
+func TestBlockLines(t *testing.T) {
+	content := [][]rune{[]rune("abcdef"), []rune("\txyz"), []rune("ab")}
+	selection := Selection{Ssx: 4, Ssy: 2, Sex: 1, Sey: 0, IsSelected: true, IsBlock: true}
+
+	lines := selection.GetBlockLines(content, 2)
+	expected := []string{"bcd", "\txy", "b"}
+	if len(lines) != len(expected) { t.Fatal("unexpected lines", lines) }
+	for i := range lines {
+		if lines[i] != expected[i] { t.Error("unexpected line", i, lines[i]) }
+	}
+
+	if !selection.IsUnderBlock(content[1], 1, 1, 2) { t.Error("x must be under block") }
+	if selection.IsUnderBlock(content[1], 3, 1, 2) { t.Error("z must not be under block") }
+}
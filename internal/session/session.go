@@ -0,0 +1,78 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+/*
+	Session is the state of the editor in a directory: opened files with cursors, the files tree,
+	panel sizes, breakpoints and search history. It is saved on quit and restored
+	on the next launch in the same directory.
+*/
+
+const MaxSearches = 50
+
+// File is an opened file with cursor and scroll positions
+type File struct {
+	Path string `json:"path"`
+	Row  int    `json:"row"`
+	Col  int    `json:"col"`
+	Y    int    `json:"y"`
+	X    int    `json:"x"`
+}
+
+type Session struct {
+	Files  []File `json:"files"`
+	Active string `json:"active"` // path of the current file
+
+	TreeSelected string   `json:"treeSelected,omitempty"` // path of the selected node of files tree
+	TreeOpen     []string `json:"treeOpen,omitempty"`     // opened directories of files tree
+
+	FilesPanelWidth    int `json:"filesPanelWidth"`
+	ProcessPanelHeight int `json:"processPanelHeight"`
+
+	Breakpoints map[string][]int `json:"breakpoints,omitempty"` // file -> lines
+	Searches    []string         `json:"searches,omitempty"`    // search patterns, the latest last
+}
+
+// Path is the session file of the project in cwd
+func Path(stateDir, cwd string) string {
+	sum := sha256.Sum256([]byte(cwd))
+	return filepath.Join(stateDir, "session", filepath.Base(cwd)+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// Load returns the saved session, false if there is none or it is broken
+func Load(path string) (Session, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil { return Session{}, false }
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil { return Session{}, false }
+	return s, true
+}
+
+func (s Session) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil { return err }
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil { return err }
+
+	// written to temp file first, the session is never half written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil { return err }
+	return os.Rename(tmp, path)
+}
+
+// AddSearch moves pattern to the end of search history, keeping at most MaxSearches patterns
+func AddSearch(searches []string, pattern string) []string {
+	if pattern == "" { return searches }
+	kept := []string{}
+	for _, old := range searches {
+		if old != pattern { kept = append(kept, old) }
+	}
+	kept = append(kept, pattern)
+	if len(kept) > MaxSearches { kept = kept[len(kept)-MaxSearches:] }
+	return kept
+}
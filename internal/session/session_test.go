@@ -0,0 +1,43 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := Path(t.TempDir(), "/home/user/project")
+	s := Session{
+		Files:  []File{{Path: "/home/user/project/main.go", Row: 10, Col: 4, Y: 2}, {Path: "/home/user/project/a.go"}},
+		Active: "/home/user/project/main.go",
+		TreeSelected: "/home/user/project/internal", TreeOpen: []string{"/home/user/project/internal"},
+		FilesPanelWidth: 30, ProcessPanelHeight: 8,
+		Breakpoints: map[string][]int{"/home/user/project/main.go": {3, 7}},
+		Searches:    []string{"func", "TODO"},
+	}
+
+	if err := s.Save(path); err != nil { t.Fatal(err) }
+	loaded, ok := Load(path)
+	if !ok || !reflect.DeepEqual(loaded, s) { t.Errorf("expected %+v, got %+v", s, loaded) }
+
+	if _, ok := Load(Path(t.TempDir(), "/home/user/other")); ok { t.Errorf("other project has no session") }
+}
+
+func TestLoadBroken(t *testing.T) {
+	path := Path(t.TempDir(), "/project")
+	s := Session{}
+	s.Save(path)
+	os.WriteFile(path, []byte("{"), 0644)
+	if _, ok := Load(path); ok { t.Errorf("broken session should not be loaded") }
+}
+
+func TestAddSearch(t *testing.T) {
+	searches := AddSearch(AddSearch(AddSearch(nil, "a"), "b"), "a")
+	searches = AddSearch(searches, "")
+	if fmt.Sprint(searches) != "[b a]" { t.Errorf("unexpected %v", searches) }
+
+	for i := 0; i < MaxSearches+5; i++ { searches = AddSearch(searches, fmt.Sprint(i)) }
+	if len(searches) != MaxSearches || searches[len(searches)-1] != fmt.Sprint(MaxSearches+4) { t.Errorf("history should be limited, got %d", len(searches)) }
+}
@@ -0,0 +1,90 @@
+package swap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+/*
+	Swap file keeps not saved content of a buffer, it is written periodically while the buffer is dirty
+	and removed after the buffer is saved. Swap files left by a crashed editor are found on the next start.
+*/
+
+type Swap struct {
+	File    string    // absolute path of the edited file
+	Content string    // not saved content
+	Time    time.Time // when swap was written
+	Pid     int       // editor process which wrote swap
+}
+
+// Path is the swap file of the file, keyed by its absolute path
+func Path(dir, file string) string {
+	sum := sha256.Sum256([]byte(file))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".swp")
+}
+
+func Write(dir string, s Swap) error {
+	data, err := json.Marshal(s)
+	if err != nil { return err }
+	if err := os.MkdirAll(dir, 0750); err != nil { return err }
+
+	// written to temp file first, crash while writing does not break the previous swap
+	path := Path(dir, s.File)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil { return err }
+	return os.Rename(tmp, path)
+}
+
+func Remove(dir, file string) {
+	os.Remove(Path(dir, file))
+}
+
+// Leftovers returns swaps of editors which are not running anymore, the latest first
+func Leftovers(dir string) []Swap {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.swp"))
+	swaps := []Swap{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil { continue }
+		var s Swap
+		if err := json.Unmarshal(data, &s); err != nil || s.File == "" { os.Remove(path); continue }
+		if s.Pid != os.Getpid() && isRunning(s.Pid) { continue }
+		swaps = append(swaps, s)
+	}
+	sort.Slice(swaps, func(i, j int) bool { return swaps[i].Time.After(swaps[j].Time) })
+	return swaps
+}
+
+func isRunning(pid int) bool {
+	if pid <= 0 { return false }
+	process, err := os.FindProcess(pid)
+	if err != nil { return false }
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// Diff returns lines of file content and swap content, marked with "- " and "+ " if they differ
+func Diff(fileContent, swapContent string) []string {
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(fileContent, swapContent)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lines)
+
+	result := []string{}
+	for _, diff := range diffs {
+		prefix := "  "
+		if diff.Type == diffmatchpatch.DiffInsert { prefix = "+ " }
+		if diff.Type == diffmatchpatch.DiffDelete { prefix = "- " }
+		for _, line := range strings.SplitAfter(diff.Text, "\n") {
+			if line == "" { continue }
+			result = append(result, prefix+strings.TrimSuffix(line, "\n"))
+		}
+	}
+	return result
+}
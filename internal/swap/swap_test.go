@@ -0,0 +1,35 @@
+package swap
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteLeftovers(t *testing.T) {
+	dir := t.TempDir()
+	Write(dir, Swap{File: "/a.go", Content: "a", Time: time.Now().Add(-time.Hour), Pid: 0})
+	Write(dir, Swap{File: "/b.go", Content: "b", Time: time.Now(), Pid: 0})
+
+	swaps := Leftovers(dir)
+	if len(swaps) != 2 || swaps[0].File != "/b.go" || swaps[1].Content != "a" {
+		t.Fatalf("expected both swaps, the latest first, got %+v", swaps)
+	}
+
+	Remove(dir, "/b.go")
+	if swaps = Leftovers(dir); len(swaps) != 1 { t.Errorf("removed swap should not be found, got %+v", swaps) }
+}
+
+func TestRunningEditorSwap(t *testing.T) {
+	dir := t.TempDir()
+	Write(dir, Swap{File: "/a.go", Pid: os.Getppid()}) // parent process is alive
+
+	if swaps := Leftovers(dir); len(swaps) != 0 { t.Errorf("swap of running editor is not a leftover, got %+v", swaps) }
+}
+
+func TestDiff(t *testing.T) {
+	diff := Diff("one\ntwo\nthree\n", "one\n2\nthree\n")
+	expected := []string{"  one", "- two", "+ 2", "  three"}
+	if !reflect.DeepEqual(diff, expected) { t.Errorf("expected %q, got %q", expected, diff) }
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"edgo/internal/highlighter"
 	"fmt"
 	. "github.com/smacker/go-tree-sitter"
 	"path/filepath"
@@ -20,7 +21,7 @@ func (this *GoTest) TestQuery() string {
 `
 }
 
-func (this *GoTest) Find(tfinder *TestFinder, root *Node, filename string, code []byte) map[int]TestData {
+func (this *GoTest) Find(tfinder *TestFinder, root *Node, filename string, code highlighter.Document) map[int]TestData {
 	if !strings.HasSuffix(filename, "test.go") { return nil }
 	return tfinder.Find(root, filename, code)
 }
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"edgo/internal/highlighter"
 	"context"
 	"fmt"
 	. "github.com/smacker/go-tree-sitter"
@@ -52,7 +53,7 @@ func Test2(t *testing.T) {
 	testFinder := TestFinder{TestQuery: q, Lang: lang}
 	node, _ := ParseCtx(context.Background(), codeBytes, language)
 
-	tests := test.Find(&testFinder, node, "example_test.go", codeBytes)
+	tests := test.Find(&testFinder, node, "example_test.go", highlighter.Bytes(codeBytes))
 	fmt.Println(tests)
 
 	if tests == nil { t.Errorf("tests cant be nil this case") }
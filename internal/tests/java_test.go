@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"edgo/internal/highlighter"
 	"context"
 	"fmt"
 	. "github.com/smacker/go-tree-sitter"
@@ -53,7 +54,7 @@ class MyFirstJUnitJupiterTests {
 	testFinder := TestFinder{TestQuery: q, Lang: lang}
 	node, _ := ParseCtx(context.Background(), codeBytes, language)
 
-	tests := test.Find(&testFinder, node, "test.java", codeBytes)
+	tests := test.Find(&testFinder, node, "test.java", highlighter.Bytes(codeBytes))
 	fmt.Println(tests)
 
 	if tests == nil { t.Errorf("tests cant be nil this case") }
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"edgo/internal/highlighter"
 	. "github.com/smacker/go-tree-sitter"
 	"strings"
 )
@@ -23,7 +24,7 @@ func (this *JavascriptTest) TestQuery() string {
 `
 }
 
-func (this *JavascriptTest) Find(tfinder *TestFinder, root *Node, filename string, code []byte) map[int]TestData {
+func (this *JavascriptTest) Find(tfinder *TestFinder, root *Node, filename string, code highlighter.Document) map[int]TestData {
 	if !strings.Contains(filename, "test") { return nil }
 	return tfinder.Find(root, filename, code)
 }
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"edgo/internal/highlighter"
 	"context"
 	"fmt"
 	. "github.com/smacker/go-tree-sitter"
@@ -67,7 +68,7 @@ describe("math tests", () => {
 	testFinder := TestFinder{TestQuery: q, Lang: lang}
 	node, _ := ParseCtx(context.Background(), codeBytes, language)
 
-	tests := test.Find(&testFinder, node, "test.js", codeBytes)
+	tests := test.Find(&testFinder, node, "test.js", highlighter.Bytes(codeBytes))
 	fmt.Println(tests)
 
 	if tests == nil { t.Errorf("tests cant be nil this case") }
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"edgo/internal/highlighter"
 	"fmt"
 	. "github.com/smacker/go-tree-sitter"
 	"strings"
@@ -27,7 +28,7 @@ func (this *PythonTest) TestQuery() string {
 `
 }
 
-func (this *PythonTest) Find(tfinder *TestFinder, root *Node, filename string, code []byte) map[int]TestData {
+func (this *PythonTest) Find(tfinder *TestFinder, root *Node, filename string, code highlighter.Document) map[int]TestData {
 	if !strings.Contains(filename, "test") { return nil }
 	return tfinder.Find(root, filename, code)
 }
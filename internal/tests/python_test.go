@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"edgo/internal/highlighter"
 	"context"
 	"fmt"
 	. "github.com/smacker/go-tree-sitter"
@@ -54,7 +55,7 @@ class TestYo:
 	testFinder := TestFinder{TestQuery: q, Lang: lang}
 	node, _ := ParseCtx(context.Background(), codeBytes, language)
 
-	tests := pythonTest.Find(&testFinder, node, "test_yo.py", codeBytes)
+	tests := pythonTest.Find(&testFinder, node, "test_yo.py", highlighter.Bytes(codeBytes))
 	fmt.Println(tests)
 
 	if tests == nil { t.Errorf("tests cant be nil this case") }
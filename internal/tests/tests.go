@@ -1,13 +1,14 @@
 package tests
 
 import (
+	"edgo/internal/highlighter"
 	. "github.com/smacker/go-tree-sitter"
 	"strings"
 )
 
 type Test interface {
 	TestQuery() string
-	Find(tfinder *TestFinder, root *Node, filename string, code []byte) map[int]TestData
+	Find(tfinder *TestFinder, root *Node, filename string, code highlighter.Document) map[int]TestData
 	Run(test TestData) []string
 }
 
@@ -23,7 +24,7 @@ type TestFinder struct {
 }
 
 
-func (this *TestFinder) Find(root *Node, filename string, code []byte) map[int]TestData {
+func (this *TestFinder) Find(root *Node, filename string, code highlighter.Document) map[int]TestData {
 	tests := make(map[int]TestData)
 
 	qc := NewQueryCursor()
@@ -32,11 +33,11 @@ func (this *TestFinder) Find(root *Node, filename string, code []byte) map[int]T
 	for {
 		m, ok := qc.NextMatch()
 		if !ok { break }
-		m = qc.FilterPredicates(m, code)
+		m = highlighter.FilterPredicates(this.TestQuery, m, code)
 		for i := range m.Captures {
 			c := m.Captures[i]; node := c.Node;
 			nodename := this.TestQuery.CaptureNameForId(c.Index)
-			content := node.Content(code)
+			content := highlighter.NodeText(node, code)
 			isTestFound := nodename == "test-name"
 			if isTestFound {
 				line := int(node.StartPoint().Row)
@@ -0,0 +1,265 @@
+package text
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+/*
+	PieceTable keeps the document as a list of pieces pointing either to the original
+	file content or to the append only add buffer. Edits never copy the document,
+	they only split pieces. Line starts are kept up to date on every edit,
+	so line, byte and rune positions can be converted without scanning the whole text.
+*/
+type PieceTable struct {
+	original   []byte  // content the document was created with, never modified
+	add        []byte  // every inserted text, only appended
+	pieces     []piece // document is the concatenation of pieces
+	length     int     // document length in bytes
+	lineStarts []int   // byte offsets of lines beginning, lineStarts[0] is always 0
+	starts     []int   // document offsets of pieces beginning, to find a piece by binary search
+	flat       []byte  // cached document bytes, nil if outdated
+}
+
+type piece struct {
+	add    bool // true if piece points to the add buffer
+	start  int  // start offset in the buffer
+	length int  // piece length in bytes
+}
+
+func New(content []byte) *PieceTable {
+	t := &PieceTable{ original: content, length: len(content), lineStarts: []int{0} }
+	if len(content) > 0 { t.pieces = []piece{{add: false, start: 0, length: len(content)}} }
+	for i, b := range content {
+		if b == '\n' { t.lineStarts = append(t.lineStarts, i+1) }
+	}
+	t.reindex()
+	return t
+}
+
+func NewFromLines(lines [][]rune) *PieceTable {
+	size := 0
+	for _, line := range lines { size += len(line) + 1 }
+
+	content := make([]byte, 0, size)
+	for i, line := range lines {
+		for _, ch := range line { content = utf8.AppendRune(content, ch) }
+		if i != len(lines)-1 { content = append(content, '\n') }
+	}
+	return New(content)
+}
+
+// document length in bytes
+func (t *PieceTable) Len() int { return t.length }
+
+func (t *PieceTable) LineCount() int { return len(t.lineStarts) }
+
+func (t *PieceTable) bytesOf(p piece) []byte {
+	if p.add { return t.add[p.start : p.start+p.length] }
+	return t.original[p.start : p.start+p.length]
+}
+
+// returns piece index and offset inside the piece for document offset
+func (t *PieceTable) locate(offset int) (int, int) {
+	if offset < 0 || offset >= t.length { return len(t.pieces), 0 }
+	i := sort.Search(len(t.starts), func(i int) bool { return t.starts[i] > offset }) - 1
+	return i, offset - t.starts[i]
+}
+
+// updates pieces offsets after an edit
+func (t *PieceTable) reindex() {
+	t.starts = t.starts[:0]
+	pos := 0
+	for _, p := range t.pieces {
+		t.starts = append(t.starts, pos)
+		pos += p.length
+	}
+}
+
+func (t *PieceTable) Insert(offset int, text []byte) {
+	if len(text) == 0 { return }
+	offset = clamp(offset, 0, t.length)
+
+	addStart := len(t.add)
+	t.add = append(t.add, text...)
+	newPiece := piece{add: true, start: addStart, length: len(text)}
+
+	i, inner := t.locate(offset)
+	if inner == 0 {
+		// typing usually continues the previous insert, just extend that piece
+		if i > 0 && t.pieces[i-1].add && t.pieces[i-1].start+t.pieces[i-1].length == addStart {
+			t.pieces[i-1].length += len(text)
+		} else {
+			t.pieces = append(t.pieces[:i], append([]piece{newPiece}, t.pieces[i:]...)...)
+		}
+	} else {
+		p := t.pieces[i]
+		left := piece{add: p.add, start: p.start, length: inner}
+		right := piece{add: p.add, start: p.start + inner, length: p.length - inner}
+		t.pieces = append(t.pieces[:i], append([]piece{left, newPiece, right}, t.pieces[i+1:]...)...)
+	}
+
+	t.length += len(text)
+	t.flat = nil
+	t.reindex()
+
+	line := t.LineOf(offset)
+	for j := line + 1; j < len(t.lineStarts); j++ { t.lineStarts[j] += len(text) }
+
+	newStarts := []int{}
+	for j, b := range text {
+		if b == '\n' { newStarts = append(newStarts, offset+j+1) }
+	}
+	if len(newStarts) > 0 {
+		t.lineStarts = append(t.lineStarts[:line+1], append(newStarts, t.lineStarts[line+1:]...)...)
+	}
+}
+
+func (t *PieceTable) Delete(offset int, length int) {
+	offset = clamp(offset, 0, t.length)
+	end := clamp(offset+length, offset, t.length)
+	if end == offset { return }
+
+	pieces := make([]piece, 0, len(t.pieces)+1)
+	pos := 0
+	for _, p := range t.pieces {
+		pStart, pEnd := pos, pos+p.length
+		pos = pEnd
+		if pEnd <= offset || pStart >= end { pieces = append(pieces, p); continue }
+		if pStart < offset {
+			pieces = append(pieces, piece{add: p.add, start: p.start, length: offset - pStart})
+		}
+		if pEnd > end {
+			pieces = append(pieces, piece{add: p.add, start: p.start + end - pStart, length: pEnd - end})
+		}
+	}
+	t.pieces = pieces
+	t.length -= end - offset
+	t.flat = nil
+	t.reindex()
+
+	lineStarts := t.lineStarts[:0]
+	for _, start := range t.lineStarts {
+		if start > offset && start <= end { continue } // line break was deleted
+		if start > end { start -= end - offset }
+		lineStarts = append(lineStarts, start)
+	}
+	t.lineStarts = lineStarts
+}
+
+// Chunk returns the document bytes from offset to the end of the piece containing it.
+// It does not copy, the result must not be modified.
+func (t *PieceTable) Chunk(offset int) []byte {
+	if offset < 0 || offset >= t.length { return nil }
+	i, inner := t.locate(offset)
+	return t.bytesOf(t.pieces[i])[inner:]
+}
+
+// Slice copies the bytes in [start, end) range
+func (t *PieceTable) Slice(start, end int) []byte {
+	start = clamp(start, 0, t.length)
+	end = clamp(end, start, t.length)
+
+	if t.flat != nil { return append([]byte{}, t.flat[start:end]...) }
+
+	result := make([]byte, 0, end-start)
+	for offset := start; offset < end; {
+		chunk := t.Chunk(offset)
+		if len(chunk) > end-offset { chunk = chunk[:end-offset] }
+		result = append(result, chunk...)
+		offset += len(chunk)
+	}
+	return result
+}
+
+// Bytes returns the whole document. The result is cached until the next edit and must not be modified.
+func (t *PieceTable) Bytes() []byte {
+	if t.flat == nil {
+		flat := make([]byte, 0, t.length)
+		for _, p := range t.pieces { flat = append(flat, t.bytesOf(p)...) }
+		t.flat = flat
+	}
+	return t.flat
+}
+
+func (t *PieceTable) String() string { return string(t.Bytes()) }
+
+// byte offset of the line beginning
+func (t *PieceTable) LineStart(line int) int {
+	if line < 0 { return 0 }
+	if line >= len(t.lineStarts) { return t.length }
+	return t.lineStarts[line]
+}
+
+// byte offset of the line end, line break is not included
+func (t *PieceTable) LineEnd(line int) int {
+	if line < 0 { return 0 }
+	if line+1 >= len(t.lineStarts) { return t.length }
+	return t.lineStarts[line+1] - 1
+}
+
+// LineEquals reports if the line is the same as runes, the line is compared in place without copying
+func (t *PieceTable) LineEquals(line int, runes []rune) bool {
+	if line < 0 || line >= len(t.lineStarts) { return false }
+	offset, end := t.LineStart(line), t.LineEnd(line)
+	var buf [utf8.UTFMax]byte
+	var chunk []byte
+	for _, r := range runes {
+		n := utf8.EncodeRune(buf[:], r)
+		for _, b := range buf[:n] {
+			if len(chunk) == 0 {
+				if offset >= end { return false }
+				chunk = t.Chunk(offset)
+				if len(chunk) > end-offset { chunk = chunk[:end-offset] }
+			}
+			if chunk[0] != b { return false }
+			chunk = chunk[1:]
+			offset++
+		}
+	}
+	return offset == end
+}
+
+func (t *PieceTable) Line(line int) []rune {
+	if line < 0 || line >= len(t.lineStarts) { return nil }
+	return []rune(string(t.Slice(t.LineStart(line), t.LineEnd(line))))
+}
+
+// returns the line containing byte offset
+func (t *PieceTable) LineOf(offset int) int {
+	return sort.Search(len(t.lineStarts), func(i int) bool { return t.lineStarts[i] > offset }) - 1
+}
+
+// converts line and rune column to byte offset
+func (t *PieceTable) Offset(row, col int) int {
+	if row >= len(t.lineStarts) { return t.length }
+	start, end := t.LineStart(row), t.LineEnd(row)
+	line := t.Slice(start, end)
+
+	offset := 0
+	for i := 0; i < col && offset < len(line); i++ {
+		_, size := utf8.DecodeRune(line[offset:])
+		offset += size
+	}
+	return start + offset
+}
+
+// converts byte offset to line and rune column
+func (t *PieceTable) Position(offset int) (int, int) {
+	offset = clamp(offset, 0, t.length)
+	row := t.LineOf(offset)
+	return row, utf8.RuneCount(t.Slice(t.LineStart(row), offset))
+}
+
+// converts byte offset to line and byte column, as tree-sitter points are
+func (t *PieceTable) Point(offset int) (int, int) {
+	offset = clamp(offset, 0, t.length)
+	row := t.LineOf(offset)
+	return row, offset - t.LineStart(row)
+}
+
+func clamp(value, min, max int) int {
+	if value < min { return min }
+	if value > max { return max }
+	return value
+}
@@ -0,0 +1,79 @@
+package text
+
+import (
+	"testing"
+)
+
+func TestInsertDelete(t *testing.T) {
+	text := New([]byte("hello world"))
+	text.Insert(5, []byte(","))
+	text.Insert(12, []byte("!"))
+	text.Insert(0, []byte(">> "))
+	if text.String() != ">> hello, world!" { t.Error("unexpected content", text.String()) }
+
+	text.Delete(0, 3)
+	text.Delete(5, 1)
+	if text.String() != "hello world!" { t.Error("unexpected content", text.String()) }
+	if text.Len() != len("hello world!") { t.Error("unexpected length", text.Len()) }
+}
+
+func TestTypingExtendsPiece(t *testing.T) {
+	text := New([]byte("ab"))
+	for i, ch := range "xyz" { text.Insert(1+i, []byte(string(ch))) }
+	if text.String() != "axyzb" { t.Error("unexpected content", text.String()) }
+	if len(text.pieces) != 3 { t.Error("typing must extend the same piece", len(text.pieces)) }
+}
+
+func TestLines(t *testing.T) {
+	text := New([]byte("one\ntwo\nthree"))
+	if text.LineCount() != 3 { t.Error("expected 3 lines", text.LineCount()) }
+
+	text.Insert(text.LineEnd(0), []byte("\nnew"))
+	if text.LineCount() != 4 { t.Error("expected 4 lines", text.LineCount()) }
+	if string(text.Line(1)) != "new" { t.Error("unexpected line", string(text.Line(1))) }
+	if string(text.Line(2)) != "two" { t.Error("unexpected line", string(text.Line(2))) }
+
+	text.Delete(text.LineEnd(1), len("\ntwo\n"))
+	if text.String() != "one\nnewthree" { t.Error("unexpected content", text.String()) }
+	if text.LineCount() != 2 { t.Error("expected 2 lines", text.LineCount()) }
+	if text.LineStart(1) != 4 { t.Error("unexpected line start", text.LineStart(1)) }
+}
+
+func TestOffsetPosition(t *testing.T) {
+	text := NewFromLines([][]rune{[]rune("привет"), []rune("мир go")})
+	if text.String() != "привет\nмир go" { t.Error("unexpected content", text.String()) }
+
+	offset := text.Offset(1, 4)
+	if offset != len("привет\nмир ") { t.Error("unexpected offset", offset) }
+
+	row, col := text.Position(offset)
+	if row != 1 || col != 4 { t.Error("unexpected position", row, col) }
+
+	row, col = text.Point(offset)
+	if row != 1 || col != len("мир ") { t.Error("unexpected point", row, col) }
+}
+
+func TestChunks(t *testing.T) {
+	text := New([]byte("hello world"))
+	text.Insert(5, []byte(" big"))
+
+	read := []byte{}
+	for offset := 0; offset < text.Len(); {
+		chunk := text.Chunk(offset)
+		read = append(read, chunk...)
+		offset += len(chunk)
+	}
+	if string(read) != "hello big world" { t.Error("unexpected content", string(read)) }
+	if string(text.Slice(3, 9)) != "lo big" { t.Error("unexpected slice", string(text.Slice(3, 9))) }
+}
+
+func TestLineEquals(t *testing.T) {
+	text := New([]byte("one\nпривет\n"))
+	text.Insert(len("one\nпри"), []byte("ве"))
+	text.Insert(0, []byte("zero "))
+	lines := [][]rune{[]rune("zero one"), []rune("привевет"), []rune("")}
+	for i, line := range lines {
+		if !text.LineEquals(i, line) { t.Errorf("line %d should equal %q", i, string(line)) }
+	}
+	if text.LineEquals(0, []rune("zero on")) || text.LineEquals(0, []rune("zero one!")) || text.LineEquals(3, nil) { t.Error("different lines should not be equal") }
+}
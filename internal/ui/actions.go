@@ -4,7 +4,7 @@ import (
 	"edgo/internal/highlighter"
 	. "edgo/internal/operations"
 	. "edgo/internal/utils"
-	"github.com/atotto/clipboard"
+	"slices"
 	"strings"
 )
 
@@ -12,15 +12,15 @@ import (
 func (e *Editor) OnDown() {
 	e.Update = false
 	if len(e.Content) == 0 { return }
-	if e.Row+1 >= len(e.Content) {
-		e.Y = e.Row - e.ROWS + 1
-		if e.Y < 0 { e.Y = 0; }
+	if e.softWrap { e.moveWrapped(1); return }
+	if e.nextVisibleLine(e.Row) >= len(e.Content) {
+		e.Y = e.linesAbove(e.Row, e.ROWS-1)
 		return
 	}
-	e.Row++
+	e.Row = e.nextVisibleLine(e.Row) // over folded lines
 	if e.Col > len(e.Content[e.Row]) { e.Col = len(e.Content[e.Row]) } // fit to e.Content
 	if e.Row < e.Y { e.Y = e.Row }
-	if e.Row >= e.Y+ e.ROWS { e.Y = e.Row - e.ROWS + 1  }
+	if e.screenRow(e.Row) >= e.ROWS { e.Y = e.linesAbove(e.Row, e.ROWS-1) }
 
 	e.Update = true
 	clear(e.HighlightElements)
@@ -29,11 +29,12 @@ func (e *Editor) OnDown() {
 func (e *Editor) OnUp() {
 	e.Update = false
 	if len(e.Content) == 0 { return }
+	if e.softWrap { e.moveWrapped(-1); return }
 	if e.Row == 0 { e.Y = 0; return }
-	e.Row--
+	e.Row = e.prevVisibleLine(e.Row)
 	if e.Col > len(e.Content[e.Row]) { e.Col = len(e.Content[e.Row]) } // fit to e.Content
 	if e.Row < e.Y { e.Y = e.Row }
-	if e.Row > e.Y+ e.ROWS { e.Y = e.Row - e.ROWS + 1  }
+	if e.screenRow(e.Row) > e.ROWS { e.Y = e.linesAbove(e.Row, e.ROWS-1) }
 	e.Update = true
 	clear(e.HighlightElements)
 }
@@ -145,6 +146,7 @@ func (e *Editor) FocusProcessPanel() {
 
 func (e *Editor) OnEnter() {
 	var ops = EditOperation{{Enter, '\n', e.Row, e.Col}}
+	enterRow, enterCol := e.Row, e.Col
 	tabs := CountTabs(e.Content[e.Row], e.Col)
 	spaces := CountSpaces(e.Content[e.Row], e.Col)
 
@@ -158,29 +160,28 @@ func (e *Editor) OnEnter() {
 	countToInsert := tabs
 	characterToInsert := '\t'
 	if tabs == 0 && spaces != 0 { characterToInsert = ' '; countToInsert = spaces }
+	begining := []rune(strings.Repeat(string(characterToInsert), countToInsert)) // when there are no indent rules
 
-	begining := []rune{}
-	for i := 0; i < countToInsert; i++ {
-		begining = append(begining, characterToInsert)
-		ops = append(ops, Operation{Insert, characterToInsert, e.Row, e.Col + i})
-	}
-	e.Col = countToInsert
-
-	newline := append(begining, after...)
-	e.Content = InsertTo(e.Content, e.Row, newline)
+	e.Content = InsertTo(e.Content, e.Row, append([]rune{}, after...))
+	e.insertText(enterRow, enterCol, "\n")
 
-	contentToString := ConvertContentToString(e.Content)
-	e.treeSitterHighlighter.AddCharEdit(&contentToString, e.Row, max(e.Col,0), '\n')
+	// enter between brackets moves the closing one to the next line
+	if _, smart := e.smartIndent(e.Row); smart && isBracketPair(before, after) {
+		ops = append(ops, Operation{Enter, '\n', e.Row, 0})
+		e.Content = InsertTo(e.Content, e.Row, []rune{})
+		e.insertText(e.Row, 0, "\n")
+		e.indentNewLine(e.Row+1, begining, &ops)
+	}
+	e.Col = e.indentNewLine(e.Row, begining, &ops)
 
-	e.Undo = append(e.Undo, ops)
+	e.UndoTree.Push(ops)
 	e.Focus(); if e.Row- e.Y == e.ROWS { e.OnScrollDown() }
 	e.OnCursorChanged()
-	if len(e.Redo) > 0 { e.Redo = []EditOperation{} }
 	e.Update = true
 	e.IsContentChanged = true
 	e.FindTests()
 
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
 func (e *Editor) OnDelete() {
@@ -190,12 +191,15 @@ func (e *Editor) OnDelete() {
 		return
 	}
 
-	if e.Col > 0 {
+	if e.inEmptyPair() {
+		e.deletePair()
+		e.OnCursorChanged()
+	} else if e.Col > 0 {
 		e.Col--
 		e.DeleteCharacter(e.Row, e.Col)
 		e.OnCursorChanged()
 	} else if e.Row > 0 { // delete line
-		e.Undo = append(e.Undo, EditOperation{{DeleteLine, ' ', e.Row -1, len(e.Content[e.Row-1])}})
+		e.UndoTree.Push(EditOperation{{DeleteLine, ' ', e.Row -1, len(e.Content[e.Row-1])}})
 		left := e.Content[e.Row][e.Col:]
 		e.Content = Remove(e.Content, e.Row)
 
@@ -203,48 +207,49 @@ func (e *Editor) OnDelete() {
 		e.Col = len(e.Content[e.Row])
 		e.Content[e.Row] = append(e.Content[e.Row], left...)
 
-		code := ConvertContentToString(e.Content)
-		e.treeSitterHighlighter.RemoveCharEdit(&code, e.Row, e.Col, '\n')
+		e.deleteText(e.Row, e.Col, "\n")
 		e.OnCursorChanged()
 	}
 
 	e.Focus()
-	if len(e.Redo) > 0 { e.Redo = []EditOperation{} }
 	e.Update = true
 	e.IsContentChanged = true
 	e.FindTests()
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
 func (e *Editor) OnTab() {
 	e.Focus()
 
 	selectedLines := e.Selection.GetSelectedLines(e.Content)
+	unit := e.indentUnit()
 
 	if len(selectedLines) == 0 {
-		ch := '\t'
-		e.InsertCharacter(e.Row, e.Col, ch)
-		e.Col++
+		var ops = EditOperation{}
+		for i, ch := range unit { ops = append(ops, Operation{Insert, ch, e.Row, e.Col + i}) }
+		e.Content[e.Row] = slices.Insert(e.Content[e.Row], e.Col, unit...)
+		e.insertText(e.Row, e.Col, string(unit))
+		e.UndoTree.Push(ops)
+		e.Col += len(unit)
 		e.OnCursorChanged()
 	} else  {
 		var ops = EditOperation{}
 		e.Selection.Ssx = 0
 		for _, linenumber := range selectedLines {
 			e.Row = linenumber
-			e.Content[e.Row] = InsertTo(e.Content[e.Row], 0, '\t')
-			ops = append(ops, Operation{Insert, '\t', e.Row, 0})
+			e.Content[e.Row] = slices.Insert(e.Content[e.Row], 0, unit...)
+			for i, ch := range unit { ops = append(ops, Operation{Insert, ch, e.Row, i}) }
 			e.Col = len(e.Content[e.Row])
 		}
 		e.Selection.Sex = e.Col
-		e.Undo = append(e.Undo, ops)
+		e.UndoTree.Push(ops)
 		e.UpdateColors()
 	}
 
-	if len(e.Redo) > 0 { e.Redo = []EditOperation{} }
 	e.Update = true
 	e.IsContentChanged = true
 	e.FindTests()
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
 func (e *Editor) OnBackTab() {
@@ -252,57 +257,70 @@ func (e *Editor) OnBackTab() {
 
 	selectedLines := e.Selection.GetSelectedLines(e.Content)
 
-	// deleting tabs from beginning
+	// deleting a tab or indentation spaces from beginning
+	var ops = EditOperation{}
 	if len(selectedLines) == 0 {
-		if e.Content[e.Row][0] == '\t'  {
-			e.DeleteCharacter(e.Row,0)
-			e.Col--
-		}
+		removed := e.unindentLine(e.Row, &ops)
+		e.deleteText(e.Row, 0, strings.Repeat(" ", removed)) // tab is one byte too
+		e.Col = Max(e.Col-removed, 0)
 	} else {
 		e.Selection.Ssx = 0
 		for _, linenumber := range selectedLines {
 			e.Row = linenumber
-			if len(e.Content[e.Row]) > 0 && e.Content[e.Row][0] == '\t'  {
-				e.DeleteCharacter(e.Row,0)
-				e.Col = len(e.Content[e.Row])
-			}
+			e.unindentLine(e.Row, &ops)
+			e.Col = len(e.Content[e.Row])
 		}
 		e.UpdateColors()
 	}
+	if len(ops) > 0 { e.UndoTree.Push(ops) }
 
 
-	if len(e.Redo) > 0 { e.Redo = []EditOperation{} }
 	e.Update = true
 	e.IsContentChanged = true
 	e.FindTests()
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
+}
+
+// removes one level of indentation at the beginning of the line, returns count of removed chars
+func (e *Editor) unindentLine(row int, ops *EditOperation) int {
+	line := e.Content[row]
+	count := 0
+	if len(line) > 0 && line[0] == '\t' { count = 1 } else {
+		for count < e.indentSpaces && count < len(line) && line[count] == ' ' { count++ }
+	}
+
+	for i := count - 1; i >= 0; i-- { *ops = append(*ops, Operation{Delete, line[i], row, i}) }
+	e.Content[row] = line[count:]
+	return count
 }
 
 func (e *Editor) AddChar(ch rune) {
+	if e.surroundSelection(ch) { return }
 	if len(e.Selection.GetSelectionString(e.Content)) != 0 { e.Cut(false) }
 
 	e.Focus()
+	if e.skipsCloser(ch) { e.Col++; e.OnCursorChanged(); e.Update = true; return }
+	closeChar, pair := e.autoPair(ch)
 	e.InsertCharacter(e.Row, e.Col, ch)
 	e.Col++
 
-	e.MaybeAddPair(ch)
+	if pair { e.InsertCharacter(e.Row, e.Col, closeChar) }
+	e.reindentOnClose(ch)
 	e.OnCursorChanged()
 
-	if len(e.Redo) > 0 { e.Redo = []EditOperation{} }
 
 	e.Update = true
 	e.IsContentChanged = true
 	e.FindTests()
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
 func (e *Editor) InsertCharacter(line, pos int, ch rune) {
 	e.Content[line] = InsertTo(e.Content[line], pos, ch)
 	//if lsp.isReady { go lsp.didChange(AbsoluteFilePath, Line, pos, Line, pos, string(ch)) }
-	e.Undo = append(e.Undo, EditOperation{{Insert, ch, e.Row, e.Col}})
+	e.UndoTree.Push(EditOperation{{Insert, ch, e.Row, e.Col}})
 
-	code := ConvertContentToString(e.Content)
-	e.treeSitterHighlighter.AddCharEdit(&code, line, pos, ch)
+	e.insertText(line, pos, string(ch))
 }
 
 func (e *Editor) InsertString(line, pos int, linestring string) {
@@ -319,7 +337,7 @@ func (e *Editor) InsertString(line, pos int, linestring string) {
 		pos++
 	}
 	e.Col = pos
-	e.Undo = append(e.Undo, ops)
+	e.UndoTree.Push(ops)
 }
 
 func (e *Editor) InsertLines(line, pos int, lines []string) {
@@ -351,20 +369,20 @@ func (e *Editor) InsertLines(line, pos int, lines []string) {
 		e.Row++
 	}
 	e.Row--
-	e.Undo = append(e.Undo, ops)
+	e.shiftMarks(line, 0, len(lines))
+	e.UndoTree.Push(ops)
 }
 
 func (e *Editor) DeleteCharacter(line, pos int) {
 	ch := e.Content[line][pos]
-	e.Undo = append(e.Undo, EditOperation{
+	e.UndoTree.Push(EditOperation{
 		{MoveCursor, ch, line, pos+1},
 		{Delete, ch, line, pos},
 	})
 
 	e.Content[line] = Remove(e.Content[line], pos)
 
-	code := ConvertContentToString(e.Content)
-	e.treeSitterHighlighter.RemoveCharEdit(&code, line, pos, ch)
+	e.deleteText(line, pos, string(ch))
 }
 
 func (e *Editor) OnSwapLinesUp() {
@@ -385,12 +403,12 @@ func (e *Editor) OnSwapLinesUp() {
 	e.Row--
 
 	e.UpdateColors()
-	e.Undo = append(e.Undo, ops)
+	e.UndoTree.Push(ops)
 	e.Selection.CleanSelection()
 	e.Update = true
 	e.IsContentChanged = true
 	e.FindTests()
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
 func (e *Editor) OnSwapLinesDown() {
@@ -412,17 +430,17 @@ func (e *Editor) OnSwapLinesDown() {
 	e.Row++
 
 	e.UpdateColors()
-	e.Undo = append(e.Undo, ops)
+	e.UndoTree.Push(ops)
 	e.Selection.CleanSelection()
 	e.Update = true
 	e.IsContentChanged = true
 	e.FindTests()
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
 func (e *Editor) OnCopy() {
 	selectionString := e.Selection.GetSelectionString(e.Content)
-	clipboard.WriteAll(selectionString)
+	e.copyText(selectionString)
 }
 
 func (e *Editor) OnSelectMoreAtCursor() {
@@ -459,16 +477,23 @@ func (e *Editor) OnSelectAll() {
 }
 
 func (e *Editor) OnPaste() {
+	text := e.pasteText()
+	e.paste(text, e.isBlockClipboard())
+}
+
+// pastes text replacing the selection, block text goes line by line to the rows below
+func (e *Editor) paste(text string, block bool) {
+	if block { e.OnBlockPaste(text); return }
 
 	if e.Selection.IsSelectionNonEmpty() {
 		e.Cut(false)
 	}
 
-	text, _ := clipboard.ReadAll()
 	lines := strings.Split(text, "\n")
 
 	if len(lines) == 0 { return }
 
+	from := e.Row
 	if len(lines) == 1 { // single Line paste
 		e.InsertString(e.Row, e.Col, lines[0])
 	}
@@ -476,6 +501,7 @@ func (e *Editor) OnPaste() {
 	if len(lines) > 1 { // multiple Line paste
 		e.InsertLines(e.Row, e.Col, lines)
 	}
+	e.pasted = pasteRange{e.UndoTree.Current, from, e.Row}
 	
 	e.Update = true
 	e.UpdateNeeded()
@@ -510,19 +536,20 @@ func (e *Editor) Cut(isCopySelected bool) {
 		}
 
 		e.Content = Remove(e.Content, e.Row)
+		e.shiftMarks(ops[len(ops)-1].Line, ops[len(ops)-1].Column, -1)
 		if e.Row > 0 { e.Row-- }
 
 		e.UpdateColors()
 		e.Update = true
 		e.IsContentChanged = true
-		if len(e.Content) <= 10000 { go e.WriteFile() }
+		e.AutoSave()
 		e.UpdateNeeded() // optimize
 
 	} else { // cut selection
 
 		if isCopySelected {
 			selectionString := e.Selection.GetSelectionString(e.Content)
-			clipboard.WriteAll(selectionString)
+			e.copyText(selectionString)
 		}
 
 		ops = append(ops, Operation{MoveCursor, ' ', e.Row, e.Col})
@@ -542,8 +569,7 @@ func (e *Editor) Cut(isCopySelected bool) {
 				ops = append(ops, Operation{Delete, ch, yd, xd})
 				e.Content[yd] = append(e.Content[yd][:xd], e.Content[yd][xd+1:]...)
 
-				code := ConvertContentToString(e.Content)
-				e.treeSitterHighlighter.RemoveCharEdit(&code, yd, xd, ch)
+				e.deleteText(yd, xd, string(ch))
 			}
 
 			if len(e.Content[yd]) == 0 { // delete Line
@@ -553,8 +579,7 @@ func (e *Editor) Cut(isCopySelected bool) {
 
 				e.Content = append(e.Content[:yd], e.Content[yd+1:]...)
 
-				code := ConvertContentToString(e.Content)
-				e.treeSitterHighlighter.RemoveCharEdit(&code, e.Col, e.Row, '\n')
+				if yd > 0 { e.deleteText(yd-1, len(e.Content[yd-1]), "\n") } else { e.deleteText(0, 0, "\n") }
 			}
 		}
 
@@ -573,12 +598,12 @@ func (e *Editor) Cut(isCopySelected bool) {
 		e.Selection.CleanSelection()
 		e.Update = true
 		e.IsContentChanged = true
-		if len(e.Content) <= 10000 { go e.WriteFile() }
+		e.AutoSave()
 
 		e.UpdateNeeded() // optimize
 	}
 
-	e.Undo = append(e.Undo, ops)
+	e.UndoTree.Push(ops)
 }
 
 func (e *Editor) Duplicate() {
@@ -599,13 +624,14 @@ func (e *Editor) Duplicate() {
 		}
 		e.Row++
 		e.Content = InsertTo(e.Content, e.Row, duplicatedSlice)
+		e.shiftMarks(e.Row-1, len(duplicatedSlice), 1)
 
 		e.UpdateColors()
-		e.Undo = append(e.Undo, ops)
+		e.UndoTree.Push(ops)
 		e.Update = true
 		e.IsContentChanged = true
 		e.FindTests()
-		if len(e.Content) <= 10000 { go e.WriteFile() }
+		e.AutoSave()
 
 	} else {
 		selection := e.Selection.GetSelectionString(e.Content)
@@ -636,7 +662,7 @@ func (e *Editor) OnCursorBackUndo() {
 	e.CursorHistoryUndo = e.CursorHistoryUndo[:len(e.CursorHistoryUndo)-1]
 
 
-	if lastCursor.Filename != e.Filename {
+	if lastCursor.Filename != e.AbsoluteFilePath {
 		e.OpenFile(lastCursor.Filename)
 	}
 
@@ -657,7 +683,7 @@ func (e *Editor) OnCursorBack() {
 		 CursorMove{e.AbsoluteFilePath, e.Row, e.Col, e.Y, e.X},
 	)
 
-	if lastCursor.Filename != e.Filename {
+	if lastCursor.Filename != e.AbsoluteFilePath {
 		e.OpenFile(lastCursor.Filename)
 	}
 
@@ -670,12 +696,24 @@ func (e *Editor) OnCursorBack() {
 }
 
 func (e *Editor) OnUndo() {
-	if len(e.Undo) == 0 { return }
-
-	lastOperation := e.Undo[len(e.Undo)-1]
-	e.Undo = e.Undo[:len(e.Undo)-1]
+	lastOperation, found := e.UndoTree.Undo()
+	if !found { return }
 	e.Focus()
+	e.revertOperation(lastOperation)
+	e.UpdateColors()
+	e.UpdateNeeded()
+}
 
+func (e *Editor) OnRedo() {
+	lastRedoOperation, found := e.UndoTree.Redo()
+	if !found { return }
+	e.applyOperation(lastRedoOperation)
+	e.UpdateColors()
+	e.UpdateNeeded()
+}
+
+// reverts operations of one undo step, from the last one
+func (e *Editor) revertOperation(lastOperation EditOperation) {
 	for i := len(lastOperation) - 1; i >= 0; i-- {
 		o := lastOperation[i]
 
@@ -692,9 +730,13 @@ func (e *Editor) OnUndo() {
 			e.Content[o.Line] = append(e.Content[o.Line], e.Content[o.Line+1]...)
 			e.Content = append(e.Content[:o.Line+1], e.Content[o.Line+2:]...)
 			e.Row = o.Line; e.Col = o.Column
+			e.shiftFolds(o.Line, -1)
+			e.shiftMarks(o.Line, o.Column, -1)
 
 		} else if o.Action == DeleteLine {
 			// Insert enter
+			e.shiftFolds(o.Line, 1)
+			e.shiftMarks(o.Line, o.Column, 1)
 			e.Row = o.Line; e.Col = o.Column
 			after := e.Content[e.Row][e.Col:]
 			before := e.Content[e.Row][:e.Col]
@@ -707,17 +749,10 @@ func (e *Editor) OnUndo() {
 		}
 		e.OnCursorChanged()
 	}
-
-	e.UpdateColors()
-	e.Redo = append(e.Redo, lastOperation)
-	e.UpdateNeeded()
 }
-func (e *Editor) OnRedo() {
-	if len(e.Redo) == 0 { return }
-
-	lastRedoOperation := e.Redo[len(e.Redo)-1]
-	e.Redo = e.Redo[:len(e.Redo)-1]
 
+// applies operations of one undo step again
+func (e *Editor) applyOperation(lastRedoOperation EditOperation) {
 	for i := 0; i < len(lastRedoOperation); i++ {
 		o := lastRedoOperation[i]
 
@@ -729,6 +764,8 @@ func (e *Editor) OnRedo() {
 			e.Row = o.Line; e.Col = o.Column
 			e.Content[e.Row] = append(e.Content[e.Row][:e.Col], e.Content[e.Row][e.Col+1:]...)
 		} else if o.Action == Enter {
+			e.shiftFolds(o.Line, 1)
+			e.shiftMarks(o.Line, o.Column, 1)
 			e.Row = o.Line; e.Col = o.Column
 			after := e.Content[e.Row][e.Col:]
 			before := e.Content[e.Row][:e.Col]
@@ -741,91 +778,13 @@ func (e *Editor) OnRedo() {
 			e.Content[o.Line] = append(e.Content[o.Line], e.Content[o.Line+1]...)
 			e.Content = append(e.Content[:o.Line+1], e.Content[o.Line+2:]...)
 			e.Row = o.Line; e.Col = o.Column
+			e.shiftFolds(o.Line, -1)
+			e.shiftMarks(o.Line, o.Column, -1)
 		} else if o.Action == MoveCursor {
 			e.Row = o.Line; e.Col = o.Column
 		}
 	}
-
-	e.UpdateColors()
-	e.Undo = append(e.Undo, lastRedoOperation)
-	e.UpdateNeeded()
-}
-func (e *Editor) OnCommentLine() {
-	e.Focus()
-
-	found := false
-
-	for i, ch := range e.Content[e.Row] {
-		if len(e.Content[e.Row]) == 0 { break }
-
-		if len(e.langConf.Comment) == 1 && ch == rune(e.langConf.Comment[0]) {
-			// found 1 char comment, uncomment
-			e.Col = i
-			e.Undo = append(e.Undo, EditOperation{
-				{MoveCursor, ch, e.Row, i+1},
-				{Delete, ch, e.Row, i},
-			})
-			e.Content[e.Row] = Remove(e.Content[e.Row], i)
-
-			code := ConvertContentToString(e.Content)
-			e.treeSitterHighlighter.RemoveCharEdit(&code, e.Row, i, ch)
-
-			found = true
-			break
-		}
-		if len(e.langConf.Comment) == 2 && ch == rune(e.langConf.Comment[0]) && e.Content[e.Row][i+1] == rune(e.langConf.Comment[1]) {
-			// found 2 char comment, uncomment
-			e.Col = i
-			e.Undo = append(e.Undo, EditOperation{
-				{MoveCursor, ch, e.Row, i+1},
-				{Delete, ch, e.Row, i},
-				{MoveCursor, e.Content[e.Row][i+1], e.Row, i+1},
-				{Delete, ch, e.Row, i},
-			})
-			e.Content[e.Row] = Remove(e.Content[e.Row], i)
-			e.Content[e.Row] = Remove(e.Content[e.Row], i)
-
-			code := ConvertContentToString(e.Content)
-			e.treeSitterHighlighter.RemoveCharEdit(&code, e.Row, i, ch)
-			e.treeSitterHighlighter.RemoveCharEdit(&code, e.Row, i, ch)
-
-			found = true
-			break
-		}
-	}
-
-	if found {
-		if e.Col < 0 { e.Col = 0 }
-		e.OnDown()
-		e.Update = true
-		e.IsContentChanged = true
-		if len(e.Content) <= 10000 { go e.WriteFile() }
-		return
-	}
-
-	tabs := CountTabs(e.Content[e.Row], e.Col)
-	spaces := CountSpaces(e.Content[e.Row], e.Col)
-
-	from := tabs
-	if tabs == 0 && spaces != 0 { from = spaces }
-
-	e.Col = from
-	ops := EditOperation{}
-	for _, ch := range e.langConf.Comment {
-		e.Content[e.Row] = InsertTo(e.Content[e.Row], from, ch)
-		code := ConvertContentToString(e.Content)
-		e.treeSitterHighlighter.AddCharEdit(&code, e.Row, from, ch)
-		ops = append(ops, Operation{Insert, ch, e.Row, from})
-	}
-
-	e.Undo = append(e.Undo, ops)
-	if e.Col < 0 { e.Col = 0 }
-	e.OnDown()
-	e.Update = true
-	e.IsContentChanged = true
-	if len(e.Content) <= 10000 { go e.WriteFile() }
 }
-
 func (e *Editor) HandleSmartMove(char rune) {
 	e.Focus()
 	if char == 'f' || char == 'F' {
@@ -839,87 +798,41 @@ func (e *Editor) HandleSmartMove(char rune) {
 	}
 }
 
-func (e *Editor) HandleSmartMoveAlac(char int) {
-	e.Focus()
-	if char == 259 {
-		nw := FindNextWord(e.Content[e.Row], e.Col+ 1)
-		e.Col = nw
-		e.Col = Min(e.Col, len(e.Content[e.Row]))
-	}
-	if char == 260 {
-		nw := FindPrevWord(e.Content[e.Row], e.Col-1)
-		e.Col = nw
-	}
-}
-
 func (e *Editor) HandleSmartMoveDown() {
 
-	var ops = EditOperation{{Enter, '\n', e.Row, e.Col}}
+	var ops = EditOperation{{Enter, '\n', e.Row, len(e.Content[e.Row])}}
 
-	// moving down, insert new Line, add same amount of tabs
-	tabs := CountTabs(e.Content[e.Row], e.Col)
-	spaces := CountSpaces(e.Content[e.Row], e.Col)
-
-	countToInsert := tabs
-	characterToInsert := '\t'
-	if tabs == 0 && spaces != 0 { characterToInsert = ' '; countToInsert = spaces }
+	// moving down, insert new Line, indent it by syntax or as the current one
+	indent := leadingWhitespace(e.Content[e.Row])
 
+	e.insertText(e.Row, len(e.Content[e.Row]), "\n")
 	e.Row++; e.Col = 0
 	e.Content = InsertTo(e.Content, e.Row, []rune{})
-	for i := 0; i < countToInsert; i++ {
-		e.Content[e.Row] = InsertTo(e.Content[e.Row], e.Col, characterToInsert)
-		ops = append(ops, Operation{Insert, characterToInsert, e.Row, e.Col})
-		e.Col++
-	}
+	e.Col = e.indentNewLine(e.Row, indent, &ops)
 
 	e.UpdateColors()
 	e.Focus(); e.OnScrollDown()
-	e.Undo = append(e.Undo, ops)
+	e.UndoTree.Push(ops)
 	e.Update = true
 	e.IsContentChanged = true
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
 func (e *Editor) HandleSmartMoveUp() {
 	e.Focus()
-	// add new Line and shift all lines, add same amount of tabs/spaces
-	tabs := CountTabs(e.Content[e.Row], e.Col)
-	spaces := CountSpaces(e.Content[e.Row], e.Col)
-
-	countToInsert := tabs
-	characterToInsert := '\t'
-	if tabs == 0 && spaces != 0 { characterToInsert = ' '; countToInsert = spaces }
+	// add new Line and shift all lines, indent it by syntax or as the current one
+	indent := leadingWhitespace(e.Content[e.Row])
 
-	var ops = EditOperation{{Enter, '\n', e.Row, e.Col}}
+	var ops = EditOperation{{Enter, '\n', e.Row, 0}}
 	e.Content = InsertTo(e.Content, e.Row, []rune{})
+	e.insertText(e.Row, 0, "\n")
 
-	e.Col = 0
-	for i := 0; i < countToInsert; i++ {
-		e.Content[e.Row] = InsertTo(e.Content[e.Row], e.Col, characterToInsert)
-		ops = append(ops, Operation{Insert, characterToInsert, e.Row, e.Col})
-		e.Col++
-	}
+	e.Col = e.indentNewLine(e.Row, indent, &ops)
 
 	e.UpdateColors()
-	e.Undo = append(e.Undo, ops)
+	e.UndoTree.Push(ops)
 	e.Update = true
 	e.IsContentChanged = true
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
-func (e *Editor) MaybeAddPair(ch rune) {
-	pairMap := map[rune]rune{
-		'(': ')', '{': '}', '[': ']',
-		'"': '"', '\'': '\'', '`': '`',
-	}
-
-	if closeChar, found := pairMap[ch]; found {
-		noMoreChars := e.Col >= len(e.Content[e.Row])
-		isSpaceNext := e.Col < len(e.Content[e.Row]) && e.Content[e.Row][e.Col] == ' '
-		isStringAndClosedBracketNext := closeChar == '"' && e.Col < len(e.Content[e.Row]) && e.Content[e.Row][e.Col] == ')'
-
-		if noMoreChars || isSpaceNext || isStringAndClosedBracketNext {
-			e.InsertCharacter(e.Row, e.Col, closeChar)
-		}
-	}
-}
@@ -0,0 +1,208 @@
+package ui
+
+import (
+	. "edgo/internal/operations"
+	. "edgo/internal/selection"
+	. "edgo/internal/utils"
+	. "github.com/gdamore/tcell"
+	"strings"
+)
+
+/*
+	Block selection is e.Selection with IsBlock set. Ssy and Sey are rows as usual,
+	Ssx and Sex are visual columns, so the rectangle stays straight on lines with tabs.
+	Zero width block works as a column cursor: typing inserts on every row.
+*/
+
+// handles block selection keys, returns false if the key is not for the block
+func (e *Editor) HandleBlockKeyboard(key Key, ev *EventKey, modifiers ModMask) bool {
+	if !e.Selection.IsBlock { return false }
+	if e.isBoundTo(ev, "block-select-up", "block-select-down", "block-select-left", "block-select-right") { return false }
+
+	if key == KeyRune && modifiers&(ModAlt|ModCtrl) == 0 { e.OnBlockType(ev.Rune()); return true }
+	if key == KeyBackspace || key == KeyBackspace2 { e.OnBlockDelete(); return true }
+	if e.isBoundTo(ev, "copy") { e.OnBlockCopy(); return true }
+	if e.isBoundTo(ev, "cut") { e.OnBlockCopy(); e.OnBlockDelete(); return true }
+	if e.isBoundTo(ev, "paste") { e.OnBlockPaste(e.pasteText()); return true }
+	if key == KeyEscape { e.Selection.CleanSelection(); return true }
+
+	// other actions work without block
+	e.Selection.CleanSelection()
+	return false
+}
+
+// starts or extends block selection from the cursor
+func (e *Editor) OnBlockSelect(key Key) {
+	if len(e.Content) == 0 { return }
+	if !e.Selection.IsBlock {
+		visual := VisualColumn(e.Content[e.Row], e.Col, e.langTabWidth)
+		e.Selection = Selection{Ssx: visual, Ssy: e.Row, Sex: visual, Sey: e.Row, IsSelected: true, IsBlock: true}
+	}
+
+	if key == KeyUp && e.Selection.Sey > 0 { e.Selection.Sey-- }
+	if key == KeyDown && e.Selection.Sey < len(e.Content)-1 { e.Selection.Sey++ }
+	if key == KeyLeft && e.Selection.Sex > 0 { e.Selection.Sex-- }
+	if key == KeyRight { e.Selection.Sex++ }
+
+	e.Row = e.Selection.Sey
+	e.Col = RuneColumn(e.Content[e.Row], e.Selection.Sex, e.langTabWidth)
+	e.Update = true
+}
+
+// extends block selection to the mouse position, visual is the screen column.
+// Block starts at the mouse down cell, not at the cursor
+func (e *Editor) OnBlockDrag(row, visual int, start bool) {
+	if len(e.Content) == 0 { return }
+	if row > len(e.Content)-1 { row = len(e.Content) - 1 }
+	if start || !e.Selection.IsBlock {
+		e.Selection = Selection{Ssx: visual, Ssy: row, Sex: visual, Sey: row, IsBlock: true}
+	}
+	e.Selection.Sex, e.Selection.Sey = visual, row
+	e.Selection.IsSelected = true
+
+	e.Row = row
+	e.Col = RuneColumn(e.Content[e.Row], visual, e.langTabWidth)
+	e.Update = true
+}
+
+func (e *Editor) OnBlockCopy() {
+	text := strings.Join(e.Selection.GetBlockLines(e.Content, e.langTabWidth), "\n")
+	e.copyText(text)
+	e.blockClipboard = text
+}
+
+// replaces block contents with ch on every row long enough to reach the block
+func (e *Editor) OnBlockType(ch rune) {
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+	e.deleteBlock(&ops)
+
+	left, _ := e.Selection.BlockVisualColumns()
+	top, bottom := e.Selection.BlockRows()
+	for row := top; row <= bottom && row < len(e.Content); row++ {
+		line := e.Content[row]
+		if VisualColumn(line, len(line), e.langTabWidth) < left { continue }
+		col := RuneColumn(line, left, e.langTabWidth)
+		e.Content[row] = InsertTo(line, col, ch)
+		ops = append(ops, Operation{Insert, ch, row, col})
+		if row == e.Selection.Sey { e.Col = col + 1 }
+	}
+
+	width := 1
+	if ch == '\t' { width = e.langTabWidth }
+	e.Selection.Ssx, e.Selection.Sex = left+width, left+width
+	e.Row = e.Selection.Sey
+	e.finishBlockEdit(ops)
+}
+
+// deletes block contents, or the char before zero width block on every row
+func (e *Editor) OnBlockDelete() {
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+	left, right := e.Selection.BlockVisualColumns()
+
+	if left != right {
+		e.deleteBlock(&ops)
+	} else {
+		if left == 0 { return }
+		top, bottom := e.Selection.BlockRows()
+		newLeft := left
+		for row := top; row <= bottom && row < len(e.Content); row++ {
+			line := e.Content[row]
+			col := RuneColumn(line, left, e.langTabWidth)
+			if col == 0 || VisualColumn(line, col, e.langTabWidth) != left { continue }
+			ops = append(ops, Operation{Delete, line[col-1], row, col - 1})
+			e.Content[row] = append(line[:col-1], line[col:]...)
+			newLeft = Min(newLeft, VisualColumn(e.Content[row], col-1, e.langTabWidth))
+		}
+		left = newLeft
+	}
+
+	e.Selection.Ssx, e.Selection.Sex = left, left
+	e.Row = e.Selection.Sey
+	e.Col = RuneColumn(e.Content[e.Row], left, e.langTabWidth)
+	e.finishBlockEdit(ops)
+}
+
+// pastes lines of the text one per row, replacing the block if any
+func (e *Editor) OnBlockPaste(text string) {
+	lines := strings.Split(text, "\n")
+
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+	top, left := e.Row, VisualColumn(e.Content[e.Row], e.Col, e.langTabWidth)
+	if e.Selection.IsBlock {
+		e.deleteBlock(&ops)
+		top, _ = e.Selection.BlockRows()
+		left, _ = e.Selection.BlockVisualColumns()
+	}
+
+	for i, s := range lines {
+		row := top + i
+		if row >= len(e.Content) {
+			last := len(e.Content) - 1
+			ops = append(ops, Operation{Enter, '\n', last, len(e.Content[last])})
+			e.Content = append(e.Content, []rune{})
+		}
+
+		// short lines are padded with spaces up to the block column
+		for VisualColumn(e.Content[row], len(e.Content[row]), e.langTabWidth) < left {
+			ops = append(ops, Operation{Insert, ' ', row, len(e.Content[row])})
+			e.Content[row] = append(e.Content[row], ' ')
+		}
+
+		col := RuneColumn(e.Content[row], left, e.langTabWidth)
+		for _, ch := range s {
+			e.Content[row] = InsertTo(e.Content[row], col, ch)
+			ops = append(ops, Operation{Insert, ch, row, col})
+			col++
+		}
+		e.Row, e.Col = row, col
+	}
+
+	e.Selection.CleanSelection()
+	e.finishBlockEdit(ops)
+}
+
+// true if the latest copied text is the last copied block, so it has to be pasted as block.
+// History is read as it is, text copied outside gets into it on paste
+func (e *Editor) isBlockClipboard() bool {
+	history := e.clip().History
+	return e.blockClipboard != "" && len(history) > 0 && history[0] == e.blockClipboard
+}
+
+// removes the block contents from every row
+func (e *Editor) deleteBlock(ops *EditOperation) {
+	top, bottom := e.Selection.BlockRows()
+	for row := top; row <= bottom && row < len(e.Content); row++ {
+		from, to := e.Selection.BlockColumns(e.Content[row], e.langTabWidth)
+		for i := to - 1; i >= from; i-- {
+			*ops = append(*ops, Operation{Delete, e.Content[row][i], row, i})
+		}
+		e.Content[row] = append(e.Content[row][:from], e.Content[row][to:]...)
+	}
+}
+
+func (e *Editor) finishBlockEdit(ops EditOperation) {
+	if len(ops) <= 1 { return }
+	e.UndoTree.Push(ops)
+	e.UpdateNeeded()
+}
+
+func (e *Editor) isUnderSelection(x, y int) bool {
+	if e.Selection.IsBlock { return e.Selection.IsUnderBlock(e.Content[y], x, y, e.langTabWidth) }
+	return e.Selection.IsUnderSelection(x, y)
+}
+
+// draws zero width block as a column of cursors
+func (e *Editor) DrawBlockCursor() {
+	if !e.Selection.IsBlock || e.Selection.Ssx != e.Selection.Sex || e.X != 0 || e.softWrap { return }
+	top, bottom := e.Selection.BlockRows()
+	for row := top; row <= bottom && row < len(e.Content); row++ {
+		y := e.screenRow(row)
+		if y < 0 || y >= e.ROWS || row == e.Row { continue }
+		if VisualColumn(e.Content[row], len(e.Content[row]), e.langTabWidth) < e.Selection.Ssx { continue }
+
+		x := e.Selection.Ssx + e.LINES_WIDTH + e.FilesPanelWidth
+		if x >= e.COLUMNS { continue }
+		mainc, _, style, _ := e.Screen.GetContent(x, y)
+		e.Screen.SetContent(x, y, mainc, nil, style.Reverse(true))
+	}
+}
@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"edgo/internal/clipboard"
+	. "edgo/internal/selection"
+	. "github.com/gdamore/tcell"
+	"testing"
+)
+
+func TestBlockDragStartsAtMouse(t *testing.T) {
+	e := testEditor("abcdef\nabcdef\nabcdef")
+	e.Row, e.Col = 2, 5
+
+	e.OnBlockDrag(0, 1, true)
+	e.OnBlockDrag(1, 3, false)
+	if s := e.Selection; s.Ssy != 0 || s.Ssx != 1 || s.Sey != 1 || s.Sex != 3 { t.Errorf("block should start at mouse down cell, got %+v", s) }
+
+	e.OnBlockDrag(2, 2, true) // new drag over the block
+	if s := e.Selection; s.Ssy != 2 || s.Ssx != 2 { t.Errorf("new drag should start a new block, got %+v", s) }
+}
+
+func TestBlockKeysFollowKeymap(t *testing.T) {
+	e := testEditor("abcdef\nabcdef")
+	e.clipboard = clipboard.New(clipboard.Internal)
+	e.Keymap, _ = NewKeymap(map[string]map[string]string{"editor": {"ctrl+c": "none", "ctrl+k": "copy"}})
+	e.Selection = Selection{Ssx: 1, Ssy: 0, Sex: 3, Sey: 1, IsSelected: true, IsBlock: true}
+
+	if !e.HandleBlockKeyboard(KeyCtrlK, NewEventKey(KeyCtrlK, 0, ModCtrl), ModCtrl) || e.blockClipboard != "bc\nbc" {
+		t.Errorf("rebound copy should copy the block, got %q", e.blockClipboard)
+	}
+	if e.HandleBlockKeyboard(KeyCtrlC, NewEventKey(KeyCtrlC, 0, ModCtrl), ModCtrl) { t.Errorf("unbound ctrl+c should not copy the block") }
+}
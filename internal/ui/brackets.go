@@ -0,0 +1,96 @@
+package ui
+
+import (
+	. "edgo/internal/highlighter"
+	"strings"
+)
+
+/*
+	Brackets are taken from the tree-sitter tree, so those in strings and comments have no pairs.
+	The pair at the cursor, or just before it, is highlighted; rainbow colors visible brackets by nesting level.
+	Languages without grammar and large files have no bracket matching.
+*/
+
+func (e *Editor) hasSyntaxTree() bool {
+	return !e.NoHighlight && e.treeSitterHighlighter != nil && e.treeSitterHighlighter.HasGrammar()
+}
+
+// bracket with a pair at the cursor or before it
+func (e *Editor) cursorBracket() (Bracket, bool) {
+	if !e.hasSyntaxTree() || e.Row >= len(e.Content) { return Bracket{}, false }
+	b, found := e.treeSitterHighlighter.MatchBracket(e.Text.Offset(e.Row, e.Col))
+	if (!found || b.Match < 0) && e.Col > 0 {
+		b, found = e.treeSitterHighlighter.MatchBracket(e.Text.Offset(e.Row, e.Col-1))
+	}
+	return b, found && b.Match >= 0
+}
+
+// finds the pair at the cursor and levels of visible brackets for drawing
+func (e *Editor) findBrackets() {
+	e.bracketMatch, e.bracketLevels = [2]int{-1, -1}, nil
+	if b, found := e.cursorBracket(); found { e.bracketMatch = [2]int{b.Start, b.Match} }
+	if !e.Config.Rainbow || !e.hasSyntaxTree() { return }
+
+	last := e.lineAtRow(e.ROWS)
+	e.bracketLevels = map[int]int{}
+	for _, b := range e.treeSitterHighlighter.Brackets(e.Text.LineStart(e.Y), e.Text.LineStart(last+1)) {
+		if b.Match >= 0 { e.bracketLevels[b.Start] = b.Level }
+	}
+}
+
+// pair at the cursor, or the innermost one around it, as offsets of the opening and closing brackets
+func (e *Editor) bracketPair() (int, int, bool) {
+	if !e.hasSyntaxTree() { return 0, 0, false }
+	b, found := e.treeSitterHighlighter.MatchBracket(e.Text.Offset(e.Row, e.Col))
+	if !found || b.Match < 0 {
+		b, found = e.treeSitterHighlighter.EnclosingBrackets(e.Text.Offset(e.Row, e.Col))
+	}
+	if !found { return 0, 0, false }
+	return min(b.Start, b.Match), max(b.Start, b.Match), true
+}
+
+// jumps to the pair of the bracket at the cursor, or to the opening bracket around it
+func (e *Editor) OnMatchBracket() {
+	target := -1
+	if b, found := e.cursorBracket(); found {
+		target = b.Match
+	} else if open, _, found := e.bracketPair(); found {
+		target = open
+	}
+	if target < 0 { return }
+
+	e.Row, e.Col = e.Text.Position(target)
+	e.Selection.CleanSelection()
+	e.Update = true
+}
+
+// selects text between the brackets, without the line breaks after the opening and before the closing one
+func (e *Editor) OnSelectInsideBrackets() {
+	open, close, found := e.bracketPair()
+	if !found { return }
+	sy, sx := e.Text.Position(open + 1)
+	ey, ex := e.Text.Position(close)
+	if sx == len(e.Content[sy]) && sy < ey { sy, sx = sy+1, 0 }
+	if strings.TrimSpace(string(e.Content[ey][:ex])) == "" && ey > sy { ey, ex = ey-1, len(e.Content[ey-1]) }
+	e.selectRange(sy, sx, ey, ex)
+}
+
+func (e *Editor) OnSelectAroundBrackets() {
+	open, close, found := e.bracketPair()
+	if !found { return }
+	sy, sx := e.Text.Position(open)
+	ey, ex := e.Text.Position(close + 1)
+	e.selectRange(sy, sx, ey, ex)
+}
+
+func (e *Editor) selectRange(sy, sx, ey, ex int) {
+	e.Selection.Ssx, e.Selection.Ssy = sx, sy
+	e.Selection.Sex, e.Selection.Sey = ex, ey
+	e.Selection.IsSelected = true
+	e.Update = true
+}
+
+func (e *Editor) OnToggleRainbow() {
+	e.Config.Rainbow = !e.Config.Rainbow
+	e.Update = true
+}
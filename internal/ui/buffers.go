@@ -0,0 +1,346 @@
+package ui
+
+import (
+	. "edgo/internal/config"
+	"edgo/internal/editorconfig"
+	"edgo/internal/fileformat"
+	. "edgo/internal/highlighter"
+	. "edgo/internal/search"
+	. "edgo/internal/selection"
+	"edgo/internal/text"
+	"edgo/internal/undo"
+	. "edgo/internal/utils"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"path/filepath"
+	"sort"
+)
+
+// Buffer keeps everything related to one opened file,
+// so switching between files does not reload it from disk and does not lose history.
+type Buffer struct {
+	Filename         string // file name
+	AbsoluteFilePath string // file name and directory
+	InputFile        string // exact user input
+
+	Lang         string // file language
+	langConf     Lang   // lang conf
+	langTabWidth int    // lang tabs indentation
+	indentSpaces int
+	editorConfig editorconfig.Properties
+	softWrap     bool
+	folds        []fold
+	foldLines    int
+
+	Content [][]rune        // text characters
+	Text    *text.PieceTable // same text as piece table
+
+	Format        fileformat.Format
+	IsFullyLoaded bool
+	NoHighlight   bool
+	loader        *fileLoader
+	lineEndings   *fileformat.Endings
+
+	Row int // cursor position row
+	Col int // cursor position column
+	Y   int // row offset for scrolling
+	X   int // col offset for scrolling
+
+	Selection Selection
+
+	UndoTree *undo.Tree
+
+	IsContentChanged bool
+
+	treeSitterHighlighter *TreeSitterHighlighter
+}
+
+// storeBuffer saves current editor state to the active buffer
+func (e *Editor) storeBuffer() {
+	if e.BufferIndex < 0 || e.BufferIndex >= len(e.Buffers) { return }
+	b := e.Buffers[e.BufferIndex]
+
+	b.Filename = e.Filename
+	b.AbsoluteFilePath = e.AbsoluteFilePath
+	b.InputFile = e.InputFile
+	b.Lang = e.Lang
+	b.langConf = e.langConf
+	b.langTabWidth = e.langTabWidth
+	b.indentSpaces, b.editorConfig, b.softWrap = e.indentSpaces, e.editorConfig, e.softWrap
+	b.folds, b.foldLines = e.folds, e.foldLines
+	b.Content = e.Content
+	b.Text = e.Text
+	b.Format = e.Format
+	b.IsFullyLoaded, b.NoHighlight, b.loader, b.lineEndings = e.IsFullyLoaded, e.NoHighlight, e.loader, e.lineEndings
+	b.Row, b.Col, b.Y, b.X = e.Row, e.Col, e.Y, e.X
+	b.Selection = e.Selection
+	b.UndoTree = e.UndoTree
+	b.IsContentChanged = e.IsContentChanged
+	b.treeSitterHighlighter = e.treeSitterHighlighter
+}
+
+// restoreBuffer loads buffer state to the editor
+func (e *Editor) restoreBuffer(b *Buffer) {
+	e.Filename = b.Filename
+	e.AbsoluteFilePath = b.AbsoluteFilePath
+	e.InputFile = b.InputFile
+	e.setLang(b.Lang)
+	e.langConf = b.langConf
+	e.langTabWidth = b.langTabWidth
+	e.indentSpaces, e.editorConfig, e.softWrap = b.indentSpaces, b.editorConfig, b.softWrap
+	e.folds, e.foldLines, e.foldCache = b.folds, b.foldLines, nil
+	e.Content = b.Content
+	e.Text = b.Text
+	e.Format = b.Format
+	e.IsFullyLoaded, e.NoHighlight, e.loader, e.lineEndings = b.IsFullyLoaded, b.NoHighlight, b.loader, b.lineEndings
+	e.Row, e.Col, e.Y, e.X = b.Row, b.Col, b.Y, b.X
+	e.Selection = b.Selection
+	e.UndoTree = b.UndoTree
+	e.IsContentChanged = b.IsContentChanged
+	e.treeSitterHighlighter = b.treeSitterHighlighter
+}
+
+func (e *Editor) FindBuffer(absoluteFilePath string) int {
+	for i, b := range e.Buffers {
+		if b.AbsoluteFilePath == absoluteFilePath { return i }
+	}
+	return -1
+}
+
+func (e *Editor) SwitchBuffer(index int) {
+	if index < 0 || index >= len(e.Buffers) { return }
+	if index == e.BufferIndex && e.AbsoluteFilePath == e.Buffers[index].AbsoluteFilePath { return }
+
+	e.autoSaveOnLeave()
+	e.storeBuffer()
+	e.BufferIndex = index
+	e.restoreBuffer(e.Buffers[index])
+	e.resetWrap()
+	e.CleanCursors()
+
+	clear(e.HighlightElements)
+	e.TreePath = nil
+	e.SearchResults = []SearchResult{}
+
+	e.FileWatcher.UpdateFile(e.AbsoluteFilePath)
+	e.FileWatcher.UpdateStats()
+	e.FindTests()
+	e.lspOpen(e.Lang, e.AbsoluteFilePath, e.Text, e.loader == nil) // server could start after the buffer was opened
+	e.Update = true
+}
+
+// writes not saved changes of the buffer as autosave does, or asks if autosave is off.
+// False if closing is cancelled
+func (e *Editor) saveOnClose(index int) bool {
+	b := e.Buffers[index]
+	answer := "y"
+	if e.Config.Save.AutoSave == "" || e.Config.Save.AutoSave == AutoSaveOff || len(b.Content) > e.Config.LargeFile.AutoSaveLines {
+		input, ok := e.inputPrompt(" " + b.Filename + " is not saved, save it? y - save, n - close without saving: ")
+		if !ok || input != "y" && input != "n" { return false }
+		answer = input
+	}
+	if answer == "n" { return true }
+
+	e.writeBuffer(index)
+	e.storeBuffer()
+	return !b.IsContentChanged // failed to write, the error is logged
+}
+
+// writes the buffer without switching the view to it
+func (e *Editor) writeBuffer(index int) {
+	if index < 0 || index >= len(e.Buffers) { return }
+	if index == e.BufferIndex { e.WriteFile(); return }
+
+	e.storeBuffer()
+	active := e.BufferIndex
+	e.BufferIndex = index
+	e.restoreBuffer(e.Buffers[index])
+	e.WriteFile()
+	e.storeBuffer()
+
+	e.BufferIndex = active
+	if active >= 0 { e.restoreBuffer(e.Buffers[active]) }
+}
+
+func (e *Editor) NextBuffer() {
+	if len(e.Buffers) < 2 { return }
+	e.SwitchBuffer((e.BufferIndex + 1) % len(e.Buffers))
+}
+
+func (e *Editor) PrevBuffer() {
+	if len(e.Buffers) < 2 { return }
+	e.SwitchBuffer((e.BufferIndex - 1 + len(e.Buffers)) % len(e.Buffers))
+}
+
+func (e *Editor) CloseBuffer(index int) {
+	if index < 0 || index >= len(e.Buffers) { return }
+	e.storeBuffer()
+	b := e.Buffers[index]
+
+	if b.IsContentChanged && !e.saveOnClose(index) { return }
+	e.saveHistoryOf(b)
+	e.removeSwap(b.AbsoluteFilePath) // saved or discarded
+
+	if lsp, found := e.lsp2lang[b.Lang]; found && lsp.IsReady {
+		go lsp.DidClose(b.AbsoluteFilePath)
+	}
+
+	e.Buffers = Remove(e.Buffers, index)
+
+	if len(e.Buffers) == 0 {
+		e.BufferIndex = -1
+		e.Filename = ""; e.AbsoluteFilePath = ""; e.InputFile = ""
+		e.Content = nil; e.Text = text.New(nil); e.loader, e.lineEndings = nil, nil
+		e.UndoTree = undo.New()
+		e.Row, e.Col, e.Y, e.X = 0, 0, 0, 0
+		e.Selection.CleanSelection()
+		e.CleanCursors()
+		e.FileWatcher.UpdateFile("")
+		e.Screen.Clear()
+		e.DrawLogo()
+		return
+	}
+
+	if index != e.BufferIndex {
+		if index < e.BufferIndex { e.BufferIndex-- }
+		return
+	}
+
+	// closed the active one, show its neighbour
+	next := Min(index, len(e.Buffers)-1)
+	e.restoreBuffer(e.Buffers[next])
+	e.BufferIndex = next
+	e.CleanCursors()
+
+	clear(e.HighlightElements)
+	e.TreePath = nil
+	e.FileWatcher.UpdateFile(e.AbsoluteFilePath)
+	e.FileWatcher.UpdateStats()
+	e.FindTests()
+}
+
+// draw opened buffers on the bottom line, the active one is highlighted
+func (e *Editor) DrawTabs() {
+	if len(e.Buffers) < 2 { return }
+
+	x := e.FilesPanelWidth + e.LINES_WIDTH
+	for i, b := range e.Buffers {
+		name := b.Filename
+		if i == e.BufferIndex && e.IsContentChanged || i != e.BufferIndex && b.IsContentChanged { name += "*" }
+		label := []rune(" " + name + " ")
+
+		style := StyleDefault.Background(Color(OverlayColor)).Foreground(247)
+		if i == e.BufferIndex { style = StyleDefault.Background(Color(AccentColor)).Foreground(ColorWhite) }
+
+		for _, ch := range label {
+			if x >= e.COLUMNS { return }
+			e.Screen.SetContent(x, e.ROWS-1, ch, nil, style)
+			x++
+		}
+		x++
+	}
+}
+
+// returns buffer index under the tabs line position, -1 if none
+func (e *Editor) FindTabUnderMouse(mx int, my int) int {
+	if len(e.Buffers) < 2 || my != e.ROWS-1 { return -1 }
+
+	x := e.FilesPanelWidth + e.LINES_WIDTH
+	for i, b := range e.Buffers {
+		width := len([]rune(b.Filename)) + 2
+		if i == e.BufferIndex && e.IsContentChanged || i != e.BufferIndex && b.IsContentChanged { width++ }
+		if mx >= x && mx < x+width { return i }
+		x += width + 1
+	}
+	return -1
+}
+
+// fuzzy switcher over opened buffers
+func (e *Editor) OnBufferSwitcher() {
+	if len(e.Buffers) == 0 { return }
+	e.storeBuffer()
+
+	e.IsOverlay = true
+	defer e.OverlayFalse()
+
+	var pattern = []rune{}
+	var selected = 0
+	var selectedOffset = 0
+
+	for {
+		matches := e.filterBuffers(string(pattern))
+
+		var options = []string{}
+		for _, index := range matches {
+			b := e.Buffers[index]
+			relativePath, err := filepath.Rel(e.Cwd, b.AbsoluteFilePath)
+			if err != nil { relativePath = b.AbsoluteFilePath }
+			changed := ""
+			if b.IsContentChanged { changed = "*" }
+			options = append(options, fmt.Sprintf("%s%s  %d:%d", relativePath, changed, b.Row+1, b.Col+1))
+		}
+
+		height := MinMany(10, len(options), e.ROWS-2)
+		if selected >= len(options) { selected = len(options) - 1 }
+		if selected < 0 { selected = 0 }
+		if selected < selectedOffset { selectedOffset = selected }
+		if selected >= selectedOffset+height { selectedOffset = selected - height + 1 }
+
+		atx := e.FilesPanelWidth + e.LINES_WIDTH
+		width := Max(40, MaxString(options)+2)
+		e.DrawEverything()
+		e.drawCompletion(atx, 1, height, width, options, selected, selectedOffset, StyleDefault)
+
+		prefix := " buffer: " + string(pattern)
+		for i, ch := range []rune(prefix) { e.Screen.SetContent(atx+i, 0, ch, nil, StyleDefault) }
+		for i := atx + len([]rune(prefix)); i < atx+width; i++ { e.Screen.SetContent(i, 0, ' ', nil, StyleDefault) }
+		e.Screen.ShowCursor(atx+len([]rune(prefix)), 0)
+		e.Screen.Show()
+
+		switch ev := e.PollEvent().(type) {
+		case *EventResize:
+			e.COLUMNS, e.ROWS = e.Screen.Size()
+			e.ROWS -= e.ProcessPanelHeight
+
+		case *EventKey:
+			key := ev.Key()
+			if key == KeyEscape || key == KeyCtrlN { return }
+			if key == KeyDown { selected = Min(len(options)-1, selected+1) }
+			if key == KeyUp { selected = Max(0, selected-1) }
+			if key == KeyRune { pattern = append(pattern, ev.Rune()); selected = 0 }
+			if (key == KeyBackspace || key == KeyBackspace2) && len(pattern) > 0 {
+				pattern = pattern[:len(pattern)-1]; selected = 0
+			}
+			if key == KeyCtrlW && len(matches) > 0 {
+				e.CloseBuffer(matches[selected])
+				if len(e.Buffers) == 0 { return }
+			}
+			if key == KeyEnter && len(matches) > 0 {
+				e.SwitchBuffer(matches[selected])
+				return
+			}
+		}
+	}
+}
+
+// returns indexes of buffers matching the pattern, best matches first
+func (e *Editor) filterBuffers(pattern string) []int {
+	type match struct { index, score int }
+	var matches = []match{}
+
+	for i, b := range e.Buffers {
+		relativePath, err := filepath.Rel(e.Cwd, b.AbsoluteFilePath)
+		if err != nil { relativePath = b.AbsoluteFilePath }
+		if score, ok := FuzzyMatch(relativePath, pattern); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+
+	if len(pattern) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	}
+
+	indexes := make([]int, len(matches))
+	for i, m := range matches { indexes[i] = m.index }
+	return indexes
+}
@@ -0,0 +1,49 @@
+package ui
+
+import (
+	. "edgo/internal/config"
+	. "github.com/gdamore/tcell"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCloseNotSavedBuffer(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} { os.WriteFile(filepath.Join(dir, name), []byte("text\n"), 0644) }
+
+	e := testEditor("")
+	e.Lang = ""
+	e.Config.LargeFile.Size = 1 << 20
+	e.Config.LargeFile.AutoSaveLines = 10000
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		e.OpenFile(filepath.Join(dir, name))
+		e.Content[0] = []rune("changed")
+		e.IsContentChanged = true
+	}
+	read := func(name string) string { written, _ := os.ReadFile(filepath.Join(dir, name)); return string(written) }
+	keys := func(keys ...Key) {
+		e.macroQueue = nil
+		for _, key := range keys { e.macroQueue = append(e.macroQueue, NewEventKey(key, 'n', ModNone)) }
+	}
+
+	// autosave writes the closed buffer, the view stays on the active one
+	e.Config.Save.AutoSave = AutoSaveEdit
+	e.CloseBuffer(0)
+	if read("a.txt") != "changed\n" { t.Errorf("closed buffer should be written, got %q", read("a.txt")) }
+	if len(e.Buffers) != 2 || e.BufferIndex != 1 { t.Errorf("expected 2 buffers with active 1, got %d with active %d", len(e.Buffers), e.BufferIndex) }
+	if e.AbsoluteFilePath != filepath.Join(dir, "c.txt") || string(e.Content[0]) != "changed" { t.Errorf("view should stay on the active buffer, got %s", e.AbsoluteFilePath) }
+
+	// without autosave asks, escape cancels closing
+	e.Config.Save.AutoSave = AutoSaveOff
+	keys(KeyEscape)
+	e.CloseBuffer(0)
+	if len(e.Buffers) != 2 { t.Errorf("closing should be cancelled") }
+
+	// n closes without writing
+	keys(KeyRune, KeyEnter)
+	e.CloseBuffer(0)
+	if read("b.txt") != "text\n" { t.Errorf("discarded buffer should not be written, got %q", read("b.txt")) }
+	if len(e.Buffers) != 1 || e.BufferIndex != 0 || e.AbsoluteFilePath != filepath.Join(dir, "c.txt") { t.Errorf("view should stay on the active buffer, got %s", e.AbsoluteFilePath) }
+}
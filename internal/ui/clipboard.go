@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"edgo/internal/clipboard"
+	. "edgo/internal/logger"
+	. "edgo/internal/utils"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"strings"
+)
+
+/*
+	Every copy and cut goes through copyText, so the text is kept in clipboard history
+	even if there is no system clipboard, and paste falls back to the latest copied text.
+	Backend is chosen by `clipboard` config: auto, system, osc52 or internal.
+*/
+
+func (e *Editor) clip() *clipboard.Clipboard {
+	if e.clipboard == nil { e.clipboard = clipboard.New(e.Config.Clipboard) }
+	return e.clipboard
+}
+
+func (e *Editor) copyText(text string) {
+	if err := e.clip().Copy(text); err != nil { Log.Error("clipboard copy", err.Error()) }
+}
+
+func (e *Editor) pasteText() string {
+	return e.clip().Paste()
+}
+
+// pastes text chosen from history of copied texts, the latest first
+func (e *Editor) OnPasteFromHistory() {
+	e.pasteText() // text copied outside of the editor gets into history
+	history := e.clip().History
+	if len(history) == 0 { return }
+
+	selected, ok := e.selectFromHistory(history)
+	if !ok { return }
+	e.paste(history[selected], history[selected] == e.blockClipboard) // clipboard and history order stay as they are
+	e.Update = true
+}
+
+func (e *Editor) selectFromHistory(history []string) (int, bool) {
+	e.IsOverlay = true
+	defer e.OverlayFalse()
+
+	var options = []string{}
+	for _, text := range history { options = append(options, historyOption(text)) }
+
+	var selected = 0
+	var selectedOffset = 0
+
+	for {
+		height := MinMany(10, len(options), e.ROWS-2)
+		if selected < selectedOffset { selectedOffset = selected }
+		if selected >= selectedOffset+height { selectedOffset = selected - height + 1 }
+
+		atx := e.FilesPanelWidth + e.LINES_WIDTH
+		width := Max(40, MaxString(options)+2)
+		e.DrawEverything()
+		e.drawCompletion(atx, 1, height, width, options, selected, selectedOffset, StyleDefault)
+
+		prefix := fmt.Sprintf(" paste from history: %d/%d", selected+1, len(options))
+		for i, ch := range []rune(prefix) { e.Screen.SetContent(atx+i, 0, ch, nil, StyleDefault) }
+		for i := atx + len([]rune(prefix)); i < atx+width; i++ { e.Screen.SetContent(i, 0, ' ', nil, StyleDefault) }
+		e.Screen.HideCursor()
+		e.Screen.Show()
+
+		switch ev := e.PollEvent().(type) {
+		case *EventResize:
+			e.COLUMNS, e.ROWS = e.Screen.Size()
+			e.ROWS -= e.ProcessPanelHeight
+
+		case *EventKey:
+			key := ev.Key()
+			if key == KeyEscape { return 0, false }
+			if key == KeyDown { selected = Min(len(options)-1, selected+1) }
+			if key == KeyUp { selected = Max(0, selected-1) }
+			if key == KeyEnter { return selected, true }
+		}
+	}
+}
+
+// first line of the text, with count of the other lines
+func historyOption(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	option := strings.ReplaceAll(strings.TrimSpace(lines[0]), "\t", " ")
+	if runes := []rune(option); len(runes) > 60 { option = string(runes[:60]) + "..." }
+	if len(lines) > 1 { option += fmt.Sprintf("  (+%d lines)", len(lines)-1) }
+	return option
+}
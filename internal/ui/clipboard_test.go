@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"edgo/internal/clipboard"
+	. "edgo/internal/selection"
+	. "github.com/gdamore/tcell"
+	"fmt"
+	"testing"
+)
+
+// system clipboard of the test, counts reads
+type testBackend struct {
+	text  string
+	reads int
+}
+
+func (b *testBackend) Read() (string, error)   { b.reads++; return b.text, nil }
+func (b *testBackend) Write(text string) error { b.text = text; return nil }
+
+func TestPasteFromHistory(t *testing.T) {
+	e := testEditor("")
+	e.Lang = "" // history is drawn over the buffer, diagnostics need a language server
+	backend := &testBackend{}
+	e.clipboard = &clipboard.Clipboard{Backend: backend}
+	e.copyText("one")
+	e.copyText("two")
+
+	e.macroQueue = []Event{NewEventKey(KeyDown, 0, ModNone), NewEventKey(KeyEnter, 0, ModNone)}
+	e.OnPasteFromHistory()
+	if content(e) != "one" { t.Errorf("chosen text should be pasted, got %q", content(e)) }
+	if backend.text != "two" || fmt.Sprint(e.clip().History) != "[two one]" { t.Errorf("clipboard %q and history %v should stay", backend.text, e.clip().History) }
+}
+
+func TestPasteBlock(t *testing.T) {
+	e := testEditor("abc\nabc\n")
+	backend := &testBackend{}
+	e.clipboard = &clipboard.Clipboard{Backend: backend}
+	e.Selection = Selection{Ssx: 1, Ssy: 0, Sex: 2, Sey: 1, IsSelected: true, IsBlock: true}
+	e.OnBlockCopy()
+	e.Selection.CleanSelection()
+
+	if !e.isBlockClipboard() || backend.reads != 0 { t.Errorf("block should be found in history without reading clipboard") }
+	e.Row, e.Col = 0, 0
+	e.OnPaste()
+	if content(e) != "babc\nbabc\n" || backend.reads != 1 { t.Errorf("block should be pasted by rows reading clipboard once, got %q, %d reads", content(e), backend.reads) }
+
+	backend.text = "x" // copied outside
+	e.Row, e.Col = 0, 0
+	e.OnPaste()
+	if e.isBlockClipboard() || content(e) != "xbabc\nbabc\n" { t.Errorf("text copied outside is not a block, got %q", content(e)) }
+}
@@ -0,0 +1,292 @@
+package ui
+
+import (
+	"edgo/internal/keymap"
+	. "edgo/internal/logger"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"os"
+	"sort"
+)
+
+/*
+	Every shortcut is a named command. Keys are bound to commands per context by the default keymap,
+	`keymap:` section of config overrides it, for example:
+
+	keymap:
+	  editor:
+	    "ctrl+k ctrl+c": comment
+	    "ctrl+k": none        # unbind
+	  debug:
+	    f8: continue
+
+	Typing, enter, backspace, arrows and shift selection are not commands.
+*/
+
+type editorCommand func(e *Editor)
+
+var editorCommands = map[string]editorCommand{
+	"quit":                 (*Editor).OnQuit,
+	"save":                 (*Editor).OnSave,
+	"search":               (*Editor).OnSearch,
+	"lines-count":          (*Editor).OnLangLinesCount,
+	"files-tree":           func(e *Editor) { e.OnFilesTree(true) },
+	"buffer-switcher":      (*Editor).OnBufferSwitcher,
+	"prev-buffer":          (*Editor).PrevBuffer,
+	"next-buffer":          (*Editor).NextBuffer,
+	"undo":                 (*Editor).OnUndo,
+	"redo":                 (*Editor).OnRedo,
+	"undo-earlier":         (*Editor).OnUndoEarlier,
+	"undo-later":           (*Editor).OnUndoLater,
+	"undo-tree":            (*Editor).OnUndoTree,
+	"copy":                 (*Editor).OnCopy,
+	"cut":                  func(e *Editor) { e.Cut(true) },
+	"paste":                (*Editor).OnPaste,
+	"paste-from-history":   (*Editor).OnPasteFromHistory,
+	"select-all":           (*Editor).OnSelectAll,
+	"select-more":          (*Editor).OnSelectMoreAtCursor,
+	"select-less":          (*Editor).OnSelectLessAtCursor,
+	"clear-selection":      func(e *Editor) { e.Selection.CleanSelection() },
+	"duplicate":            (*Editor).Duplicate,
+	"comment":              (*Editor).OnCommentLine,
+	"indent":               (*Editor).OnTab,
+	"unindent":             (*Editor).OnBackTab,
+	"swap-lines-up":        (*Editor).OnSwapLinesUp,
+	"swap-lines-down":      (*Editor).OnSwapLinesDown,
+	"go-top":               (*Editor).GoTop,
+	"go-bottom":            (*Editor).GoBottom,
+	"go-to-line":           (*Editor).GoToLine,
+	"word-right":           func(e *Editor) { e.HandleSmartMove('f') },
+	"word-left":            func(e *Editor) { e.HandleSmartMove('b') },
+	"cursor-back":          (*Editor).OnCursorBack,
+	"cursor-forward":       (*Editor).OnCursorBackUndo,
+	"add-cursor-below":     (*Editor).OnAddCursorBelow,
+	"add-cursor-above":     (*Editor).OnAddCursorAbove,
+	"add-next-occurrence":  (*Editor).OnAddNextOccurrence,
+	"block-select-up":      func(e *Editor) { e.OnBlockSelect(KeyUp) },
+	"block-select-down":    func(e *Editor) { e.OnBlockSelect(KeyDown) },
+	"block-select-left":    func(e *Editor) { e.OnBlockSelect(KeyLeft) },
+	"block-select-right":   func(e *Editor) { e.OnBlockSelect(KeyRight) },
+	"completion":           (*Editor).OnCompletion,
+	"hover":                (*Editor).OnHover,
+	"signature-help":       (*Editor).OnSignatureHelp,
+	"definition":           (*Editor).OnDefinition,
+	"references":           (*Editor).OnReferences,
+	"code-action":          (*Editor).OnCodeAction,
+	"rename":               (*Editor).OnRename,
+	"errors":               (*Editor).OnErrors,
+	"run":                  func(e *Editor) { e.OnProcessRun(true) },
+	"debug":                (*Editor).OnDebug,
+	"breakpoint":           (*Editor).Breakpoint,
+	"set-encoding":         (*Editor).OnSetEncoding,
+	"toggle-line-ending":   (*Editor).OnToggleLineEnding,
+	"toggle-bom":           (*Editor).OnToggleBOM,
+	"toggle-final-newline": (*Editor).OnToggleFinalNewline,
+	"toggle-wrap":          (*Editor).OnToggleWrap,
+	"fold":                 (*Editor).OnFold,
+	"unfold":               (*Editor).OnUnfold,
+	"fold-all":             (*Editor).OnFoldAll,
+	"unfold-all":           (*Editor).OnUnfoldAll,
+	"fold-level":           (*Editor).OnFoldLevel,
+	"reindent":             (*Editor).OnReindent,
+	"match-bracket":        (*Editor).OnMatchBracket,
+	"select-inside-brackets": (*Editor).OnSelectInsideBrackets,
+	"select-around-brackets": (*Editor).OnSelectAroundBrackets,
+	"toggle-rainbow":       (*Editor).OnToggleRainbow,
+	"surround":             (*Editor).OnSurround,
+	"change-surround":      (*Editor).OnChangeSurround,
+	"delete-surround":      (*Editor).OnDeleteSurround,
+	"block-comment":        (*Editor).OnBlockComment,
+	"toggle-bookmark":      (*Editor).OnToggleBookmark,
+	"next-bookmark":        (*Editor).OnNextBookmark,
+	"prev-bookmark":        (*Editor).OnPrevBookmark,
+	"set-mark":             (*Editor).OnSetMark,
+	"go-to-mark":           (*Editor).OnGoToMark,
+	"bookmarks":            (*Editor).OnBookmarks,
+}
+
+// commands available before any file is opened
+var noFileCommands = map[string]bool{"quit": true, "search": true, "lines-count": true, "command-palette": true, "bookmarks": true}
+
+var processCommands = map[string]editorCommand{
+	"search":       (*Editor).OnProcessSearch,
+	"stop":         (*Editor).OnProcessStop,
+	"scroll-right": func(e *Editor) { e.ProcessPanelHScroll++ },
+	"follow": func(e *Editor) {
+		if len(e.ProcessContent) > e.ProcessPanelHeight {
+			e.ProcessPanelScroll = len(e.ProcessContent) - e.ProcessPanelHeight + 1
+		}
+	},
+}
+
+var debugCommands = map[string]func(e *Editor, threadId int){
+	"continue":   func(e *Editor, threadId int) { e.Dap.Continue(threadId); e.DebugInfo.stopline = -1 },
+	"next":       func(e *Editor, threadId int) { e.Dap.Next(threadId) },
+	"step-in":    func(e *Editor, threadId int) { e.Dap.StepIn(threadId) },
+	"pause":      func(e *Editor, threadId int) { e.Dap.Pause(threadId) },
+	"stop":       func(e *Editor, threadId int) { e.OnDebugStop() },
+	"breakpoint": func(e *Editor, threadId int) { e.Breakpoint(); e.DrawEverything(); e.Screen.Show() },
+}
+
+// files tree runs its own loop, its commands are handled there
+var treeCommands = []string{"quit", "new-file", "search", "cancel", "close"}
+
+var defaultKeymap = map[string]map[string]string{
+	keymap.Editor: {
+		"ctrl+q": "quit", "ctrl+s": "save", "ctrl+f": "search", "ctrl+y": "lines-count", "ctrl+t": "files-tree",
+		"ctrl+n": "buffer-switcher", "ctrl+pgup": "prev-buffer", "ctrl+pgdn": "next-buffer",
+		"ctrl+u": "undo", "¨": "redo", "alt+z": "undo-tree", // '¨' is option + u on Mac
+		"ctrl+c": "copy", "ctrl+x": "cut", "ctrl+v": "paste", "alt+v": "paste-from-history",
+		"ctrl+a": "select-all", "alt+up": "select-more", "alt+down": "select-less", "esc": "clear-selection",
+		"ctrl+d": "duplicate", "alt+/": "comment", "÷": "comment", // '÷' is option + '/' on Mac
+		"alt+?": "block-comment",
+		"tab": "indent", "backtab": "unindent", "ctrl+shift+up": "swap-lines-up", "ctrl+shift+down": "swap-lines-down",
+		"ctrl+j": "go-top", "ctrl+k": "go-bottom", "ctrl+l": "go-to-line",
+		"alt+f": "word-right", "alt+F": "word-right", "alt+right": "word-right",
+		"alt+b": "word-left", "alt+B": "word-left", "alt+left": "word-left",
+		"ctrl+o": "cursor-back", "ctrl+]": "cursor-forward",
+		"ctrl+alt+down": "add-cursor-below", "ctrl+alt+up": "add-cursor-above", "alt+n": "add-next-occurrence",
+		"shift+alt+up": "block-select-up", "shift+alt+down": "block-select-down",
+		"shift+alt+left": "block-select-left", "shift+alt+right": "block-select-right",
+		"ctrl+space": "completion", "ctrl+h": "hover", "ctrl+p": "signature-help", "ctrl+g": "definition",
+		"ctrl+r": "references", "ctrl+w": "code-action", "f18": "rename", "ctrl+e": "errors",
+		"f22": "run", "f23": "debug", "ctrl+b": "breakpoint", "f1": "command-palette", "alt+x": "command-palette",
+		"f5": "macro-record", "f6": "macro-play", "alt+-": "fold", "alt+=": "unfold",
+		"alt+m": "match-bracket", "alt+i": "select-inside-brackets", "alt+a": "select-around-brackets",
+		"alt+k": "toggle-bookmark", "alt+.": "next-bookmark", "alt+,": "prev-bookmark",
+		"alt+K": "set-mark", "alt+'": "go-to-mark", "alt+l": "bookmarks",
+	},
+	keymap.Process: { "ctrl+f": "search", "s": "stop", "l": "scroll-right", "f": "follow" },
+	keymap.Debug: {
+		"c": "continue", "n": "next", "s": "step-in", "p": "pause", "q": "stop", "b": "breakpoint",
+	},
+	keymap.Tree: { "ctrl+q": "quit", "ctrl+n": "new-file", "ctrl+f": "search", "esc": "cancel", "ctrl+t": "close" },
+}
+
+func commandNames(context string) []string {
+	names := []string{}
+	switch context {
+	case keymap.Editor: for name := range editorCommands { names = append(names, name) }
+	case keymap.Process: for name := range processCommands { names = append(names, name) }
+	case keymap.Debug: for name := range debugCommands { names = append(names, name) }
+	case keymap.Tree: names = append(names, treeCommands...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// builds keymap from defaults and config overrides, returns every invalid binding of config
+func NewKeymap(config map[string]map[string]string) (*keymap.Keymap, []error) {
+	bindings := keymap.New()
+	for context, defaults := range defaultKeymap {
+		for keys, command := range defaults {
+			sequence, err := keymap.ParseSequence(keys)
+			if err != nil { panic(err) }
+			bindings.Bind(context, sequence, command)
+		}
+	}
+
+	errors := []error{}
+	user := keymap.New() // bindings of config only, to find conflicts between them
+	for _, context := range sortedKeys(config) {
+		names := commandNames(context)
+		if len(names) == 0 { errors = append(errors, fmt.Errorf("keymap: unknown context %q", context)); continue }
+
+		for _, keys := range sortedKeys(config[context]) {
+			command := config[context][keys]
+			sequence, err := keymap.ParseSequence(keys)
+			if err != nil { errors = append(errors, fmt.Errorf("keymap %s: %v", context, err)); continue }
+
+			if command == "none" { bindings.Unbind(context, sequence); continue }
+			if i := sort.SearchStrings(names, command); i == len(names) || names[i] != command {
+				errors = append(errors, fmt.Errorf("keymap %s: unknown command %q for %q", context, command, keys))
+				continue
+			}
+			if conflicts := user.Conflicts(context, sequence); len(conflicts) > 0 {
+				errors = append(errors, fmt.Errorf("keymap %s: %q conflicts with %q", context, keys, keymap.FormatSequence(conflicts[0].Keys)))
+				continue
+			}
+			user.Bind(context, sequence, command)
+			bindings.Bind(context, sequence, command)
+		}
+	}
+	return bindings, errors
+}
+
+// invalid bindings are logged and skipped, their keys keep default commands
+func (e *Editor) InitKeymap() {
+	bindings, errors := NewKeymap(e.Config.Keymap)
+	for _, err := range errors { Log.Error(err.Error(), "(skipped)") }
+	e.Keymap = bindings
+}
+
+// returns command bound to the key in context, collecting multi-key sequences.
+// handled is true if the key is a part of a sequence or a bound command
+func (e *Editor) lookupCommand(context string, ev *EventKey) (command string, handled bool) {
+	if e.Keymap == nil { return "", false }
+	keys := append(e.pendingKeys, keymap.ChordOf(ev))
+	command, isPrefix := e.Keymap.Lookup(context, keys)
+
+	if command == "" && !isPrefix && len(e.pendingKeys) > 0 {
+		// sequence is broken, the key may start a new one
+		e.pendingKeys = nil
+		return e.lookupCommand(context, ev)
+	}
+	if isPrefix { e.pendingKeys = keys; return "", true }
+
+	e.pendingKeys = nil
+	if command != "" { e.lastKeys = keys }
+	return command, command != ""
+}
+
+// true if the key alone is bound to one of commands, does not affect sequences
+func (e *Editor) isBoundTo(ev *EventKey, commands ...string) bool {
+	if e.Keymap == nil || len(e.pendingKeys) > 0 { return false }
+	bound, _ := e.Keymap.Lookup(keymap.Editor, []keymap.Chord{keymap.ChordOf(ev)})
+	for _, command := range commands {
+		if bound == command { return true }
+	}
+	return false
+}
+
+func (e *Editor) OnQuit() {
+	e.autoSaveOnLeave()
+	if !e.confirmQuit() { return }
+	e.WriteSwaps() // removes swaps of saved and discarded buffers
+	e.storeBuffer()
+	for _, b := range e.Buffers { e.saveHistoryOf(b) }
+	e.saveSession()
+	e.Screen.Fini()
+	os.Exit(1)
+}
+
+// asks to save buffers with not saved changes, false if quit is cancelled.
+// Discarded changes are not kept in swaps, they are not offered to restore on the next start
+func (e *Editor) confirmQuit() bool {
+	e.storeBuffer()
+	dirty := []int{}
+	for i, b := range e.Buffers {
+		if b.IsContentChanged { dirty = append(dirty, i) }
+	}
+	if len(dirty) == 0 { return true }
+
+	answer, ok := e.inputPrompt(fmt.Sprintf(" %d files not saved, save them? y - save, n - quit without saving: ", len(dirty)))
+	if !ok || answer != "y" && answer != "n" { return false }
+	for _, i := range dirty {
+		if answer == "y" { e.writeBuffer(i) } else { e.Buffers[i].IsContentChanged = false }
+	}
+	if answer == "n" { e.IsContentChanged = false }
+
+	e.storeBuffer()
+	for _, b := range e.Buffers {
+		if b.IsContentChanged { return false } // failed to write, the error is logged
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m { keys = append(keys, key) }
+	sort.Strings(keys)
+	return keys
+}
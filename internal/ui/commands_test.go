@@ -0,0 +1,46 @@
+package ui
+
+import (
+	"edgo/internal/keymap"
+	. "github.com/gdamore/tcell"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitKeymapSkipsInvalid(t *testing.T) {
+	e := testEditor("")
+	e.Config.Keymap = map[string]map[string]string{"editor": {"ctrl+s": "no-such-command", "ctrl+q ctrl+": "save", "f2": "rename"}}
+	e.InitKeymap()
+
+	for keys, expected := range map[string]string{"ctrl+s": "save", "f2": "rename", "ctrl+q": "quit"} {
+		sequence, _ := keymap.ParseSequence(keys)
+		if command, _ := e.Keymap.Lookup(keymap.Editor, sequence); command != expected { t.Errorf("%s: bound to %q, expected %q", keys, command, expected) }
+	}
+}
+
+func TestConfirmQuit(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	dir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} { os.WriteFile(filepath.Join(dir, name), []byte("text\n"), 0644) }
+
+	e := testEditor("")
+	e.Lang = ""
+	e.Config.LargeFile.Size = 1 << 20
+	e.OpenFile(filepath.Join(dir, "a.txt"))
+	e.Content[0] = []rune("changed")
+	e.IsContentChanged = true
+	e.OpenFile(filepath.Join(dir, "b.txt"))
+
+	keys := func(keys ...Key) {
+		e.macroQueue = nil
+		for _, key := range keys { e.macroQueue = append(e.macroQueue, NewEventKey(key, 'y', ModNone)) }
+	}
+	keys(KeyEscape)
+	if e.confirmQuit() { t.Errorf("quit should be cancelled") }
+
+	keys(KeyRune, KeyEnter)
+	if !e.confirmQuit() { t.Errorf("quit should be confirmed") }
+	if written, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(written) != "changed\n" { t.Errorf("not saved file should be written, got %q", written) }
+	if e.AbsoluteFilePath != filepath.Join(dir, "b.txt") { t.Errorf("view should stay on the active buffer, got %s", e.AbsoluteFilePath) }
+}
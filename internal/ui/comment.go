@@ -0,0 +1,184 @@
+package ui
+
+import (
+	. "edgo/internal/config"
+	. "edgo/internal/operations"
+	"strings"
+)
+
+/*
+	Comments are toggled on the cursor line or selected lines, line comments are aligned at the smallest indentation.
+	Languages without line comments (html, css) wrap the lines into a block comment.
+	Tokens are of the language at the cursor, so a style element of html gets css comments.
+*/
+
+type commentTokens struct {
+	line        string
+	open, close string // block comment
+}
+
+// comment tokens of the language injected at the row, or of the file language
+func (e *Editor) commentTokensAt(row int) commentTokens {
+	conf := e.langConf
+	if e.hasSyntaxTree() {
+		offset := e.Text.Offset(row, len(leadingWhitespace(e.Content[row])))
+		if lang := e.treeSitterHighlighter.LanguageAt(row, offset); lang != "" && lang != e.Lang {
+			conf = DefaultLangConfig
+			if injected, found := e.Config.Langs[lang]; found { conf = injected }
+		}
+	}
+
+	tokens := commentTokens{line: conf.Comment}
+	if open, close, found := strings.Cut(conf.Comment, " "); found {
+		tokens = commentTokens{open: open, close: strings.TrimSpace(close)}
+	} else if open, close, found := strings.Cut(conf.BlockComment, " "); found {
+		tokens.open, tokens.close = open, strings.TrimSpace(close)
+	}
+	return tokens
+}
+
+// first and last selected rows, a line the selection ends at the beginning of is not selected
+func (e *Editor) selectedRows() (int, int, bool) {
+	if !e.Selection.IsSelectionNonEmpty() { return e.Row, e.Row, false }
+	from, to := e.Selection.Ssy, e.Selection.Sey
+	endCol := e.Selection.Sex
+	if to < from { from, to, endCol = to, from, e.Selection.Ssx }
+	to = min(to, len(e.Content)-1)
+	if endCol == 0 && to > from && !e.Selection.IsBlock { to-- }
+	return from, to, true
+}
+
+// position change by edits of the lines, kept as row -> column and length change
+type lineShifts map[int][][2]int
+
+func (s lineShifts) add(row, col, delta int) { s[row] = append(s[row], [2]int{col, delta}) }
+
+func (s lineShifts) apply(row, col int) int {
+	for _, shift := range s[row] {
+		if col >= shift[0] { col = max(col+shift[1], shift[0]) }
+	}
+	return col
+}
+
+// toggles comments of the cursor line or selected lines
+func (e *Editor) OnCommentLine() {
+	if len(e.Content) == 0 { return }
+	e.Focus()
+	from, to, selected := e.selectedRows()
+	tokens := e.commentTokensAt(from)
+
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+	shifts := lineShifts{}
+	if tokens.line != "" {
+		e.toggleLineComments(from, to, tokens.line, shifts, &ops)
+	} else if tokens.open != "" {
+		e.toggleBlockComment(from, to, tokens, shifts, &ops)
+	}
+	if len(ops) == 1 { return }
+
+	e.Col = shifts.apply(e.Row, e.Col)
+	if selected {
+		e.Selection.Ssx = shifts.apply(e.Selection.Ssy, e.Selection.Ssx)
+		e.Selection.Sex = shifts.apply(e.Selection.Sey, e.Selection.Sex)
+	}
+	e.finishEdit(ops)
+	if !selected { e.OnDown() }
+}
+
+// comments every non blank line at the smallest indentation, or uncomments if all of them are commented
+func (e *Editor) toggleLineComments(from, to int, token string, shifts lineShifts, ops *EditOperation) {
+	rows, indent := []int{}, -1
+	for row := from; row <= to; row++ {
+		if strings.TrimSpace(string(e.Content[row])) == "" && from != to { continue }
+		rows = append(rows, row)
+		if ws := len(leadingWhitespace(e.Content[row])); indent < 0 || ws < indent { indent = ws }
+	}
+
+	commented := true
+	for _, row := range rows {
+		text := string(e.Content[row][len(leadingWhitespace(e.Content[row])):])
+		commented = commented && strings.HasPrefix(text, token)
+	}
+
+	for _, row := range rows {
+		if commented {
+			col := len(leadingWhitespace(e.Content[row]))
+			count := len([]rune(token))
+			if col+count < len(e.Content[row]) && e.Content[row][col+count] == ' ' { count++ }
+			e.deleteRunes(row, col, count, ops)
+			shifts.add(row, col, -count)
+		} else {
+			e.insertRunes(row, indent, []rune(token+" "), ops)
+			shifts.add(row, indent, len([]rune(token))+1)
+		}
+	}
+}
+
+// wraps the lines into one block comment, or unwraps the comment with the cursor or around the lines
+func (e *Editor) toggleBlockComment(from, to int, tokens commentTokens, shifts lineShifts, ops *EditOperation) {
+	start, end := e.Text.Offset(from, len(leadingWhitespace(e.Content[from]))), e.Text.Offset(to, len(strings.TrimRight(string(e.Content[to]), " \t")))
+	if from == to && e.hasSyntaxTree() {
+		cs, ce, found := e.treeSitterHighlighter.CommentAround(e.Text.Offset(e.Row, e.Col))
+		if comment := string(e.Text.Slice(cs, ce)); found && isBlockComment(comment, tokens) { start, end = cs, ce }
+	}
+
+	text := string(e.Text.Slice(start, end))
+	sy, sx := e.Text.Position(start)
+	ey, ex := e.Text.Position(end)
+	if isBlockComment(text, tokens) {
+		count := len([]rune(tokens.close))
+		if ex-count > 0 && e.Content[ey][ex-count-1] == ' ' { count++ }
+		e.deleteRunes(ey, ex-count, count, ops)
+		shifts.add(ey, ex-count, -count)
+
+		count = len([]rune(tokens.open))
+		if sx+count < len(e.Content[sy]) && e.Content[sy][sx+count] == ' ' { count++ }
+		e.deleteRunes(sy, sx, count, ops)
+		shifts.add(sy, sx, -count)
+		return
+	}
+
+	e.insertRunes(ey, ex, []rune(" "+tokens.close), ops)
+	e.insertRunes(sy, sx, []rune(tokens.open+" "), ops)
+	shifts.add(ey, ex, len([]rune(tokens.close))+1)
+	shifts.add(sy, sx, len([]rune(tokens.open))+1)
+}
+
+func isBlockComment(text string, tokens commentTokens) bool {
+	return len(text) >= len(tokens.open)+len(tokens.close) && strings.HasPrefix(text, tokens.open) && strings.HasSuffix(text, tokens.close)
+}
+
+// toggles a block comment around the selection, or around the cursor line
+func (e *Editor) OnBlockComment() {
+	if len(e.Content) == 0 { return }
+	e.Focus()
+	from, to, selected := e.selectedRows()
+	tokens := e.commentTokensAt(from)
+	if tokens.open == "" { return }
+
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+	shifts := lineShifts{}
+	if sy, sx, ey, ex, found := e.surroundTarget(); found && selected && !e.Selection.IsBlock {
+		text := string(e.Text.Slice(e.Text.Offset(sy, sx), e.Text.Offset(ey, ex)))
+		if isBlockComment(text, tokens) {
+			e.deleteRunes(ey, ex-len([]rune(tokens.close)), len([]rune(tokens.close)), &ops)
+			e.deleteRunes(sy, sx, len([]rune(tokens.open)), &ops)
+			shifts.add(ey, ex-len([]rune(tokens.close)), -len([]rune(tokens.close)))
+			shifts.add(sy, sx, -len([]rune(tokens.open)))
+		} else {
+			e.insertRunes(ey, ex, []rune(tokens.close), &ops)
+			e.insertRunes(sy, sx, []rune(tokens.open), &ops)
+			shifts.add(sy, sx, len([]rune(tokens.open)))
+		}
+	} else {
+		e.toggleBlockComment(from, to, tokens, shifts, &ops)
+	}
+	if len(ops) == 1 { return }
+
+	e.Col = shifts.apply(e.Row, e.Col)
+	if selected {
+		e.Selection.Ssx = shifts.apply(e.Selection.Ssy, e.Selection.Ssx)
+		e.Selection.Sex = shifts.apply(e.Selection.Sey, e.Selection.Sex)
+	}
+	e.finishEdit(ops)
+}
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	. "edgo/internal/selection"
+	"testing"
+)
+
+func TestToggleComments(t *testing.T) {
+	tests := map[string]struct {
+		code     string
+		comment  string // language comment tokens
+		row, col int
+		sel      Selection
+		block    bool // block-comment command
+		expected string
+		col2     int       // cursor column after
+		sel2     Selection // selection after
+	}{
+		"line":             {code: "\tx := 1", comment: "//", col: 2, expected: "\t// x := 1", col2: 5},
+		"uncomment line":   {code: "\t// x := 1", comment: "//", col: 5, expected: "\tx := 1", col2: 2},
+		"lines at indent":  {code: "\tif a {\n\t\tb()\n\t}", comment: "//", row: 1, col: 2, sel: Selection{Ssy: 0, Ssx: 0, Sey: 2, Sex: 2, IsSelected: true}, expected: "\t// if a {\n\t// \tb()\n\t// }", col2: 5, sel2: Selection{Ssy: 0, Ssx: 0, Sey: 2, Sex: 5, IsSelected: true}},
+		"block":            {code: "a b", comment: "/* */", col: 1, expected: "/* a b */", col2: 4},
+		"block at end":     {code: "a b", comment: "/* */", col: 3, expected: "/* a b */", col2: 9},
+		"block lines":      {code: "a\nb", comment: "/* */", row: 1, col: 1, sel: Selection{Ssy: 0, Ssx: 0, Sey: 1, Sex: 1, IsSelected: true}, expected: "/* a\nb */", col2: 4, sel2: Selection{Ssy: 0, Ssx: 3, Sey: 1, Sex: 4, IsSelected: true}},
+		"unwrap block":     {code: "/* a b */", comment: "/* */", col: 4, expected: "a b", col2: 1},
+		"selection":        {code: "f(a b)", comment: "//", col: 5, sel: Selection{Ssy: 0, Ssx: 2, Sey: 0, Sex: 5, IsSelected: true}, block: true, expected: "f(/*a b*/)", col2: 7, sel2: Selection{Ssy: 0, Ssx: 4, Sey: 0, Sex: 7, IsSelected: true}},
+		"unwrap selection": {code: "f(/*a b*/)", comment: "//", col: 9, sel: Selection{Ssy: 0, Ssx: 2, Sey: 0, Sex: 9, IsSelected: true}, block: true, expected: "f(a b)", col2: 5, sel2: Selection{Ssy: 0, Ssx: 2, Sey: 0, Sex: 5, IsSelected: true}},
+	}
+
+	for name, test := range tests {
+		e := testEditor(test.code)
+		e.langConf.Comment, e.langConf.BlockComment = test.comment, "/* */"
+		e.Row, e.Col, e.Selection = test.row, test.col, test.sel
+		if test.block { e.OnBlockComment() } else { e.OnCommentLine() }
+
+		if content(e) != test.expected { t.Errorf("%s: got %q, expected %q", name, content(e), test.expected) }
+		if e.Col != test.col2 { t.Errorf("%s: cursor at %d, expected %d", name, e.Col, test.col2) }
+		if test.sel.IsSelected && e.Selection != test.sel2 { t.Errorf("%s: selection %+v, expected %+v", name, e.Selection, test.sel2) }
+
+		e.OnUndo()
+		if content(e) != test.code { t.Errorf("%s: undo got %q", name, content(e)) }
+	}
+}
@@ -0,0 +1,352 @@
+package ui
+
+import (
+	"edgo/internal/keymap"
+	. "edgo/internal/operations"
+	. "edgo/internal/search"
+	. "edgo/internal/selection"
+	. "edgo/internal/utils"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Cursor is an additional cursor for multi-cursor editing,
+// the main cursor is still e.Row, e.Col and e.Selection
+type Cursor struct {
+	Row       int
+	Col       int
+	Selection Selection
+}
+
+func (e *Editor) CleanCursors() {
+	e.Cursors = nil
+}
+
+// commands that run for every cursor
+var cursorsCommands = map[string]editorCommand{
+	"paste":    (*Editor).OnCursorsPaste,
+	"indent":   func(e *Editor) { e.forEachCursor(func(int) { e.OnTab() }) },
+	"unindent": func(e *Editor) { e.forEachCursor(func(int) { e.OnBackTab() }) },
+}
+
+// commands that keep additional cursors, other commands work with the main cursor only
+var addCursorCommands = map[string]bool{"add-cursor-below": true, "add-cursor-above": true, "add-next-occurrence": true}
+
+// handles keys while there are additional cursors, returns false if the key is not for all cursors
+func (e *Editor) HandleCursorsKeyboard(key Key, ev *EventKey, modifiers ModMask) bool {
+	if len(e.Cursors) == 0 { return false }
+	if key == KeyEscape { e.CleanCursors(); e.Selection.CleanSelection(); return true }
+
+	// bound keys are commands first, ctrl+h is the same key as backspace
+	command, handled := e.lookupCommand(keymap.Editor, ev)
+	if command != "" {
+		if run, found := cursorsCommands[command]; found { run(e); return true }
+		if !addCursorCommands[command] { e.CleanCursors() }
+		editorCommands[command](e)
+		return true
+	}
+	if handled { return true } // the key starts a sequence
+
+	if key == KeyRune && modifiers&(ModAlt|ModCtrl) == 0 {
+		e.forEachCursor(func(int) { e.AddChar(ev.Rune()) })
+		return true
+	}
+	if key == KeyBackspace || key == KeyBackspace2 { e.forEachCursor(func(int) { e.OnDelete() }); return true }
+	if key == KeyEnter { e.forEachCursor(func(int) { e.OnEnter() }); return true }
+
+	if modifiers == 0 && (key == KeyLeft || key == KeyRight || key == KeyUp || key == KeyDown) {
+		e.moveCursors(key)
+		return true
+	}
+
+	e.CleanCursors()
+	return false
+}
+
+// adds cursor on the next line after the last cursor
+func (e *Editor) OnAddCursorBelow() {
+	last := Cursor{e.Row, e.Col, e.Selection}
+	for _, c := range e.Cursors { if c.Row > last.Row { last = c } }
+	if last.Row+1 >= len(e.Content) { return }
+
+	row := last.Row + 1
+	e.Cursors = append(e.Cursors, Cursor{Row: row, Col: Min(last.Col, len(e.Content[row])), Selection: emptySelection()})
+	e.Update = true
+}
+
+// adds cursor on the line before the first cursor
+func (e *Editor) OnAddCursorAbove() {
+	first := Cursor{e.Row, e.Col, e.Selection}
+	for _, c := range e.Cursors { if c.Row < first.Row { first = c } }
+	if first.Row == 0 { return }
+
+	row := first.Row - 1
+	e.Cursors = append(e.Cursors, Cursor{Row: row, Col: Min(first.Col, len(e.Content[row])), Selection: emptySelection()})
+	e.Update = true
+}
+
+// selects word under cursor, or adds a cursor selecting next occurrence of the selected text
+func (e *Editor) OnAddNextOccurrence() {
+	if !e.Selection.IsSelectionNonEmpty() {
+		from, to := e.Col, e.Col
+		line := e.Content[e.Row]
+		for from > 0 && isWordRune(line[from-1]) { from-- }
+		for to < len(line) && isWordRune(line[to]) { to++ }
+		if from == to { return }
+
+		e.Selection = Selection{Ssx: from, Ssy: e.Row, Sex: to, Sey: e.Row, IsSelected: true}
+		e.Col = to
+		e.Update = true
+		return
+	}
+
+	pattern := e.Selection.GetSelectionString(e.Content)
+	if strings.Contains(pattern, "\n") { return }
+	length := utf8.RuneCountInString(pattern)
+
+	// search after the last added cursor, wrapping to the beginning
+	last := Cursor{e.Row, e.Col, e.Selection}
+	if len(e.Cursors) > 0 { last = e.Cursors[len(e.Cursors)-1] }
+
+	results := e.searchRunes(pattern)
+	if len(results) == 0 { return }
+	sort.SliceStable(results, func(i, j int) bool {
+		ai := results[i].Line < last.Row || results[i].Line == last.Row && results[i].Position < last.Col
+		aj := results[j].Line < last.Row || results[j].Line == last.Row && results[j].Position < last.Col
+		return !ai && aj
+	})
+
+	for _, r := range results {
+		if e.isCursorAt(r.Line, r.Position+length) { continue }
+		e.Cursors = append(e.Cursors, Cursor{
+			Row: r.Line, Col: r.Position + length,
+			Selection: Selection{Ssx: r.Position, Ssy: r.Line, Sex: r.Position + length, Sey: r.Line, IsSelected: true},
+		})
+		e.Update = true
+		return
+	}
+}
+
+// puts a cursor selecting every search result, the first one becomes the main cursor
+func (e *Editor) OnCursorsAtSearchResults(pattern string) {
+	results := e.searchRunes(pattern)
+	if len(results) == 0 { return }
+	length := utf8.RuneCountInString(pattern)
+
+	e.Cursors = nil
+	for i, r := range results {
+		selection := Selection{Ssx: r.Position, Ssy: r.Line, Sex: r.Position + length, Sey: r.Line, IsSelected: true}
+		if i == 0 {
+			e.Row, e.Col, e.Selection = r.Line, r.Position+length, selection
+			continue
+		}
+		e.Cursors = append(e.Cursors, Cursor{Row: r.Line, Col: r.Position + length, Selection: selection})
+	}
+	e.Update = true
+}
+
+// pastes to every cursor, if clipboard has as many lines as cursors, each cursor gets its own line
+func (e *Editor) OnCursorsPaste() {
+	text := e.pasteText()
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+	count := len(e.Cursors) + 1
+
+	e.forEachCursor(func(n int) {
+		if e.Selection.IsSelectionNonEmpty() { e.Cut(false) }
+		if len(lines) == count { e.insertAtCursor(lines[n]) } else { e.insertAtCursor(text) }
+	})
+	e.IsContentChanged = true
+	e.UpdateColors()
+	e.FindTests()
+}
+
+// inserts text at the cursor, text may contain line breaks
+func (e *Editor) insertAtCursor(s string) {
+	e.insertText(e.Row, e.Col, s)
+
+	ops := EditOperation{}
+	for _, ch := range s {
+		if ch == '\n' {
+			ops = append(ops, Operation{Enter, '\n', e.Row, e.Col})
+			after := append([]rune{}, e.Content[e.Row][e.Col:]...)
+			e.Content[e.Row] = e.Content[e.Row][:e.Col]
+			e.Row++; e.Col = 0
+			e.Content = InsertTo(e.Content, e.Row, after)
+			continue
+		}
+		ops = append(ops, Operation{Insert, ch, e.Row, e.Col})
+		e.Content[e.Row] = InsertTo(e.Content[e.Row], e.Col, ch)
+		e.Col++
+	}
+	e.UndoTree.Push(ops)
+}
+
+/*
+	Runs edit action once for every cursor, from the last cursor to the first one.
+	Action works with the main cursor fields, so each cursor is loaded there before the action,
+	operations recorded by the action are used to shift the other cursors.
+	All recorded operations are merged to a single undo step.
+	Action gets the cursor number in document order.
+*/
+func (e *Editor) forEachCursor(action func(n int)) {
+	if len(e.Cursors) == 0 { action(0); return }
+
+	cursors := append([]Cursor{{e.Row, e.Col, e.Selection}}, e.Cursors...) // main cursor is the first
+	order := make([]int, len(cursors))
+	for i := range order { order[i] = i }
+	sort.Slice(order, func(i, j int) bool {
+		a, b := cursors[order[i]], cursors[order[j]]
+		return a.Row > b.Row || a.Row == b.Row && a.Col > b.Col
+	})
+
+	undoStart := e.UndoTree.Current
+	e.isMultiEdit = true
+
+	for n, i := range order {
+		e.Row, e.Col, e.Selection = cursors[i].Row, cursors[i].Col, cursors[i].Selection
+		before := e.UndoTree.Current
+
+		action(len(order) - 1 - n)
+
+		cursors[i] = Cursor{e.Row, e.Col, e.Selection}
+		for _, ops := range e.UndoTree.Since(before) {
+			for _, op := range ops {
+				for j := range cursors {
+					if j != i { cursors[j] = cursors[j].shift(op) }
+				}
+			}
+		}
+	}
+
+	e.isMultiEdit = false
+
+	e.UndoTree.MergeSince(undoStart)
+
+	e.Row, e.Col, e.Selection = cursors[0].Row, cursors[0].Col, cursors[0].Selection
+	e.Cursors = cursors[1:]
+	e.removeDuplicateCursors()
+	e.Update = true
+	e.AutoSave()
+}
+
+// moves position the same way an edit operation moved the text
+func (c Cursor) shift(op Operation) Cursor {
+	c.Row, c.Col = shiftPosition(c.Row, c.Col, op)
+	if c.Selection.IsSelected {
+		c.Selection.Ssy, c.Selection.Ssx = shiftPosition(c.Selection.Ssy, c.Selection.Ssx, op)
+		c.Selection.Sey, c.Selection.Sex = shiftPosition(c.Selection.Sey, c.Selection.Sex, op)
+	}
+	return c
+}
+
+func shiftPosition(row, col int, op Operation) (int, int) {
+	switch op.Action {
+	case Insert:
+		if row == op.Line && col >= op.Column { col++ }
+	case Delete:
+		if row == op.Line && col > op.Column { col-- }
+	case Enter: // line is split at column
+		if row == op.Line && col >= op.Column { row++; col -= op.Column } else if row > op.Line { row++ }
+	case DeleteLine: // next line is joined at column
+		if row == op.Line+1 { row = op.Line; col += op.Column } else if row > op.Line+1 { row-- }
+	}
+	return row, col
+}
+
+func (e *Editor) moveCursors(key Key) {
+	if key == KeyLeft { e.OnLeft() }
+	if key == KeyRight { e.OnRight() }
+	if key == KeyUp { e.OnUp() }
+	if key == KeyDown { e.OnDown() }
+	e.Selection.CleanSelection()
+
+	for i, c := range e.Cursors {
+		switch key {
+		case KeyLeft:
+			if c.Col > 0 { c.Col-- } else if c.Row > 0 { c.Row--; c.Col = len(e.Content[c.Row]) }
+		case KeyRight:
+			if c.Col < len(e.Content[c.Row]) { c.Col++ } else if c.Row+1 < len(e.Content) { c.Row++; c.Col = 0 }
+		case KeyUp:
+			if c.Row > 0 { c.Row--; c.Col = Min(c.Col, len(e.Content[c.Row])) }
+		case KeyDown:
+			if c.Row+1 < len(e.Content) { c.Row++; c.Col = Min(c.Col, len(e.Content[c.Row])) }
+		}
+		c.Selection = emptySelection()
+		e.Cursors[i] = c
+	}
+	e.removeDuplicateCursors()
+}
+
+func (e *Editor) removeDuplicateCursors() {
+	cursors := []Cursor{}
+	for _, c := range e.Cursors {
+		if c.Row == e.Row && c.Col == e.Col { continue }
+		duplicate := false
+		for _, added := range cursors { if added.Row == c.Row && added.Col == c.Col { duplicate = true } }
+		if !duplicate { cursors = append(cursors, c) }
+	}
+	e.Cursors = cursors
+}
+
+func (e *Editor) isCursorAt(row, col int) bool {
+	if e.Row == row && e.Col == col { return true }
+	for _, c := range e.Cursors { if c.Row == row && c.Col == col { return true } }
+	return false
+}
+
+func (e *Editor) IsUnderCursorsSelection(x, y int) bool {
+	for _, c := range e.Cursors {
+		if c.Selection.IsUnderSelection(x, y) { return true }
+	}
+	return false
+}
+
+// draws additional cursors, the main one is the terminal cursor
+func (e *Editor) DrawCursors() {
+	for _, c := range e.Cursors {
+		if e.softWrap {
+			x, y, visible := e.wrapPosition(c.Row, c.Col)
+			if !visible || x >= e.COLUMNS { continue }
+			mainc, _, style, _ := e.Screen.GetContent(x, y)
+			e.Screen.SetContent(x, y, mainc, nil, style.Reverse(true))
+			continue
+		}
+		row := e.screenRow(c.Row)
+		if row < 0 || row >= e.ROWS || c.Row >= len(e.Content) { continue }
+
+		tabcorrection := 0
+		if e.X == 0 { tabcorrection = CountTabsTo(e.Content[c.Row], c.Col) * (e.langTabWidth - 1) }
+		x := c.Col - e.X + e.LINES_WIDTH + e.FilesPanelWidth + tabcorrection
+		if x < e.LINES_WIDTH+e.FilesPanelWidth || x >= e.COLUMNS { continue }
+
+		mainc, _, style, _ := e.Screen.GetContent(x, row)
+		e.Screen.SetContent(x, row, mainc, nil, style.Reverse(true))
+	}
+}
+
+func (e *Editor) cursorsStatus() string {
+	if len(e.Cursors) == 0 { return "" }
+	return fmt.Sprintf(" %d cursors", len(e.Cursors)+1)
+}
+
+// search results with rune positions
+func (e *Editor) searchRunes(pattern string) []SearchResult {
+	results := Search(e.Content, pattern)
+	for i, r := range results {
+		results[i].Position = utf8.RuneCountInString(string(e.Content[r.Line])[:r.Position])
+	}
+	return results
+}
+
+func isWordRune(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_'
+}
+
+func emptySelection() Selection {
+	return Selection{Ssx: -1, Ssy: -1, Sex: -1, Sey: -1}
+}
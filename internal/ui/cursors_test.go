@@ -0,0 +1,77 @@
+package ui
+
+import (
+	. "edgo/internal/operations"
+	. "github.com/gdamore/tcell"
+	"testing"
+)
+
+func TestShiftPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		op       Operation
+		row, col int
+		expected [2]int
+	}{
+		{"insert before on the row", Operation{Action: Insert, Line: 1, Column: 2}, 1, 5, [2]int{1, 6}},
+		{"insert at the position", Operation{Action: Insert, Line: 1, Column: 5}, 1, 5, [2]int{1, 6}},
+		{"insert after on the row", Operation{Action: Insert, Line: 1, Column: 6}, 1, 5, [2]int{1, 5}},
+		{"insert on another row", Operation{Action: Insert, Line: 0, Column: 0}, 1, 5, [2]int{1, 5}},
+		{"delete before on the row", Operation{Action: Delete, Line: 1, Column: 2}, 1, 5, [2]int{1, 4}},
+		{"delete at the position", Operation{Action: Delete, Line: 1, Column: 5}, 1, 5, [2]int{1, 5}},
+		{"enter before on the row", Operation{Action: Enter, Line: 1, Column: 2}, 1, 5, [2]int{2, 3}},
+		{"enter after on the row", Operation{Action: Enter, Line: 1, Column: 7}, 1, 5, [2]int{1, 5}},
+		{"enter on a row above", Operation{Action: Enter, Line: 0, Column: 3}, 1, 5, [2]int{2, 5}},
+		{"join of the row", Operation{Action: DeleteLine, Line: 0, Column: 4}, 1, 5, [2]int{0, 9}},
+		{"join of a row above", Operation{Action: DeleteLine, Line: 0, Column: 4}, 3, 5, [2]int{2, 5}},
+	}
+	for _, test := range tests {
+		row, col := shiftPosition(test.row, test.col, test.op)
+		if [2]int{row, col} != test.expected { t.Errorf("%s: got %d:%d, expected %v", test.name, row, col, test.expected) }
+	}
+}
+
+func TestCursorsOnOneRow(t *testing.T) {
+	e := testEditor("abc abc abc\nabc")
+	e.Config.Save.AutoSave = "off"
+	setCursors := func(cursors ...Cursor) {
+		e.Row, e.Col, e.Selection = cursors[0].Row, cursors[0].Col, emptySelection()
+		e.Cursors = nil
+		for _, c := range cursors[1:] { e.Cursors = append(e.Cursors, Cursor{c.Row, c.Col, emptySelection()}) }
+	}
+	press := func(key Key, ch rune) { e.HandleKeyboard(key, NewEventKey(key, ch, ModNone), ModNone) }
+	check := func(step, expected string, cursors ...Cursor) {
+		t.Helper()
+		if content(e) != expected { t.Errorf("%s: content %q, expected %q", step, content(e), expected) }
+		if e.Text.String() != content(e) { t.Errorf("%s: text is not synced, %q", step, e.Text.String()) }
+		got := append([]Cursor{{e.Row, e.Col, e.Selection}}, e.Cursors...)
+		if len(got) != len(cursors) { t.Fatalf("%s: %d cursors, expected %d", step, len(got), len(cursors)) }
+		for i, c := range cursors {
+			if got[i].Row != c.Row || got[i].Col != c.Col { t.Errorf("%s: cursor %d at %d:%d, expected %d:%d", step, i, got[i].Row, got[i].Col, c.Row, c.Col) }
+		}
+	}
+
+	// later cursors on the row are shifted by edits of earlier ones
+	setCursors(Cursor{Row: 0, Col: 3}, Cursor{Row: 0, Col: 7}, Cursor{Row: 0, Col: 11})
+	press(KeyRune, 'x')
+	check("type", "abcx abcx abcx\nabc", Cursor{Row: 0, Col: 4}, Cursor{Row: 0, Col: 9}, Cursor{Row: 0, Col: 14})
+
+	press(KeyBackspace2, 0)
+	check("backspace", "abc abc abc\nabc", Cursor{Row: 0, Col: 3}, Cursor{Row: 0, Col: 7}, Cursor{Row: 0, Col: 11})
+
+	press(KeyEnter, 0)
+	check("enter", "abc\nabc\nabc\n\nabc", Cursor{Row: 1, Col: 0}, Cursor{Row: 2, Col: 0}, Cursor{Row: 3, Col: 0})
+
+	e.OnUndo()
+	if content(e) != "abc abc abc\nabc" { t.Errorf("edit of all cursors should be one undo step, got %q", content(e)) }
+
+	// bound commands run for every cursor
+	setCursors(Cursor{Row: 0, Col: 0}, Cursor{Row: 1, Col: 0})
+	press(KeyTab, 0)
+	check("indent", "\tabc abc abc\n\tabc", Cursor{Row: 0, Col: 1}, Cursor{Row: 1, Col: 1})
+
+	// other commands work with the main cursor only
+	press(KeyCtrlD, 0)
+	if len(e.Cursors) != 0 { t.Errorf("cursors should be cleaned by duplicate, got %d", len(e.Cursors)) }
+	if content(e) != "\tabc abc abc\n\tabc abc abc\n\tabc" { t.Errorf("duplicate: content %q", content(e)) }
+}
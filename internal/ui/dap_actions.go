@@ -2,6 +2,7 @@ package ui
 
 import (
 	dap "edgo/internal/dap"
+	"edgo/internal/keymap"
 	. "edgo/internal/logger"
 	. "edgo/internal/utils"
 	//"encoding/json"
@@ -54,7 +55,7 @@ func (e *Editor) OnDebug() {
 	if (e.Lang == "" || e.langConf.Cmd == "") { return }
 
 	if e.ProcessPanelHeight == 0 {
-		e.ProcessPanelHeight = 10
+		e.ProcessPanelHeight = e.openedProcessPanelHeight()
 		e.COLUMNS, e.ROWS = e.Screen.Size()
 		e.ROWS -= e.ProcessPanelHeight
 	}
@@ -218,29 +219,7 @@ func (e *Editor) GetDebugInfo()  {
 
 
 func (e *Editor) OnDebugKeyHandle(key Key, ev *EventKey, threadId int) {
-	keyrune := ev.Rune()
-
-	if key == KeyRune && keyrune == 'c' {
-		e.Dap.Continue(threadId)
-		e.DebugInfo.stopline = -1
-	}
-
-	if key == KeyRune && keyrune == 'n' {
-		e.Dap.Next(threadId)
-	}
-	if key == KeyRune && keyrune == 'b' {
-		e.Breakpoint()
-		e.DrawEverything()
-		e.Screen.Show()
-	}
-	if key == KeyRune && keyrune == 's' {
-		e.Dap.StepIn(threadId)
-	}
-	if key == KeyRune && keyrune == 'q' {
-		e.OnDebugStop()
-	}
-	if key == KeyRune && keyrune == 'p' {
-		e.Dap.Pause(threadId)
-	}
+	command, _ := e.lookupCommand(keymap.Debug, ev)
+	if action, found := debugCommands[command]; found { action(e, threadId) }
 }
 
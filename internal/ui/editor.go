@@ -4,17 +4,25 @@ import (
 	. "edgo/internal/config"
 	"edgo/internal/dap"
 	. "edgo/internal/highlighter"
+	"edgo/internal/editorconfig"
+	"edgo/internal/fileformat"
 	. "edgo/internal/io"
+	"edgo/internal/keymap"
 	. "edgo/internal/logger"
 	. "edgo/internal/lsp"
+	"edgo/internal/clipboard"
+	"edgo/internal/macro"
+	"edgo/internal/marks"
 	. "edgo/internal/operations"
 	. "edgo/internal/process"
 	. "edgo/internal/search"
 	. "edgo/internal/selection"
+	"edgo/internal/session"
 	. "edgo/internal/tests"
+	"edgo/internal/text"
+	"edgo/internal/undo"
 	. "edgo/internal/utils"
 	"fmt"
-	"github.com/atotto/clipboard"
 	. "github.com/gdamore/tcell"
 	"github.com/gdamore/tcell/encoding"
 	"github.com/rjeczalik/notify"
@@ -44,6 +52,13 @@ type Editor struct {
 	X   int // col offset for scrolling
 
 	Content [][]rune // text characters
+	Text    *text.PieceTable // same text as piece table, for reading without copying
+
+	Format        fileformat.Format // encoding, bom, line ending and final newline of the file on disk
+	IsFullyLoaded bool        // false while a large file is read in background or if reading failed, such content is never written
+	NoHighlight   bool        // syntax highlighting is off, file is larger than configured threshold
+	loader        *fileLoader // reads the rest of a large file
+	lineEndings   *fileformat.Endings // of a file with mixed line endings, nil for others
 
 	Screen Screen // Screen for drawing
 
@@ -51,11 +66,50 @@ type Editor struct {
 	Config       Config // config, lsp, tabs, comments, etc
 	langConf     Lang   // current lang conf
 	langTabWidth int    // current lang tabs indentation  '\t' -> "    "
+	indentSpaces int    // indentation by spaces, by tabs if 0
+	softWrap     bool   // long lines are wrapped, see wrap.go
+	wrapTop      int    // hidden wrapped segments of the first visible line
+	wrapY        int    // Y of the last draw
+	wrapCursor   [2]int // cursor of the last draw
+	wrapGoal     int    // screen column kept by moves between rows, -1 if none
+	wrapKeepGoal bool
+	wrapRows     []wrapRow // screen rows of the last draw
+	editorConfig editorconfig.Properties // .editorconfig properties of the file
+	folds        []fold      // closed folds ordered by the first line, see fold.go
+	foldLines    int         // line count the folds are kept for
+	foldCursor   [2]int      // cursor of the last draw
+	foldCache    []fold      // foldable regions of foldCacheVersion
+	foldCacheVersion foldVersion
+	pasted       pasteRange  // lines of the last paste, see OnReindent
+	bracketMatch  [2]int      // byte offsets of the bracket pair at the cursor, see brackets.go
+	bracketLevels map[int]int // nesting levels of visible brackets by byte offset, if rainbow is on
 
 	Selection Selection // selection
-
-	Undo []EditOperation // stack for undo operations
-	Redo []EditOperation // stack for redo operations
+	Cursors   []Cursor  // additional cursors for multi-cursor editing
+
+	isMultiEdit bool // an edit is being applied to every cursor, see forEachCursor
+	blockClipboard string // text of the last copied block, pasted line by line
+	isBlockDrag bool // mouse button is held since block drag started
+	clipboard *clipboard.Clipboard // system or terminal clipboard with history of copied texts
+	vim vimState // modal editing state, used if Config.Vim is set
+
+	Keymap      *keymap.Keymap // key bindings of commands, see commands.go
+	pendingKeys []keymap.Chord // beginning of multi-key sequence
+	lastKeys []keymap.Chord // sequence of the last executed command
+	macros macro.Macros // recorded macros by register, saved in state dir
+	macroRecording string // register being recorded into, empty if not recording
+	macroKeys []keymap.Chord // keys recorded so far
+	macroMark int // recorded keys count before the current event
+	macroQueue []Event // keys of playing macros, read before the screen events
+	macroDepth int // nested macro plays
+	marks marks.Marks // bookmarks and named marks of the project, saved in state dir
+	searchHistory []string // search patterns, the latest last, kept in session
+	NoSession bool // launched with -no-session, the last session is not restored and not saved
+	isFileArg bool // launched with a file, the session of the directory is kept as it was
+	swapped map[string]swapVersion // buffers with written swap files
+	saveTimer *time.Timer // idle autosave
+
+	UndoTree *undo.Tree // every state of the buffer, undo and redo move over it
 
 	Cwd              string // current dir
 	InputFile        string // exact user input
@@ -91,6 +145,7 @@ type Editor struct {
 
 	// process panel vars
 	ProcessPanelHeight            int
+	processPanelSize              int // the last dragged or restored height
 	ProcessPanelWidth             int
 	ProcessContent                [][]rune
 	ProcessPanelScroll            int
@@ -125,11 +180,14 @@ type Editor struct {
 
 	HighlightElements map[int][]NodeRange
 
+	Buffers     []*Buffer // opened files
+	BufferIndex int       // active buffer index, -1 if none
+
 	// drawingWg sync.WaitGroup
 	mu sync.Mutex
 }
 
-func (e *Editor) Start() {
+func (e *Editor) Start(args []string) {
 	Log.Info("starting edgo")
 
 	e.Init()
@@ -137,13 +195,18 @@ func (e *Editor) Start() {
 	cwd, _ := os.Getwd()
 	e.Cwd = cwd
 
+	e.OnSwapRecovery()
+	e.startSwapTimer()
+
 	// reading file from cmd args
-	if len(os.Args) == 1 {
-		// if no args, open current dir
-		e.DrawLogo()
-		e.OnFilesTree(false)
+	if len(args) == 0 {
+		// if no args, open the last session or current dir
+		if !e.restoreSession() {
+			e.DrawLogo()
+			e.OnFilesTree(false)
+		}
 	} else {
-		e.Filename = os.Args[1]
+		e.Filename = args[0]
 		e.InputFile = e.Filename
 
 		info, err := os.Stat(e.InputFile)
@@ -153,9 +216,11 @@ func (e *Editor) Start() {
 			// if arg is dir, go to dir and open
 			err = os.Chdir(e.InputFile)
 			if err != nil { log.Fatal(err) }
-			e.OnFilesTree(true)
+			e.Cwd, _ = os.Getwd()
+			if !e.restoreSession() { e.OnFilesTree(true) }
 		} else {
 			// if arg is file, open file
+			e.isFileArg = true
 			err := e.OpenFile(e.InputFile)
 			if err != nil { log.Fatal(err) }
 		}
@@ -163,6 +228,7 @@ func (e *Editor) Start() {
 
 	// main draw cycle
 	for {
+		e.applyLoadedLines()
 		if e.Update && e.Filename != "" {
 			e.DrawEverything()
 			e.Screen.Show()
@@ -178,7 +244,8 @@ func (e *Editor) Exit() {
 func (e *Editor) HandleEvents() {
 	//e.Update = false
 	e.Update = true
-	ev := e.Screen.PollEvent()
+	e.macroMark = len(e.macroKeys)
+	ev := e.PollEvent()
 	switch ev := ev.(type) {
 	case *EventResize:
 		e.COLUMNS, e.ROWS = e.Screen.Size()
@@ -188,6 +255,11 @@ func (e *Editor) HandleEvents() {
 		e.DrawEverything()
 		e.Screen.Show()
 
+	case *EventInterrupt:
+		if _, ok := ev.Data().(swapTick); ok { e.Update = e.WriteSwaps() } // redraw only if a swap changed
+		if tick, ok := ev.Data().(saveTick); ok { e.onSaveTick(tick) }
+		if ready, ok := ev.Data().(lspReady); ok { e.onLspReady(ready) }
+
 	case *EventMouse:
 		mx, my := ev.Position()
 		buttons := ev.Buttons()
@@ -212,6 +284,7 @@ func (e *Editor) HandleEvents() {
 
 func (e *Editor) HandleMouse(mx int, my int, buttons ButtonMask, modifiers ModMask) {
 	_, screenRows := e.Screen.Size()
+	if buttons&Button1 != 0 { e.CleanCursors() } else { e.isBlockDrag = false }
 
 	// upper play button
 	if mx == e.COLUMNS-2 && my == 0 && buttons&Button1 == 1 {
@@ -259,6 +332,7 @@ func (e *Editor) HandleMouse(mx int, my int, buttons ButtonMask, modifiers ModMa
 	if e.IsProcessPanelMoving && buttons&Button1 == 1 && screenRows >= my {
 		e.ROWS = my
 		e.ProcessPanelHeight = screenRows - e.ROWS
+		e.processPanelSize = e.ProcessPanelHeight
 		e.Update = true
 		return
 	}
@@ -307,7 +381,7 @@ func (e *Editor) HandleMouse(mx int, my int, buttons ButtonMask, modifiers ModMa
 		if buttons&Button1 == 0 {
 			if e.ProcessPanelSelection.IsSelectionNonEmpty() {
 				selectionString := e.ProcessPanelSelection.GetSelectionString(e.ProcessContent)
-				clipboard.WriteAll(selectionString)
+				e.copyText(selectionString)
 			}
 
 			e.ProcessPanelSelection.CleanSelection()
@@ -340,8 +414,15 @@ func (e *Editor) HandleMouse(mx int, my int, buttons ButtonMask, modifiers ModMa
 
 	if e.Filename == "" { return }
 
+	if buttons&Button1 == 1 { // click on a tab
+		if index := e.FindTabUnderMouse(mx, my); index != -1 {
+			e.SwitchBuffer(index)
+			return
+		}
+	}
+
 	if buttons&Button1 == 1 && mx == e.COLUMNS-2 { // test button
-		line := my + e.Y
+		line := e.screenLine(my)
 		if _, found := e.Tests[line]; found {
 			e.RunTest(e.Tests[line])
 			return
@@ -349,18 +430,26 @@ func (e *Editor) HandleMouse(mx int, my int, buttons ButtonMask, modifiers ModMa
 
 	}
 
+	if buttons&Button1 == 1 && mx == e.LINES_WIDTH+e.FilesPanelWidth-1 { // fold marker
+		if e.toggleFold(e.screenLine(my)) { return }
+	}
+
 	mx -= e.LINES_WIDTH + e.FilesPanelWidth
 
 	if mx < 0 { return }
 	if my > e.ROWS { return }
 	if e.Content == nil { return }
 
+	// drag with control and option selects block
+	if buttons&Button1 == 1 && modifiers&ModAlt != 0 && modifiers&ModCtrl != 0 {
+		e.OnBlockDrag(e.screenLine(my), mx+e.X, !e.isBlockDrag)
+		e.isBlockDrag = true
+		return
+	}
+
 	// if click with control or option, lookup for definition or references
 	if buttons&Button1 == 1 && (modifiers&ModAlt != 0 || modifiers&ModCtrl != 0) {
-		e.Row = my + e.Y
-		if e.Row > len(e.Content)-1 { e.Row = len(e.Content) - 1 } // fit cursor to e.Content
-
-		e.Col = e.FindCursorXPosition(mx)
+		e.Row, e.Col = e.mousePosition(mx, my)
 
 		if len(e.Selection.GetSelectedLines(e.Content)) > 0 { // if text selected
 			e.Selection.Sey = e.Row
@@ -375,18 +464,14 @@ func (e *Editor) HandleMouse(mx int, my int, buttons ButtonMask, modifiers ModMa
 
 	if e.Selection.IsSelected && buttons&Button1 == 1 {
 		e.Update = true
-		e.Row = my + e.Y
-		if e.Row > len(e.Content)-1 { e.Row = len(e.Content) - 1 } // fit cursor to e.Content
-
-		xPosition := e.FindCursorXPosition(mx)
+		var xPosition int
+		e.Row, xPosition = e.mousePosition(mx, my)
 
 		isTripleClick := e.Selection.IsUnderSelection(xPosition, e.Row) &&
 			len(e.Selection.GetSelectedLines(e.Content)) == 1
 
 		if isTripleClick {
-			e.Row = my + e.Y
 			e.Col = xPosition
-			if e.Row > len(e.Content)-1 { e.Row = len(e.Content) - 1 } // fit cursor to e.Content
 			if e.Col > len(e.Content[e.Row]) { e.Col = len(e.Content[e.Row]) }
 			//if e.Col < 0 { Sex = len(e.Content[Row]) }
 
@@ -417,10 +502,8 @@ func (e *Editor) HandleMouse(mx int, my int, buttons ButtonMask, modifiers ModMa
 	if buttons&Button1 == 1 {
 		e.Update = true
 
-		e.Row = my + e.Y
-		if e.Row > len(e.Content)-1 { e.Row = len(e.Content) - 1 } // fit cursor to e.Content
-
-		xPosition := e.FindCursorXPosition(mx)
+		var xPosition int
+		e.Row, xPosition = e.mousePosition(mx, my)
 
 		if prevRow == e.Row && e.Col == xPosition && len(e.Selection.GetSelectedLines(e.Content)) == 0 {
 			// double click
@@ -457,48 +540,18 @@ func (e *Editor) HandleMouse(mx int, my int, buttons ButtonMask, modifiers ModMa
 }
 
 func (e *Editor) HandleKeyboard(key Key, ev *EventKey, modifiers ModMask) {
-	if key == KeyCtrlF && !e.IsProcessPanelFocused { e.OnSearch() }
-	if key == KeyCtrlY { e.OnLangLinesCount() }
-
-	if e.Filename == "" && key != KeyCtrlQ { return }
-
 	if e.IsProcessPanelFocused {
-		e.OnProcessKeyHandle(key, ev.Rune())
+		e.OnProcessKeyHandle(key, ev)
 		return
 	}
 
-	intrune := int(ev.Rune())
-	if ev.Rune() == '/' && modifiers&ModAlt != 0 || intrune == '÷' {
-		// '÷' is option + '/' on Mac
-		e.OnCommentLine()
-		return
-	}
-	if intrune == '¨' {
-		// '¨' is option + u on Mac
-		e.OnRedo()
-		return
-	}
+	if e.HandleVimKeyboard(key, ev, modifiers) { return }
+	if e.HandleCursorsKeyboard(key, ev, modifiers) { return }
+	if e.HandleBlockKeyboard(key, ev, modifiers) { return }
 
-	if key == KeyUp && modifiers == 3 { e.OnSwapLinesUp(); return } // control + shift + up
-	if key == KeyDown && modifiers == 3 { e.OnSwapLinesDown(); return } // control + shift + down
-	if key == KeyBacktab { e.OnBackTab(); return }
-	if key == KeyTab { e.OnTab(); return }
-	if key == KeyCtrlH { e.OnHover(); return }
-	if key == KeyCtrlR { e.OnReferences(); return }
-	if key == KeyCtrlW { e.OnCodeAction(); return }
-	if key == KeyCtrlP { e.OnSignatureHelp(); return }
-	if key == KeyCtrlG { e.OnDefinition(); return }
-	if key == KeyCtrlE { e.OnErrors(); return }
-	if key == KeyCtrlC { e.OnCopy(); return }
-	if key == KeyCtrlV { e.OnPaste(); return }
-	if key == KeyEscape { e.Selection.CleanSelection(); return }
-	if key == KeyCtrlA { e.OnSelectAll(); return }
-	if key == KeyCtrlX { e.Cut(true) }
-	if key == KeyCtrlD { e.Duplicate() }
-	if key == KeyCtrlB { e.Breakpoint() }
-	if key == KeyCtrlK { e.GoBottom(); return }
-	if key == KeyCtrlJ { e.GoTop(); return }
-	if key == KeyCtrlL { e.GoToLine(); return }
+	command, handled := e.lookupCommand(keymap.Editor, ev)
+	if command != "" && (e.Filename != "" || noFileCommands[command]) { editorCommands[command](e) }
+	if handled || e.Filename == "" { return }
 
 	if modifiers&ModShift != 0 && (key == KeyRight || key == KeyLeft || key == KeyUp || key == KeyDown) {
 
@@ -514,28 +567,7 @@ func (e *Editor) HandleKeyboard(key Key, ev *EventKey, modifiers ModMask) {
 		return
 	}
 
-	if key == KeyRune && modifiers&ModAlt != 0 && len(e.Content) > 0 {
-		e.HandleSmartMove(ev.Rune())
-		return
-	}
-
-	if modifiers&ModAlt != 0 &&
-		(int(ev.Key()) == 259 || int(ev.Key()) == 260) &&
-		len(e.Content) > 0 {
-		e.HandleSmartMoveAlac(int(ev.Key()))
-		return
-	}
-
-	if key == KeyDown && modifiers&ModAlt != 0 {
-		e.OnSelectLessAtCursor()
-		//e.HandleSmartMoveDown();
-		return
-	}
-	if key == KeyUp && modifiers&ModAlt != 0 {
-		e.OnSelectMoreAtCursor()
-		//e.HandleSmartMoveUp();
-		return
-	}
+	if key == KeyRune && modifiers&ModAlt != 0 { return } // not bound option chords are not typed
 
 	if key == KeyRune {
 		e.AddChar(ev.Rune())
@@ -547,23 +579,12 @@ func (e *Editor) HandleKeyboard(key Key, ev *EventKey, modifiers ModMask) {
 		//if ev.Rune() == '(' { e.DrawEverything(); e.Screen.Show(); e.OnSignatureHelp(); e.Screen.Clear() }
 	}
 
-	if /*key == tcell.KeyEscape ||*/ key == KeyCtrlQ { e.Screen.Fini(); os.Exit(1) }
-	if key == KeyCtrlS { e.WriteFile() }
 	if key == KeyEnter { e.OnEnter(); return }
 	if key == KeyBackspace || key == KeyBackspace2 { e.OnDelete() }
 	if key == KeyDown { e.OnDown(); e.Selection.CleanSelection() }
 	if key == KeyUp { e.OnUp(); e.Selection.CleanSelection() }
 	if key == KeyLeft { e.OnLeft(); e.Selection.CleanSelection() }
 	if key == KeyRight { e.OnRight(); e.Selection.CleanSelection() }
-	if key == KeyCtrlT { e.OnFilesTree(true) }
-	if key == KeyF18 { e.OnRename() }
-	if key == KeyF22 { e.OnProcessRun(true) }
-	if key == KeyF23 { e.OnDebug() }
-	if key == KeyCtrlU { e.OnUndo() }
-	if key == KeyCtrlO { e.OnCursorBack() }
-	if key == KeyCtrlRightSq { e.OnCursorBackUndo() }
-	if key == KeyCtrlSpace { e.OnCompletion() }
-
 }
 
 func (e *Editor) OpenFile(fname string) error {
@@ -575,45 +596,56 @@ func (e *Editor) OpenFile(fname string) error {
 
 	absoluteDir, err := filepath.Abs(path.Dir(fname))
 	if err != nil { return err }
+	absoluteFilePath := path.Join(absoluteDir, filepath.Base(fname))
+
+	// already opened, switch to it without reading from disk
+	if index := e.FindBuffer(absoluteFilePath); index != -1 {
+		e.SwitchBuffer(index)
+		e.UpdateFilesOpenStats(fname)
+		return nil
+	}
+
+	e.autoSaveOnLeave()
+	e.storeBuffer()
+	e.Buffers = append(e.Buffers, &Buffer{})
+	e.BufferIndex = len(e.Buffers) - 1
+
 	//directory := absoluteDir;
 	e.Filename = filepath.Base(fname)
-	e.AbsoluteFilePath = path.Join(absoluteDir, e.Filename)
+	e.AbsoluteFilePath = absoluteFilePath
 
 	Log.Info("open", e.AbsoluteFilePath)
 
-	newLang := DetectLang(e.AbsoluteFilePath)
-	Log.Info("new lang is", newLang)
-
-	if newLang != "" && newLang != e.Lang {
-		e.Lang = newLang
+	e.LoadFile()
+	e.UpdateFilesOpenStats(fname)
 
-		_, found := e.lsp2lang[newLang]
-		if !found {
-			lsp := LspClient{Lang: newLang}
-			e.lsp2lang[newLang] = &lsp
-			go e.InitLsp(e.Lang)
-		}
+	e.lspOpen(e.Lang, e.AbsoluteFilePath, e.Text, e.loader == nil)
 
-		if e.Dap.Port > 0 {
-			e.Dap = dap.DapClient{Lang: newLang, Conntype: "tcp", Port: e.Dap.Port + 1}
-		} else {
-			e.Dap = dap.DapClient{Lang: newLang, Conntype: "tcp", Port: 54752}
-		}
+	e.storeBuffer()
+	return nil
+}
 
-		e.DebugInfo = DebugInfo{stopline: -1}
-	}
+// LoadFile reads the active buffer file from disk, resets cursor and history
+func (e *Editor) LoadFile() {
+	newLang := DetectLang(e.AbsoluteFilePath)
+	Log.Info("new lang is", newLang)
+	e.setLang(newLang)
 
 	conf, found := e.Config.Langs[e.Lang]
 	if !found { conf = DefaultLangConfig }
 	e.langConf = conf
 	e.langTabWidth = conf.TabWidth
+	e.softWrap = e.Config.Wrap.Enabled || conf.Wrap
+	e.folds = nil
+	e.applyEditorConfig()
 
-	code := e.ReadFile(e.AbsoluteFilePath)
+	e.ReadFile(e.AbsoluteFilePath)
 	//e.Colors = HighlighterGlobal.Colorize(code, e.Filename)
 	e.treeSitterHighlighter = NewTreeSitter()
 	e.treeSitterHighlighter.SetTheme(e.Config.Theme)
 	e.treeSitterHighlighter.SetLang(e.Lang)
-	e.treeSitterHighlighter.Parse(&code)
+	e.NoHighlight = e.loader != nil || len(e.Content) > e.Config.LargeFile.HighlightLines
+	e.parseText()
 	//e.Colors = e.treeSitterHighlighter.Colorize(code)
 	clear(e.HighlightElements)
 
@@ -628,24 +660,48 @@ func (e *Editor) OpenFile(fname string) error {
 	//	e.Removed = removed
 	//}
 
-	e.Undo = []EditOperation{}
-	e.Redo = []EditOperation{}
-
-	e.UpdateFilesOpenStats(fname)
+	e.UndoTree = undo.New()
+	e.CleanCursors()
+	e.restoreHistory()
+	e.IsContentChanged = false
 
     e.Row = 0; e.Col = 0; e.Y = 0; e.X = 0
-	e.Selection = Selection{-1,-1,-1,-1,false }
+	e.resetWrap()
+	e.Selection = Selection{-1,-1,-1,-1,false,false }
 	e.SearchResults = []SearchResult{}
+	e.TreePath = nil
 
 	e.FileWatcher.UpdateFile(e.AbsoluteFilePath)
 	e.FileWatcher.UpdateStats()
 
 	e.FindTests()
+}
 
-	return nil
+// setLang switches current language, starts lsp for a new language
+func (e *Editor) setLang(newLang string) {
+	if newLang == "" || newLang == e.Lang { return }
+	e.Lang = newLang
+
+	_, found := e.lsp2lang[newLang]
+	if !found {
+		lsp := LspClient{Lang: newLang}
+		e.lsp2lang[newLang] = &lsp
+		go e.InitLsp(e.Lang)
+	}
+
+	if e.Dap.IsStarted { return } // do not break active debug session
+
+	if e.Dap.Port > 0 {
+		e.Dap = dap.DapClient{Lang: newLang, Conntype: "tcp", Port: e.Dap.Port + 1, Breakpoints: e.Dap.Breakpoints}
+	} else {
+		e.Dap = dap.DapClient{Lang: newLang, Conntype: "tcp", Port: 54752, Breakpoints: e.Dap.Breakpoints}
+	}
+
+	e.DebugInfo = DebugInfo{stopline: -1}
 }
 
 func (e *Editor) Init() {
+	e.InitKeymap()
 	encoding.Register()
 	screen, err := NewScreen()
 	if err != nil {
@@ -670,7 +726,9 @@ func (e *Editor) Init() {
 	e.Update = true
 	e.IsColorize = true
 	e.FileSelectedIndex = -1
+	e.BufferIndex = -1
 	e.CursorHistory = []CursorMove{}
+	e.UndoTree = undo.New()
 	e.lsp2lang = map[string]*LspClient{}
 	e.DebugInfo = DebugInfo{}
 
@@ -716,8 +774,10 @@ func (e *Editor) DrawEverything() {
 	countTabsTo := CountTabsTo(e.Content[e.Row], e.Col)
 	tabcor := countTabsTo * (e.langTabWidth - 1)
 
+	e.fitFolds()
+	if e.softWrap { e.X = 0; e.fitWrapped() }
 	if e.Col < e.X { e.X = e.Col }
-	if e.Col + e.LINES_WIDTH + e.FilesPanelWidth + tabcor >= e.X + e.COLUMNS  {
+	if !e.softWrap && e.Col + e.LINES_WIDTH + e.FilesPanelWidth + tabcor >= e.X + e.COLUMNS  {
 		e.X = e.Col - e.COLUMNS + 1 + e.LINES_WIDTH + e.FilesPanelWidth + tabcor
 	}
 
@@ -729,26 +789,18 @@ func (e *Editor) DrawEverything() {
 	*/
 
 	start := time.Now()
-	code := ConvertContentToString(e.Content)
-	//Log.Info("ConvertContentToString", time.Since(start).String())
-
-	//start = time.Now()
-	coloredByteRanges := e.treeSitterHighlighter.ColorRanges(e.Y, e.Y+e.TERMINAL_HEIGHT, []byte(code))
+	var coloredByteRanges []ColoredByteRange
+	if !e.NoHighlight {
+		coloredByteRanges = e.treeSitterHighlighter.ColorRanges(e.Y, e.Y+e.TERMINAL_HEIGHT, e.Text)
+	}
+	e.findBrackets()
 	//Log.Info("ColorRanges", time.Since(start).String())
 
-	bytesCounter := 0
+	bytesCounter := e.Text.LineStart(e.Y) // byte offset of the first visible line
+	if e.softWrap { e.drawWrapped(coloredByteRanges) }
 
-	if e.Y > 0 { //  if scrolling needs to recalculate bytesCounter offset
-		newlineCount := 0
-		for _, c := range code {
-			bytesCounter += utf8.RuneLen(c)
-			if c == '\n' { newlineCount++ }
-			if newlineCount == e.Y { break }
-		}
-	}
-
-	for row := 0; row < e.ROWS; row++ {
-		ry := row + e.Y // index to get right row in characters buffer by scrolling offset Y
+	ry := e.Y // index to get right row in characters buffer by scrolling offset Y and folds
+	for row := 0; row < e.ROWS && !e.softWrap; row++ {
 		if row >= len(e.Content) || ry >= len(e.Content) { break }
 		e.DrawLineNumber(ry, row)
 
@@ -766,28 +818,7 @@ func (e *Editor) DrawEverything() {
 			isOutside := col-e.X+e.LINES_WIDTH+tabsOffset+e.FilesPanelWidth > e.COLUMNS
 			if isOutside || e.X > col { bytesCounter += utf8.RuneLen(ch); continue }
 
-			style := StyleDefault
-
-			//minRange := coloredByteRanges[0]
-
-			for _, i := range coloredByteRanges {
-				if i.StartByte <= bytesCounter && bytesCounter < i.EndByte {
-					//len := i.EndByte - i.StartByte
-					//if len < minRange.EndByte - minRange.StartByte {
-					//	minRange = i
-					style = StyleDefault.Foreground(Color(i.Color))
-					//}
-					//continue
-					break
-				}
-			}
-
-			if e.Selection.IsUnderSelection(col, ry) {
-				style = style.Background(Color(SelectionColor))
-			}
-			if e.DebugInfo.stopline == ry {
-				style = style.Background(Color(SelectionColor))
-			}
+			style := e.charStyle(ry, col, bytesCounter, coloredByteRanges)
 
 			if ch == '\t' && e.X == 0 { // draw big cursor for tab
 				if ry == e.Row && cx == e.Col {
@@ -814,7 +845,7 @@ func (e *Editor) DrawEverything() {
 				for i := helement.Ssx; !skip && i < helement.Sex; i++ {
 					x := i + e.LINES_WIDTH + e.FilesPanelWidth + tabcorrection
 					mainc, _, stylec, _ := e.Screen.GetContent(x, row)
-					if e.Selection.IsUnderSelection(i, ry) {
+					if e.isUnderSelection(i, ry) {
 						skip = true
 					} else {
 						e.Screen.SetContent(x, row, mainc, nil, stylec.Background(Color(HighlightColor)))
@@ -823,30 +854,43 @@ func (e *Editor) DrawEverything() {
 			}
 
 		}
+		e.drawFoldTail(ry, e.lineEndX(ry), row)
 
 		bytesCounter += 1 // for '/n'
+		next := e.nextVisibleLine(ry)
+		if next != ry+1 && next < len(e.Content) { bytesCounter = e.Text.LineStart(next) } // folded lines are skipped
+		ry = next
 	}
 
+	e.DrawCursors()
+	e.DrawBlockCursor()
 	e.DrawDiagnostic()
 
 	ttr := time.Since(start).String()
 	var changes = ""
 	if e.IsContentChanged { changes = "*" }
-	status := fmt.Sprintf(" %s %s %d %d %s%s ", ttr, e.Lang, e.Row+1, e.Col+1, e.Filename, changes)
+	changes += e.cursorsStatus()
+	changes += e.vimStatus()
+	if e.macroRecording != "" { changes += " recording @" + e.macroRecording }
+	if e.loader != nil { changes += " loading" } else if !e.IsFullyLoaded { changes += " partially read, not saved" }
+	status := fmt.Sprintf(" %s %s %s %d %d %s%s ", ttr, e.Lang, e.Format, e.Row+1, e.Col+1, e.Filename, changes)
+	e.DrawTabs()
 	e.DrawStatus(status)
 
 	// if tab under cursor, hide cursor because it has already drawn
-	if e.Row < len(e.Content) && e.Col < len(e.Content[e.Row]) && e.Content[e.Row][e.Col] == '\t' {
+	if e.softWrap {
+		e.showWrappedCursor()
+	} else if e.Row < len(e.Content) && e.Col < len(e.Content[e.Row]) && e.Content[e.Row][e.Col] == '\t' {
 		e.Screen.HideCursor()
 	} else {
 		tabs := CountTabsTo(e.Content[e.Row], e.Col) * (e.langTabWidth - 1)
-		e.Screen.ShowCursor(e.Col-e.X+e.LINES_WIDTH+tabs+e.FilesPanelWidth, e.Row-e.Y) // show cursor
+		e.Screen.ShowCursor(e.Col-e.X+e.LINES_WIDTH+tabs+e.FilesPanelWidth, e.screenRow(e.Row)) // show cursor
 		if e.X != 0 {
-			e.Screen.ShowCursor(e.Col-e.X+e.LINES_WIDTH+e.FilesPanelWidth, e.Row-e.Y) // show cursor
+			e.Screen.ShowCursor(e.Col-e.X+e.LINES_WIDTH+e.FilesPanelWidth, e.screenRow(e.Row)) // show cursor
 		}
 	}
 
-	if e.Row-e.Y >= e.ROWS { e.Screen.HideCursor() }
+	if e.screenRow(e.Row) >= e.ROWS && !e.softWrap { e.Screen.HideCursor() }
 
 	e.DrawProcessPanel()
 
@@ -863,6 +907,40 @@ func (e *Editor) DrawEverything() {
 	//e.Update = false
 }
 
+// color of the char at byte offset, with selection and debug line backgrounds
+func (e *Editor) charStyle(row, col, offset int, coloredByteRanges []ColoredByteRange) Style {
+	style := StyleDefault
+
+	//minRange := coloredByteRanges[0]
+
+	for _, i := range coloredByteRanges {
+		if i.StartByte <= offset && offset < i.EndByte {
+			//len := i.EndByte - i.StartByte
+			//if len < minRange.EndByte - minRange.StartByte {
+			//	minRange = i
+			style = StyleDefault.Foreground(Color(i.Color))
+			//}
+			//continue
+			break
+		}
+	}
+
+	if level, found := e.bracketLevels[offset]; found {
+		style = style.Foreground(Color(RainbowColors[level%len(RainbowColors)]))
+	}
+	if offset == e.bracketMatch[0] || offset == e.bracketMatch[1] {
+		style = style.Background(Color(HighlightColor)).Bold(true)
+	}
+
+	if e.isUnderSelection(col, row) || e.IsUnderCursorsSelection(col, row) {
+		style = style.Background(Color(SelectionColor))
+	}
+	if e.DebugInfo.stopline == row {
+		style = style.Background(Color(SelectionColor))
+	}
+	return style
+}
+
 func (e *Editor) CleanProcessPanel() {
 	for j := e.ROWS; j < e.TERMINAL_HEIGHT; j++ {
 		for i := 0; i < e.COLUMNS; i++ {
@@ -971,6 +1049,8 @@ func (e *Editor) DrawDiagnostic() {
 			//	}
 			//}
 
+			if e.softWrap { e.drawWrappedDiagnostic(dline, "error: "+diagnostic.Message, style); continue }
+
 			tabs := CountTabs(e.Content[dline], len(e.Content[dline]))
 			var shifty = 0
 			errorMessage := "error: " + diagnostic.Message
@@ -978,7 +1058,7 @@ func (e *Editor) DrawDiagnostic() {
 
 			// iterate over message characters and draw it
 			for i, m := range errorMessage {
-				ypos := dline - e.Y
+				ypos := e.screenRow(dline)
 				if ypos < 0 || ypos >= len(e.Content) { break }
 
 				tabs = CountTabs(e.Content[dline], len(e.Content[dline]))
@@ -1019,6 +1099,8 @@ func (e *Editor) DrawLineNumber(brw int, row int) {
 	for index, char := range lineNumber {
 		e.Screen.SetContent(index+e.FilesPanelWidth, row, char, nil, style)
 	}
+	e.drawMarkSign(brw, row)
+	e.drawFoldMarker(brw, row)
 }
 
 func (e *Editor) DrawStatus(text string) {
@@ -1036,10 +1118,18 @@ func (e *Editor) DrawText(row, col int, text string, style Style) {
 	}
 }
 
-func (e *Editor) FindCursorXPosition(mx int) int {
+// content line and column under the mouse, mx is counted from the text area start
+func (e *Editor) mousePosition(mx, my int) (int, int) {
+	if e.softWrap { return e.wrapMousePosition(mx, my) }
+	row := e.lineAtRow(my)
+	if row > len(e.Content)-1 { row = len(e.Content) - 1 } // fit cursor to e.Content
+	return row, e.FindCursorXPosition(row, mx)
+}
+
+func (e *Editor) FindCursorXPosition(row, mx int) int {
 	count := 0
 	realCount := 0 // searching x position
-	for _, ch := range e.Content[e.Row] {
+	for _, ch := range e.Content[row] {
 		if count >= mx+e.X { break }
 		if ch == '\t' && e.X == 0 {
 			count += e.langTabWidth; realCount++
@@ -1066,8 +1156,8 @@ func (e *Editor) InitLsp(lang string) {
 	currentDir, _ := os.Getwd()
 
 	lsp.Init(currentDir)
-	code := ConvertContentToString(e.Content)
-	lsp.DidOpen(e.AbsoluteFilePath, &code)
+	// buffers are opened on the server by the main loop, InitLsp runs in its own goroutine
+	if lsp.IsReady { e.Screen.PostEvent(NewEventInterrupt(lspReady{lang})) }
 
 	//e.DrawEverything()
 	//
@@ -1135,7 +1225,7 @@ func (e *Editor) OnErrors() {
 
 			e.Screen.Show()
 
-			switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+			switch ev := e.PollEvent().(type) { // poll and handle event
 			case *EventResize:
 				e.COLUMNS, e.ROWS = e.Screen.Size()
 				//ROWS -= 1
@@ -1157,7 +1247,7 @@ func (e *Editor) OnErrors() {
 				if key == KeyUp { selected = Max(0, selected-1) }
 				if key == KeyCtrlC {
 					diagnostic := maybeDiagnostics.Diagnostics[selected]
-					clipboard.WriteAll(diagnostic.Message)
+					e.copyText(diagnostic.Message)
 				}
 				//if key == tcell.KeyRight { e.OnRight(); e.Screen.Clear(); e.DrawEverything(); selectionEnd = true }
 				if key == KeyRight {
@@ -1262,6 +1352,7 @@ func (e *Editor) OnSearch() {
 
 	var patternx = len(e.SearchPattern)
 	var isChanged = true
+	var historyIndex = len(e.searchHistory) // past the latest pattern is the typed one
 
 	// loop until escape or enter pressed
 	for !end {
@@ -1300,7 +1391,7 @@ func (e *Editor) OnSearch() {
 			isChanged = false
 		}
 
-		switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+		switch ev := e.PollEvent().(type) { // poll and handle event
 		case *EventResize:
 			e.COLUMNS, e.ROWS = e.Screen.Size()
 
@@ -1338,6 +1429,22 @@ func (e *Editor) OnSearch() {
 				e.SearchPattern = []rune{}
 				patternx = 0
 			}
+			if (key == KeyCtrlP || key == KeyCtrlN) && len(e.searchHistory) > 0 { // previous and next searches
+				if key == KeyCtrlP { historyIndex = Max(0, historyIndex-1) }
+				if key == KeyCtrlN { historyIndex = Min(len(e.searchHistory), historyIndex+1) }
+				e.SearchPattern = []rune{}
+				if historyIndex < len(e.searchHistory) { e.SearchPattern = []rune(e.searchHistory[historyIndex]) }
+				patternx = len(e.SearchPattern)
+				isChanged = true
+				e.SearchResults = Search(e.Content, string(e.SearchPattern))
+				e.SearchResultIndex = 0
+			}
+			if key == KeyCtrlA && len(e.SearchResults) > 0 { // cursor on every result
+				e.OnCursorsAtSearchResults(string(e.SearchPattern))
+				end = true
+				e.FocusCenter()
+				e.CleanContentSearch()
+			}
 			if key == KeyCtrlG {
 				end = e.OnGlobalSearch()
 				e.FocusCenter()
@@ -1369,6 +1476,7 @@ func (e *Editor) OnSearch() {
 		}
 	}
 
+	e.searchHistory = session.AddSearch(e.searchHistory, string(e.SearchPattern))
 	e.IsContentSearch = false
 }
 
@@ -1472,7 +1580,7 @@ func (e *Editor) OnGlobalSearch() bool {
 				e.Screen.Show()
 			}
 
-			switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+			switch ev := e.PollEvent().(type) { // poll and handle event
 			case *EventResize:
 				e.COLUMNS, e.ROWS = e.Screen.Size()
 				e.Screen.Sync()
@@ -1489,7 +1597,7 @@ func (e *Editor) OnGlobalSearch() bool {
 					end = true
 					if e.treeSitterHighlighter.GetLangStr() != initialLang {
 						e.treeSitterHighlighter.SetLang(initialLang)
-						e.UpdateColors()
+						e.parseText()
 					}
 
 					return true
@@ -1690,7 +1798,7 @@ func (e *Editor) OnFilesTree(forceOpen bool) {
 		e.DrawTreeSearch(e.FilesSearchPattern, patternx)
 		e.Screen.Show()
 
-		switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+		switch ev := e.PollEvent().(type) { // poll and handle event
 		case *EventMouse:
 			mx, my := ev.Position()
 			buttons := ev.Buttons()
@@ -1727,25 +1835,28 @@ func (e *Editor) OnFilesTree(forceOpen bool) {
 		case *EventKey:
 			key := ev.Key()
 
-			if key == KeyCtrlQ { e.Screen.Fini(); os.Exit(1) }
-			if key == KeyCtrlN { e.NewFileOrDir() }
-			if key == KeyCtrlF { e.IsFilesSearch = !e.IsFilesSearch }
-			if key == KeyEscape && !e.IsFilesSearch { end = true; e.FilesPanelWidth = 0 }
-			if key == KeyEscape && e.IsFilesSearch {
+			command, handled := e.lookupCommand(keymap.Tree, ev)
+			if command == "quit" { e.OnQuit() }
+			if command == "new-file" { e.NewFileOrDir() }
+			if command == "search" { e.IsFilesSearch = !e.IsFilesSearch }
+			if command == "cancel" && !e.IsFilesSearch { end = true; e.FilesPanelWidth = 0 }
+			if command == "cancel" && e.IsFilesSearch {
 				end = true
 				e.IsFilesSearch = false
 				e.CleanFilesSearch()
 				e.Screen.Show()
 			}
-			if key == KeyDown { e.FileSelectedIndex = Min(treeSize-1, e.FileSelectedIndex+1) }
-			if key == KeyUp { e.FileSelectedIndex = Max(0, e.FileSelectedIndex-1) }
-			if key == KeyLeft && e.IsFilesSearch && patternx > 0 { patternx-- }
-			if key == KeyRight && e.IsFilesSearch && patternx < len(e.FilesSearchPattern) { patternx++ }
-			if key == KeyCtrlT {
+			if command == "close" {
 				end = true
 				e.IsFilesSearch = false
 				e.FilesPanelWidth = 0
 			}
+			if handled { continue }
+
+			if key == KeyDown { e.FileSelectedIndex = Min(treeSize-1, e.FileSelectedIndex+1) }
+			if key == KeyUp { e.FileSelectedIndex = Max(0, e.FileSelectedIndex-1) }
+			if key == KeyLeft && e.IsFilesSearch && patternx > 0 { patternx-- }
+			if key == KeyRight && e.IsFilesSearch && patternx < len(e.FilesSearchPattern) { patternx++ }
 			if key == KeyBackspace2 && e.IsFilesSearch && patternx > 0 && len(e.FilesSearchPattern) > 0 {
 				patternx--
 				e.FilesSearchPattern = Remove(e.FilesSearchPattern, patternx)
@@ -1895,15 +2006,14 @@ func (e *Editor) OverlayFalse() {
 }
 
 func (e *Editor) UpdateColors() {
-	code := ConvertContentToString(e.Content)
-	e.treeSitterHighlighter.ReParse(&code) //  todo:: optimize
+	e.syncText()
 }
 
 // todo, get rid of this function, cause UpdateColors is slow for big files
 func (e *Editor) UpdateNeeded() {
 	e.Update = true
 	e.IsContentChanged = true
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 	e.UpdateColors()
 	e.FindTests()
 }
@@ -1912,7 +2022,7 @@ func (e *Editor) OnProcessRun(newRun bool) {
 	if newRun && (e.Lang == "" || e.langConf.Cmd == "") { return }
 
 	if e.ProcessPanelHeight == 0 {
-		e.ProcessPanelHeight = 10
+		e.ProcessPanelHeight = e.openedProcessPanelHeight()
 		e.COLUMNS, e.ROWS = e.Screen.Size()
 		e.ROWS -= e.ProcessPanelHeight
 	}
@@ -1979,9 +2089,10 @@ func (e *Editor) OnProcessRun(newRun bool) {
 
 }
 
-func (e *Editor) OnProcessKeyHandle(key Key, keyrune rune) {
-	if key == KeyCtrlF {
-		e.OnProcessSearch()
+func (e *Editor) OnProcessKeyHandle(key Key, ev *EventKey) {
+	if command, _ := e.lookupCommand(keymap.Process, ev); command != "" {
+		processCommands[command](e)
+		return
 	}
 
 	if key == KeyUp {
@@ -2021,20 +2132,6 @@ func (e *Editor) OnProcessKeyHandle(key Key, keyrune rune) {
 		//	e.ProcessPanelHScroll = e.ProcessPanelCursorX - e.TERMINAL_WIDHT + e.ProcessPanelSpacing
 		//}
 	}
-	if key == KeyRune && keyrune == 'l' {
-		e.ProcessPanelHScroll++
-	}
-	if key == KeyRune && keyrune == 'l' {
-		e.ProcessPanelHScroll++
-	}
-	if key == KeyRune && keyrune == 's' {
-		e.OnProcessStop()
-	}
-	if key == KeyRune && keyrune == 'f' {
-		if len(e.ProcessContent) > e.ProcessPanelHeight { // focusing
-			e.ProcessPanelScroll = len(e.ProcessContent) - e.ProcessPanelHeight + 1
-		}
-	}
 }
 
 func (e *Editor) OnProcessStop() {
@@ -2056,7 +2153,7 @@ func (e *Editor) OnProcessStop() {
 func (e *Editor) OnFileUpdate() {
 	row, col := e.Row, e.Col // save cursor
 	x, y := e.X, e.Y         // safe scroll
-	e.OpenFile(e.AbsoluteFilePath)
+	e.LoadFile()
 
 	// if row and col fits to content, restore cursor
 	if row < len(e.Content) {
@@ -2084,6 +2181,9 @@ func (e *Editor) OnFilesTreeUpdate(event notify.EventInfo) {
 		fileInfo, err := os.Stat(fullname)
 		if err != nil { return }
 		isDir := fileInfo.IsDir()
+		for _, child := range parentNode.Childs {
+			if child.FullName == fullname { return } // file saved by rename over it
+		}
 
 		f := FileInfo{
 			Name: name, FullName: fullname,
@@ -2094,8 +2194,9 @@ func (e *Editor) OnFilesTreeUpdate(event notify.EventInfo) {
 		parentNode.Childs = append(parentNode.Childs, f)
 		SortTree(*parentNode)
 
-	case notify.Remove:
-		// find and remove node from parent
+	case notify.Remove, notify.Rename:
+		// find and remove node from parent, renamed file is created under the new name
+		if _, err := os.Stat(fullname); err == nil { return }
 		for i, child := range parentNode.Childs {
 			if child.FullName == fullname {
 				parentNode.Childs = Remove(parentNode.Childs, i)
@@ -2197,7 +2298,7 @@ func (e *Editor) NewFileOrDir() {
 
 		e.Screen.Show()
 
-		switch ev := e.Screen.PollEvent().(type) {
+		switch ev := e.PollEvent().(type) {
 		case *EventKey:
 			key := ev.Key()
 
@@ -2246,6 +2347,7 @@ func (e *Editor) OnCursorChanged() {
 	clear(e.HighlightElements)
 
 	if e.Selection.IsSelectionNonEmpty() { return }
+	if len(e.Content) > e.Config.LargeFile.IdentifierLines { return } // searching all identifiers is too slow
 
 	start := time.Now()
 	nodename, noderange := e.treeSitterHighlighter.GetNodeAt(e.Row, e.Col, e.Row, e.Col)
@@ -2324,7 +2426,7 @@ func (e *Editor) OnProcessSearch() {
 			isChanged = false
 		}
 
-		switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+		switch ev := e.PollEvent().(type) { // poll and handle event
 		case *EventResize:
 			e.COLUMNS, e.ROWS = e.Screen.Size()
 
@@ -2332,7 +2434,7 @@ func (e *Editor) OnProcessSearch() {
 			isChanged = false
 			key := ev.Key()
 
-			if key == KeyCtrlQ { e.Screen.Fini(); os.Exit(1) }
+			if key == KeyCtrlQ { e.OnQuit() }
 
 			if key == KeyRune {
 				e.ProcessPanelSearchPattern = InsertTo(e.ProcessPanelSearchPattern, patternx, ev.Rune())
@@ -2415,7 +2517,7 @@ func (e *Editor) GoToLine() {
 	var end = false
 	for !end {
 
-		switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+		switch ev := e.PollEvent().(type) { // poll and handle event
 
 		case *EventKey:
 			key := ev.Key()
@@ -2501,17 +2603,14 @@ func (e *Editor) OnLangLinesCount() {
 		}
 		e.Screen.Show()
 
-		switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+		switch ev := e.PollEvent().(type) { // poll and handle event
 		case *EventResize:
 			e.COLUMNS, e.ROWS = e.Screen.Size()
 
 		case *EventKey:
 			key := ev.Key()
 
-			if key == KeyCtrlQ {
-				e.Screen.Fini()
-				os.Exit(1)
-			}
+			if key == KeyCtrlQ { e.OnQuit() }
 
 			if key == KeyESC || key == KeyEnter {
 				end = true
@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"edgo/internal/editorconfig"
+	"edgo/internal/fileformat"
+	. "edgo/internal/operations"
+	"strconv"
+)
+
+// .editorconfig properties of the file override language config, see applyEditorConfig
+
+func (e *Editor) applyEditorConfig() {
+	e.editorConfig = editorconfig.Resolve(e.AbsoluteFilePath)
+	e.indentSpaces = 0
+
+	size, _ := strconv.Atoi(e.editorConfig["indent_size"])
+	width, _ := strconv.Atoi(e.editorConfig["tab_width"])
+	if width <= 0 { width = size } // tab width defaults to indent size
+	if width > 0 { e.langTabWidth = width }
+
+	if e.editorConfig["indent_style"] == "space" {
+		if size <= 0 { size = e.langTabWidth } // indent_size = tab or not set
+		e.indentSpaces = size
+	}
+}
+
+// file format on save, it converts the file if it differs from the read one and content can be encoded
+func (e *Editor) configuredFormat(format fileformat.Format) fileformat.Format {
+	configured := format
+	switch e.editorConfig["end_of_line"] {
+	case "lf": configured.LineEnding = fileformat.LF
+	case "crlf": configured.LineEnding = fileformat.CRLF
+	}
+	if format.Mixed { configured.LineEnding = format.LineEnding } // converted only by toggle-line-ending
+	switch e.editorConfig["charset"] {
+	case "utf-8": configured.Encoding, configured.BOM = fileformat.UTF8, false
+	case "utf-8-bom": configured.Encoding, configured.BOM = fileformat.UTF8, true
+	case "latin1": configured.Encoding, configured.BOM = fileformat.Latin1, false
+	case "utf-16le": configured.Encoding, configured.BOM = fileformat.UTF16LE, true
+	case "utf-16be": configured.Encoding, configured.BOM = fileformat.UTF16BE, true
+	}
+	switch e.editorConfig["insert_final_newline"] {
+	case "true": configured.FinalNewline = true
+	case "false": configured.FinalNewline = false
+	}
+
+	if configured == format { return format }
+	if _, err := fileformat.Encode(e.Content, configured, nil); err != nil { return format }
+	return configured
+}
+
+// one level of indentation, tab or spaces
+func (e *Editor) indentUnit() []rune {
+	if e.indentSpaces == 0 { return []rune{'\t'} }
+	unit := make([]rune, e.indentSpaces)
+	for i := range unit { unit[i] = ' ' }
+	return unit
+}
+
+// removes whitespace at the ends of lines on explicit save, cursors and selections are moved along
+func (e *Editor) trimTrailingWhitespace() {
+	if !e.IsFullyLoaded || e.editorConfig["trim_trailing_whitespace"] != "true" { return }
+
+	e.cutLineEnds(func(line []rune) int {
+		end := len(line)
+		for end > 0 && (line[end-1] == ' ' || line[end-1] == '\t') { end-- }
+		return end
+	})
+}
+
+// deletes the tail of every line after end, as one undoable edit with cursors shifted.
+// Lines are cut in one pass, text and language server are synced once
+func (e *Editor) cutLineEnds(end func(line []rune) int) {
+	ops := EditOperation{}
+	for row, line := range e.Content {
+		end := end(line)
+		for i := len(line) - 1; i >= end; i-- { ops = append(ops, Operation{Delete, line[i], row, i}) }
+		e.Content[row] = line[:end]
+	}
+	if len(ops) == 0 { return }
+
+	e.syncText()
+	e.UndoTree.Push(ops)
+	cursors := append([]Cursor{{e.Row, e.Col, e.Selection}}, e.Cursors...)
+	for _, op := range ops {
+		for i := range cursors { cursors[i] = cursors[i].shift(op) }
+	}
+	e.Row, e.Col, e.Selection = cursors[0].Row, cursors[0].Col, cursors[0].Selection
+	e.Cursors = cursors[1:]
+	e.FindTests()
+	e.Update = true
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"edgo/internal/fileformat"
+	. "edgo/internal/logger"
+	"slices"
+	"strings"
+)
+
+// file format conversions, the buffer is written in the new format on the next save
+
+func (e *Editor) OnSetEncoding() {
+	input, ok := e.inputPrompt(" encoding (" + strings.Join(fileformat.Encodings, ", ") + "): ")
+	if !ok || !slices.Contains(fileformat.Encodings, input) { return }
+
+	format := e.Format
+	format.Encoding = input
+	if input == fileformat.Latin1 { format.BOM = false } // latin-1 has no bom
+	e.setFormat(format)
+}
+
+// mixed line endings become lf
+func (e *Editor) OnToggleLineEnding() {
+	format := e.Format
+	if format.Mixed {
+		format.Mixed, format.LineEnding = false, fileformat.LF
+	} else if format.LineEnding == fileformat.CRLF { format.LineEnding = fileformat.LF } else { format.LineEnding = fileformat.CRLF }
+	e.setFormat(format)
+}
+
+func (e *Editor) OnToggleBOM() {
+	if e.Format.Encoding == fileformat.Latin1 { return }
+	format := e.Format
+	format.BOM = !format.BOM
+	e.setFormat(format)
+}
+
+func (e *Editor) OnToggleFinalNewline() {
+	format := e.Format
+	format.FinalNewline = !format.FinalNewline
+	e.setFormat(format)
+}
+
+// content has to be encodable in the new format, otherwise it could not be saved
+func (e *Editor) setFormat(format fileformat.Format) {
+	if format == e.Format { return }
+	if _, err := fileformat.Encode(e.Content, format, nil); err != nil {
+		Log.Error("can not convert to", format.String(), err.Error())
+		return
+	}
+	e.Format = format
+	e.IsContentChanged = true
+	e.AutoSave()
+}
@@ -0,0 +1,282 @@
+package ui
+
+import (
+	. "edgo/internal/highlighter"
+	. "edgo/internal/selection"
+	. "edgo/internal/utils"
+	"edgo/internal/text"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	Folding hides lines of a region after its first line.
+	Regions come from the tree-sitter tree, or from indentation for languages without grammar and large files.
+	Closed folds are kept by lines, line edits shift them and open those they touch,
+	other changes of the line count open everything. A fold hiding the cursor is opened on draw,
+	so search, go to definition and other jumps reveal the place they land at.
+*/
+
+type fold struct { start, end int }
+
+type foldVersion struct {
+	text        *text.PieceTable
+	node, nodes int
+	lines       int
+}
+
+// foldable regions of the buffer ordered by the first line, computed once per change
+func (e *Editor) foldRegions() []fold {
+	version := foldVersion{e.Text, e.UndoTree.Current, len(e.UndoTree.Nodes), len(e.Content)}
+	if e.foldCache != nil && e.foldCacheVersion == version { return e.foldCache }
+
+	regions := []fold{}
+	if !e.NoHighlight && e.treeSitterHighlighter != nil && e.treeSitterHighlighter.HasGrammar() {
+		for _, r := range e.treeSitterHighlighter.FoldRanges() {
+			if r.Sey < len(e.Content) { regions = append(regions, fold{r.Ssy, r.Sey}) }
+		}
+	} else {
+		regions = e.indentRegions()
+	}
+	e.foldCache, e.foldCacheVersion = regions, version
+	return regions
+}
+
+// line starts a region if the next non blank lines are indented deeper
+func (e *Editor) indentRegions() []fold {
+	type open struct { line, indent int }
+	regions, stack, last := []fold{}, []open{}, -1
+
+	closeTo := func(indent int) {
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if last > top.line { regions = append(regions, fold{top.line, last}) }
+		}
+	}
+
+	for i, line := range e.Content {
+		if strings.TrimSpace(string(line)) == "" { continue }
+		indent := 0
+		for _, ch := range line {
+			if ch != ' ' && ch != '\t' { break }
+			indent += e.runeWidth(ch)
+		}
+		closeTo(indent)
+		stack = append(stack, open{i, indent})
+		last = i
+	}
+	closeTo(0)
+
+	sort.Slice(regions, func(i, j int) bool { return regions[i].start < regions[j].start })
+	return regions
+}
+
+func (e *Editor) regionAt(line int) (fold, bool) {
+	for _, r := range e.foldRegions() {
+		if r.start == line { return r, true }
+		if r.start > line { break }
+	}
+	return fold{}, false
+}
+
+// closed fold starting at the line
+func (e *Editor) closedFold(line int) (fold, bool) {
+	for _, f := range e.folds {
+		if f.start == line { return f, true }
+	}
+	return fold{}, false
+}
+
+// the outermost closed fold hiding the line
+func (e *Editor) hidingFold(line int) (fold, bool) {
+	for _, f := range e.folds {
+		if f.start < line && line <= f.end { return f, true }
+	}
+	return fold{}, false
+}
+
+func (e *Editor) closeFold(region fold) {
+	if _, found := e.closedFold(region.start); found { return }
+	if len(e.folds) == 0 { e.foldLines = len(e.Content) }
+	e.folds = append(e.folds, region)
+	sort.Slice(e.folds, func(i, j int) bool { return e.folds[i].start < e.folds[j].start })
+}
+
+// opens folds hiding the line, and the one starting at it if start is set
+func (e *Editor) openFolds(line int, start bool) {
+	folds := e.folds[:0]
+	for _, f := range e.folds {
+		if f.start < line && line <= f.end || start && f.start == line { continue }
+		folds = append(folds, f)
+	}
+	e.folds = folds
+}
+
+// keeps the cursor out of folded lines
+func (e *Editor) cursorToFold() {
+	if f, hidden := e.hidingFold(e.Row); hidden {
+		e.Row, e.Col = f.start, Min(e.Col, len(e.Content[f.start]))
+	}
+	if f, hidden := e.hidingFold(e.Y); hidden { e.Y = f.start }
+	e.Update = true
+}
+
+func (e *Editor) nextVisibleLine(line int) int {
+	line++
+	if f, hidden := e.hidingFold(line); hidden { line = f.end + 1 }
+	return line
+}
+
+func (e *Editor) prevVisibleLine(line int) int {
+	line--
+	if f, hidden := e.hidingFold(line); hidden { line = f.start }
+	return line
+}
+
+// screen row of the line when the text is not wrapped, negative if it is above the screen or folded
+func (e *Editor) screenRow(line int) int {
+	if line < e.Y { return line - e.Y }
+	row, covered := line-e.Y, -1
+	for _, f := range e.folds {
+		if f.end <= covered { continue } // nested
+		covered = f.end
+		if f.start < line && line <= f.end { return -1 }
+		from, to := Max(f.start+1, e.Y), Min(f.end, line-1)
+		if to >= from { row -= to - from + 1 }
+	}
+	return row
+}
+
+// line shown at the screen row when the text is not wrapped
+func (e *Editor) lineAtRow(row int) int {
+	if len(e.folds) == 0 { return row + e.Y }
+	line := e.Y
+	for i := 0; i < row && line < len(e.Content); i++ { line = e.nextVisibleLine(line) }
+	return line
+}
+
+// the line count visible lines above the line
+func (e *Editor) linesAbove(line, count int) int {
+	for ; count > 0 && line > 0; count-- { line = e.prevVisibleLine(line) }
+	return line
+}
+
+// mirrors lines inserted after the row, or removed after it if count is negative
+func (e *Editor) shiftFolds(row, count int) {
+	if len(e.folds) == 0 || count == 0 { return }
+	last := row
+	if count < 0 { last = row - count }
+
+	folds := e.folds[:0]
+	for _, f := range e.folds {
+		if f.start > last {
+			folds = append(folds, fold{f.start + count, f.end + count})
+		} else if f.end < row {
+			folds = append(folds, f)
+		} // touched folds are opened
+	}
+	e.folds = folds
+	e.foldLines += count
+}
+
+// folds are dropped if lines changed not by tracked edits, the cursor reveals lines it lands at
+func (e *Editor) fitFolds() {
+	if len(e.folds) == 0 { return }
+	if e.foldLines != len(e.Content) { e.folds = nil; return }
+	e.openFolds(e.Row, false)
+	if f, hidden := e.hidingFold(e.Y); hidden { e.Y = f.start }
+
+	if e.softWrap || e.foldCursor == [2]int{e.Row, e.Col} { return } // wrapped view fits itself
+	e.foldCursor = [2]int{e.Row, e.Col}
+	if e.Row < e.Y { e.Y = e.Row }
+	if e.screenRow(e.Row) >= e.ROWS { e.Y = e.linesAbove(e.Row, e.ROWS-1) }
+}
+
+// toggles fold starting at the line, false if there is no region
+func (e *Editor) toggleFold(line int) bool {
+	if _, closed := e.closedFold(line); closed { e.openFolds(line, true); e.Update = true; return true }
+	region, found := e.regionAt(line)
+	if !found { return false }
+	e.closeFold(region)
+	e.cursorToFold()
+	return true
+}
+
+// folds the innermost region with the cursor, unfolds if it is on a folded line
+func (e *Editor) OnFold() {
+	if len(e.Content) == 0 || e.toggleFold(e.Row) { return }
+	inner, found := fold{}, false
+	for _, r := range e.foldRegions() {
+		if r.start > e.Row { break }
+		if r.end >= e.Row { inner, found = r, true }
+	}
+	if !found { return }
+	e.closeFold(inner)
+	e.cursorToFold()
+}
+
+func (e *Editor) OnUnfold() {
+	e.openFolds(e.Row, true)
+	e.Update = true
+}
+
+func (e *Editor) OnFoldAll() {
+	e.folds, e.foldLines = append([]fold{}, e.foldRegions()...), len(e.Content)
+	e.cursorToFold()
+}
+
+func (e *Editor) OnUnfoldAll() {
+	e.folds = nil
+	e.Update = true
+}
+
+// closes regions nested deeper than the level, 0 folds everything
+func (e *Editor) OnFoldLevel() {
+	input, ok := e.inputPrompt(" fold level: ")
+	if !ok { return }
+	level, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || level < 0 { return }
+
+	folds, enclosing := []fold{}, []fold{}
+	for _, r := range e.foldRegions() {
+		for len(enclosing) > 0 && enclosing[len(enclosing)-1].end < r.start { enclosing = enclosing[:len(enclosing)-1] }
+		if len(enclosing) >= level { folds = append(folds, r) }
+		enclosing = append(enclosing, r)
+	}
+	e.folds, e.foldLines = folds, len(e.Content)
+	e.cursorToFold()
+}
+
+// gutter marker of a region start, drawn over the right padding of the line number
+func (e *Editor) drawFoldMarker(line, row int) {
+	x := e.FilesPanelWidth + e.LINES_WIDTH - 1
+	if _, closed := e.closedFold(line); closed {
+		e.Screen.SetContent(x, row, '▸', nil, StyleDefault.Foreground(Color(AccentColor)))
+	} else if _, found := e.regionAt(line); found {
+		e.Screen.SetContent(x, row, '▾', nil, StyleDefault.Foreground(239))
+	}
+}
+
+// count of hidden lines after the end of folded line, x is the screen column of the line end
+func (e *Editor) drawFoldTail(line, x, row int) {
+	f, closed := e.closedFold(line)
+	if !closed { return }
+	tail := fmt.Sprintf(" ⋯ %d lines", f.end-f.start)
+	if f.end-f.start == 1 { tail = " ⋯ 1 line" }
+	for _, ch := range tail {
+		if x >= e.COLUMNS { break }
+		e.Screen.SetContent(x, row, ch, nil, StyleDefault.Foreground(239))
+		x++
+	}
+}
+
+// screen column after the line when the text is not wrapped
+func (e *Editor) lineEndX(line int) int {
+	width := len(e.Content[line])
+	if e.X == 0 { width = VisualColumn(e.Content[line], width, e.langTabWidth) }
+	return e.LINES_WIDTH + e.FilesPanelWidth + width - e.X
+}
@@ -0,0 +1,35 @@
+package ui
+
+import (
+	. "edgo/internal/config"
+	"edgo/internal/history"
+	. "edgo/internal/logger"
+)
+
+// undo tree is kept between sessions in state dir, per project and file
+
+func (e *Editor) historyDir() string {
+	return history.ProjectDir(StateDir(), e.Cwd)
+}
+
+// saves history of the active buffer, see saveHistoryOf
+func (e *Editor) saveHistory() {
+	e.storeBuffer()
+	if e.BufferIndex >= 0 && e.BufferIndex < len(e.Buffers) { e.saveHistoryOf(e.Buffers[e.BufferIndex]) }
+}
+
+// history is saved for the content written to disk on explicit save, buffer close and quit,
+// hashing content and serializing the tree on every autosave would slow typing down
+func (e *Editor) saveHistoryOf(b *Buffer) {
+	if b.AbsoluteFilePath == "" || !b.IsFullyLoaded || b.IsContentChanged { return } // not on disk, nothing to restore it for
+	h := history.History{Hash: history.Hash(b.Content), Tree: b.UndoTree}
+	if err := history.Save(e.historyDir(), b.AbsoluteFilePath, h); err != nil { Log.Error("history save", err.Error()) }
+}
+
+// restores history saved for the same content, history of file changed outside is dropped
+func (e *Editor) restoreHistory() {
+	if e.AbsoluteFilePath == "" || !e.IsFullyLoaded { return }
+	h, found := history.Load(e.historyDir(), e.AbsoluteFilePath, history.Hash(e.Content))
+	if !found { return }
+	e.UndoTree = h.Tree
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	. "edgo/internal/operations"
+	"slices"
+	"strings"
+)
+
+/*
+	New lines, typed closing brackets and the reindent command take indentation from tree-sitter
+	indent queries (langs/*.go Indents). Languages without them and large files copy indentation
+	of the previous line. Whitespace is edited through insertText/deleteText, so the tree is current
+	for the next line of a reindented range.
+*/
+
+// lines of the last paste, reindent takes them while the paste is the last edit
+type pasteRange struct { node, from, to int }
+
+// indentation of the row by the syntax tree, false if the language has no indent query
+func (e *Editor) smartIndent(row int) ([]rune, bool) {
+	if e.NoHighlight || e.treeSitterHighlighter == nil { return nil, false }
+	anchor, deeper, ok := e.treeSitterHighlighter.Indent(e.Content, row)
+	if !ok { return nil, false }
+
+	indent := []rune{}
+	if anchor >= 0 { indent = append(indent, leadingWhitespace(e.Content[anchor])...) }
+	if !deeper { return indent, true }
+
+	unit := e.indentUnit()
+	if e.indentSpaces == 0 && len(indent) > 0 && indent[0] == ' ' { unit = []rune(strings.Repeat(" ", e.langTabWidth)) } // file is indented by spaces
+	return append(indent, unit...), true
+}
+
+func leadingWhitespace(line []rune) []rune {
+	count := 0
+	for count < len(line) && (line[count] == ' ' || line[count] == '\t') { count++ }
+	return line[:count]
+}
+
+// line is split between opening and closing brackets
+func isBracketPair(before, after []rune) bool {
+	open := strings.TrimRight(string(before), " \t")
+	close := strings.TrimLeft(string(after), " \t")
+	if open == "" || close == "" { return false }
+	pairs := map[byte]byte{'{': '}', '(': ')', '[': ']'}
+	return pairs[open[len(open)-1]] == close[0] && close[0] != 0
+}
+
+// replaces indentation of the row, returns change of the line length
+func (e *Editor) setIndent(row int, indent []rune, ops *EditOperation) int {
+	old := leadingWhitespace(e.Content[row])
+	if string(old) == string(indent) { return 0 }
+
+	for i := len(old) - 1; i >= 0; i-- { *ops = append(*ops, Operation{Delete, old[i], row, i}) }
+	for i, ch := range indent { *ops = append(*ops, Operation{Insert, ch, row, i}) }
+	e.deleteText(row, 0, string(old))
+	e.insertText(row, 0, string(indent))
+	e.Content[row] = append(append([]rune{}, indent...), e.Content[row][len(old):]...)
+	return len(indent) - len(old)
+}
+
+// indents a new line by the syntax tree or as fallback, returns length of the indentation
+func (e *Editor) indentNewLine(row int, fallback []rune, ops *EditOperation) int {
+	indent, ok := e.smartIndent(row)
+	if !ok { indent = fallback }
+	e.setIndent(row, indent, ops)
+	return len(indent)
+}
+
+// closing bracket typed at the beginning of the line takes the indentation of its opening line
+func (e *Editor) reindentOnClose(ch rune) {
+	if ch != '}' && ch != ')' && ch != ']' { return }
+	if strings.TrimSpace(string(e.Content[e.Row][:e.Col-1])) != "" { return }
+	indent, ok := e.smartIndent(e.Row)
+	if !ok { return }
+
+	ops := EditOperation{}
+	e.Col += e.setIndent(e.Row, indent, &ops)
+	if len(ops) > 0 { e.UndoTree.Push(ops) }
+}
+
+// reindents selected lines, or the last pasted ones, or the cursor line
+func (e *Editor) OnReindent() {
+	from, to := e.Row, e.Row
+	if lines := e.Selection.GetSelectedLines(e.Content); len(lines) > 0 {
+		from, to = slices.Min(lines), slices.Max(lines)
+	} else if e.pasted.node == e.UndoTree.Current && e.pasted.node > 0 {
+		from, to = e.pasted.from, e.pasted.to
+	}
+	if _, ok := e.smartIndent(from); !ok { return } // no indent rules
+
+	ops := EditOperation{}
+	for row := from; row <= to && row < len(e.Content); row++ {
+		if strings.TrimSpace(string(e.Content[row])) == "" { continue }
+		indent, _ := e.smartIndent(row)
+		delta := e.setIndent(row, indent, &ops)
+		if row == e.Row { e.Col = max(e.Col+delta, len(indent)) }
+	}
+	if len(ops) == 0 { return }
+
+	if e.Col > len(e.Content[e.Row]) { e.Col = len(e.Content[e.Row]) }
+	e.UndoTree.Push(ops)
+	e.Update = true
+	e.IsContentChanged = true
+	e.FindTests()
+	e.AutoSave()
+}
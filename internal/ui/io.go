@@ -2,110 +2,243 @@ package ui
 
 import (
 	"bufio"
+	. "edgo/internal/config"
+	"edgo/internal/fileformat"
+	. "edgo/internal/io"
+	. "edgo/internal/logger"
 	. "edgo/internal/utils"
 	"fmt"
+	. "github.com/gdamore/tcell"
+	"io"
 	"os"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
-func (e *Editor) ReadFile(fileToRead string) string {
-	/// if file is big, read only first 1000 lines and read rest async
-	fileSize := GetFileSize(fileToRead)
-	fileSizeMB := fileSize / (1024 * 1024) // Convert size to megabytes
+const loadChunkLines = 1000 // lines read before showing a large file, and per background chunk
+const formatHeadSize = 4096 // bytes to detect file encoding
+
+// fileLoader reads the rest of a large file in background,
+// read lines are moved to the buffer by the main loop, see applyLoadedLines
+type fileLoader struct {
+	mu    sync.Mutex
+	lines  [][]rune // read, but not applied yet
+	done   bool
+	err    error
+	format fileformat.Format // complete when done
+	endings []bool           // of every line, when done
+	finished chan struct{}   // closed when done
+}
 
-	var code string
-	if fileSizeMB >= 1 {
-		//colorize = false
-		code = e.BuildContent(fileToRead, 1000)
+func (e *Editor) ReadFile(fileToRead string) {
+	e.IsFullyLoaded = true
+	e.loader = nil
+	e.lineEndings = nil
+	e.Format = e.configuredFormat(fileformat.Default)
 
-		go func() { // sync?? no need yet
-			code = e.BuildContent(fileToRead, 1000000)
-			code, _ = GetFirstLines(code, 20000)
-			e.DrawEverything()
-			e.Screen.Show()
-		}()
+	file, err := os.Open(fileToRead)
+	if err != nil {
+		if os.IsNotExist(err) {
+			filec, err2 := os.Create(fileToRead)
+			if err2 != nil { fmt.Printf("Failed to create file: %v\n", err2) } else { filec.Close() }
+		} else {
+			e.IsFullyLoaded = false; Log.Error("failed to open", fileToRead, err.Error())
+		}
+		e.Content = make([][]rune, 1)
+		e.resetText()
+		return
+	}
+
+	// content is decoded to utf-8, format is kept to write it back the same way
+	raw := bufio.NewReader(file)
+	head, _ := raw.Peek(formatHeadSize)
+	decoder := fileformat.NewReader(raw, fileformat.Detect(head, len(head) == formatHeadSize))
+	reader := bufio.NewReader(decoder)
+	fileSize := GetFileSize(fileToRead)
 
+	if fileSize < e.Config.LargeFile.Size {
+		defer file.Close()
+		e.Content, _, err = readLines(reader, -1)
+		if err != nil { e.IsFullyLoaded = false; Log.Error("failed to read", fileToRead, err.Error()) }
+		e.setReadFormat(decoder.Format(), decoder.Endings())
 	} else {
-		code = e.BuildContent(fileToRead, 1000000)
+		// show first lines right away, read the rest in background
+		var done bool
+		e.Content, done, err = readLines(reader, loadChunkLines)
+		if err != nil { e.IsFullyLoaded = false; Log.Error("failed to read", fileToRead, err.Error()) }
+
+		if done || err != nil { file.Close(); e.setReadFormat(decoder.Format(), decoder.Endings()) } else {
+			e.IsFullyLoaded = false
+			e.loader = &fileLoader{finished: make(chan struct{})}
+			go e.loader.load(file, reader, decoder, func() { e.Screen.PostEvent(NewEventInterrupt(nil)) })
+		}
 	}
-	return code
+
+	// if no e.Content, consider it like one Line for next editing
+	if len(e.Content) == 0 { e.Content = make([][]rune, 1) }
+	e.resetText()
 }
 
-func (e *Editor) WriteFile() {
-	//too much cpu usage for big files
-	//added, removed := Diff(e.LastCommitFileContent, ConvertContentToString(e.Content))
-	//e.Added = added
-	//e.Removed = removed
+func (l *fileLoader) load(file *os.File, reader *bufio.Reader, decoder *fileformat.Reader, notify func()) {
+	defer file.Close()
+	for {
+		lines, done, err := readLines(reader, loadChunkLines * 100)
 
-	// Create a new file, or open it if it exists
-	f, err := os.Create(e.AbsoluteFilePath)
-	if err != nil { panic(err) }
+		l.mu.Lock()
+		l.lines = append(l.lines, lines...)
+		l.done = done || err != nil
+		l.err = err
+		if l.done { l.format, l.endings = decoder.Format(), decoder.Endings(); close(l.finished) }
+		l.mu.Unlock()
 
-	// Create a buffered writer from the file
-	w := bufio.NewWriter(f)
+		notify()
+		if done || err != nil { return }
+	}
+}
 
-	for _, row := range e.Content {
-		for j := 0; j < len(row); {
-			if _, err := w.WriteRune(row[j]); err != nil { panic(err) }
-			j++
-		}
+// moves lines read in background to the active buffer, called from the main loop
+func (e *Editor) applyLoadedLines() {
+	if e.loader == nil { return }
 
-		//if i != len(e.Content) - 1 { // do not write \n at the end
-			if _, err := w.WriteRune('\n'); err != nil { panic(err) }
-		//}
+	e.loader.mu.Lock()
+	lines, done, err, format, endings := e.loader.lines, e.loader.done, e.loader.err, e.loader.format, e.loader.endings
+	e.loader.lines = nil
+	e.loader.mu.Unlock()
 
+	if len(lines) > 0 {
+		chunk := []byte{}
+		for _, line := range lines { chunk = append(append(chunk, '\n'), string(line)...) }
+		e.Text.Insert(e.Text.Len(), chunk)
+		e.Content = append(e.Content, lines...)
+		e.Update = true
 	}
 
-	// Don't forget to flush the buffered writer to ensure all data is written
-	if err := w.Flush(); err != nil { panic(err) }
-	if err := f.Close(); err != nil { panic(err) }
-
-	e.IsContentChanged = false
-	e.FileWatcher.UpdateStats()
+	if !done { return }
+	e.loader = nil
+	if err != nil { Log.Error("failed to read", e.AbsoluteFilePath, err.Error()); return } // never written back
 
-	if e.Lang != "" {
-		lsp := e.lsp2lang[e.Lang]
-		if lsp.IsReady {
-			code := ConvertContentToString(e.Content)
-			go lsp.DidOpen(e.AbsoluteFilePath, &code) // todo remove it in future
-			//go lsp.didChange(AbsoluteFilePath)
-			//go lsp.didSave(AbsoluteFilePath)
-		}
+	e.IsFullyLoaded = true
+	e.setReadFormat(format, endings) // lines edited while loading are matched as changed on write
+	if len(e.UndoTree.Nodes) == 1 { e.restoreHistory() } // not edited while loading
+	e.NoHighlight = len(e.Content) > e.Config.LargeFile.HighlightLines
+	e.parseText()
+	e.FindTests()
+	e.lspOpen(e.Lang, e.AbsoluteFilePath, e.Text, true) // edits made while loading are in the text
+}
 
-	}
+// waits for the rest of a large file, for edits that need the whole content
+func (e *Editor) finishLoading() {
+	if e.loader == nil { return }
+	<-e.loader.finished
+	e.applyLoadedLines()
 }
 
-func (e *Editor) BuildContent(filename string, limit int) string {
-	//Start := time.Now()
-	//Log.info("read file Start", Name, string(limit))
-	//defer Log.info("read file end",   Name, string(limit), "elapsed", time.Since(Start).String())
+// reads up to limit lines (all if limit < 0), done is true when the end of file is reached
+func readLines(reader *bufio.Reader, limit int) ([][]rune, bool, error) {
+	lines := [][]rune{}
+	for limit < 0 || len(lines) < limit {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF { return lines, false, err }
+		if err == io.EOF && len(line) == 0 { return lines, true, nil }
 
-	file, err := os.Open(filename)
-	if err != nil {
-		filec, err2 := os.Create(filename)
-		if err2 != nil {fmt.Printf("Failed to create file: %v\n", err2)}
-		defer filec.Close()
+		if strings.HasSuffix(line, "\r\n") { line = line[:len(line)-2] } else { line = strings.TrimSuffix(line, "\n") }
+		lines = append(lines, []rune(line))
+		if err == io.EOF { return lines, true, nil }
 	}
+
+	_, err := reader.Peek(1)
+	if err == io.EOF { return lines, true, nil }
+	return lines, false, nil
+}
+
+// reads the whole file decoded as the buffer shows it, lines are joined by \n
+func readText(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil { return "", err }
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
+	raw := bufio.NewReader(file)
+	head, _ := raw.Peek(formatHeadSize)
+	decoder := fileformat.NewReader(raw, fileformat.Detect(head, len(head) == formatHeadSize))
+	lines, _, err := readLines(bufio.NewReader(decoder), -1)
+	if err != nil { return "", err }
+	return ConvertContentToString(lines), nil
+}
 
-	e.Content = make([][]rune, 0)
+// format of the read file, lines of a file with mixed endings keep their endings on write
+func (e *Editor) setReadFormat(format fileformat.Format, endings []bool) {
+	e.Format = e.configuredFormat(format)
+	e.lineEndings = nil
+	if e.Format.Mixed { e.lineEndings = fileformat.NewEndings(e.Content, endings) }
+}
 
-	for scanner.Scan() {
-		var line = scanner.Text()
-		var lineChars = []rune{}
-		for _, char := range line { lineChars = append(lineChars, char) }
-		e.Content = append(e.Content, lineChars)
-		if len(e.Content) > limit { break }
+// saves after an edit as configured, big files are saved only explicitly
+func (e *Editor) AutoSave() {
+	if e.isMultiEdit || e.isLargeToAutoSave() { return }
+	file := e.AbsoluteFilePath
+	switch e.Config.Save.AutoSave {
+	case AutoSaveEdit: e.requestSave(file)
+	case AutoSaveIdle:
+		if e.saveTimer != nil { e.saveTimer.Stop() }
+		e.saveTimer = time.AfterFunc(time.Duration(e.Config.Save.IdleMs)*time.Millisecond, func() { e.requestSave(file) })
 	}
+}
 
-	// if no e.Content, consider it like one Line for next editing
-	if e.Content == nil || len(e.Content) == 0 {
-		e.Content = make([][]rune, 1)
+// interrupt event data, autosave is done by the main loop, so it never runs along with edits or another save
+type saveTick struct{ file string }
+
+func (e *Editor) requestSave(file string) { e.Screen.PostEvent(NewEventInterrupt(saveTick{file})) }
+
+func (e *Editor) onSaveTick(tick saveTick) {
+	if tick.file == e.AbsoluteFilePath && e.IsContentChanged { e.WriteFile() }
+}
+
+// saves the active buffer before leaving it, unless autosave is off
+func (e *Editor) autoSaveOnLeave() {
+	if e.Config.Save.AutoSave == "" || e.Config.Save.AutoSave == AutoSaveOff { return }
+	if !e.IsContentChanged || e.isLargeToAutoSave() { return }
+	e.WriteFile()
+}
+
+func (e *Editor) isLargeToAutoSave() bool {
+	return len(e.Content) > e.Config.LargeFile.AutoSaveLines
+}
+
+func (e *Editor) OnSave() {
+	e.trimTrailingWhitespace()
+	e.WriteFile()
+	e.saveHistory()
+}
+
+func (e *Editor) WriteFile() {
+	if !e.IsFullyLoaded { return } // writing partially read file would truncate it
+
+	//too much cpu usage for big files
+	//added, removed := Diff(e.LastCommitFileContent, ConvertContentToString(e.Content))
+	//e.Added = added
+	//e.Removed = removed
+
+	var crlf []bool
+	if e.Format.Mixed && e.lineEndings != nil { crlf = e.lineEndings.Match(e.Content, e.Format.LineEnding) }
+	data, err := fileformat.Encode(e.Content, e.Format, crlf)
+	if err != nil { Log.Error("failed to encode", e.AbsoluteFilePath, err.Error()); return }
+
+	// temp file is renamed over the target, a crash never leaves it half written
+	if err := SaveFile(e.AbsoluteFilePath, data, e.Config.Save.Backup); err != nil {
+		Log.Error("failed to write", e.AbsoluteFilePath, err.Error())
+		return
 	}
 
-	return ConvertContentToString(e.Content)
+	e.IsContentChanged = false
+	if crlf != nil { e.lineEndings = fileformat.NewEndings(e.Content, crlf) } // the next save matches to the written lines
+	e.FileWatcher.UpdateStats()
+	e.saveMarks()
+
+	if lsp, found := e.lsp2lang[e.Lang]; found && lsp.IsReady {
+		lsp.DidSave(e.AbsoluteFilePath, func() string { return ConvertContentToString(e.Content) })
+	}
 }
 
 func (e *Editor) ReadContent(filename string, fromline int, toline int) [][]rune {
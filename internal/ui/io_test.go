@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"edgo/internal/history"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadWriteUnchanged(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"empty.txt":   "",
+		"newline.txt": "\n",
+		"crlf.txt":    "one\r\ntwo\r",
+		"mixed.txt":   "one\r\ntwo\nthree\r\n",
+		"mixed2.txt":  "one\ntwo\r\nthree",
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, name)
+		os.WriteFile(file, []byte(data), 0644)
+
+		e := testEditor("")
+		e.Config.LargeFile.Size = 1 << 20
+		e.AbsoluteFilePath = file
+		e.ReadFile(file)
+		e.WriteFile()
+
+		written, _ := os.ReadFile(file)
+		if string(written) != data { t.Errorf("%s: written %q, expected %q", name, written, data) }
+	}
+}
+
+func TestMixedLineEndings(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	file := filepath.Join(t.TempDir(), "mixed.txt")
+	os.WriteFile(file, []byte("one\r\ntwo\nthree\r\n"), 0644)
+
+	e := testEditor("")
+	e.Config.LargeFile.Size = 1 << 20
+	e.AbsoluteFilePath = file
+	e.ReadFile(file)
+	if !e.Format.Mixed || content(e) != "one\ntwo\nthree" { t.Fatalf("lines should have no \\r, got %q %s", content(e), e.Format) }
+
+	e.Content[1] = append(e.Content[1], 's')
+	e.Content = append(e.Content[:1], append([][]rune{[]rune("new")}, e.Content[1:]...)...)
+	e.WriteFile()
+	if written, _ := os.ReadFile(file); string(written) != "one\r\nnew\ntwos\nthree\r\n" { t.Errorf("lines should keep their endings, got %q", written) }
+
+	e.OnToggleLineEnding()
+	e.WriteFile()
+	if written, _ := os.ReadFile(file); e.Format.Mixed || string(written) != "one\nnew\ntwos\nthree\n" { t.Errorf("toggle should convert to lf, got %q %s", written, e.Format) }
+}
+
+func TestHistorySavedOnExplicitSave(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	file := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(file, []byte("one\n"), 0644)
+
+	e := testEditor("")
+	e.Config.LargeFile.Size = 1 << 20
+	e.OpenFile(file)
+	e.insertText(0, 3, "s")
+	e.IsContentChanged = true
+
+	e.WriteFile() // as autosave does
+	if _, found := history.Load(e.historyDir(), file, history.Hash(e.Content)); found { t.Errorf("history should not be saved on autosave") }
+	e.OnSave()
+	if _, found := history.Load(e.historyDir(), file, history.Hash(e.Content)); !found { t.Errorf("history should be saved on explicit save") }
+}
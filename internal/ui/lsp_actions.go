@@ -6,6 +6,7 @@ import (
 	. "edgo/internal/lsp"
 	. "edgo/internal/operations"
 	"edgo/internal/search"
+	"edgo/internal/text"
 	. "edgo/internal/utils"
 	"fmt"
 	. "github.com/gdamore/tcell"
@@ -14,6 +15,37 @@ import (
 	"time"
 )
 
+// interrupt event data, sent when language server of the lang is initialized
+type lspReady struct{ lang string }
+
+// opens every buffer of the language on the server, texts are read on the main loop
+func (e *Editor) onLspReady(ready lspReady) {
+	e.storeBuffer()
+	for _, b := range e.Buffers {
+		if b.Lang == ready.lang { e.lspOpen(b.Lang, b.AbsoluteFilePath, b.Text, b.loader == nil) }
+	}
+}
+
+// sends didOpen if the server of the lang is ready and the file is not opened on it yet.
+// Large file is opened when it is read completely, see applyLoadedLines
+func (e *Editor) lspOpen(lang, file string, t *text.PieceTable, loaded bool) {
+	lsp, found := e.lsp2lang[lang]
+	if !found || !lsp.IsReady || t == nil || !loaded || lsp.IsOpen(file) { return }
+	code := t.String()
+	lsp.DidOpen(file, &code) // not async, following changes must come after it
+}
+
+// sends edit of the current file to language server, nil change means the whole text was replaced
+func (e *Editor) didChange(change *ContentChange) {
+	lsp, found := e.lsp2lang[e.Lang]
+	if !found || !lsp.IsReady { return }
+	// not opened file gets the text with the change
+	if !lsp.IsOpen(e.AbsoluteFilePath) { e.lspOpen(e.Lang, e.AbsoluteFilePath, e.Text, e.loader == nil); return }
+
+	if change == nil || !lsp.IsIncremental() { change = &ContentChange{ Text: e.Text.String() } }
+	lsp.DidChange(e.AbsoluteFilePath, []ContentChange{*change})
+}
+
 func (e *Editor) OnDefinition() {
 	if e.Lang == "" { return }
 	Lsp := e.lsp2lang[e.Lang]
@@ -79,9 +111,14 @@ func (e *Editor) OnHover() {
 		tabs := CountTabsTo(e.Content[e.Row], e.Col)
 		width := Max(30, MaxString(options))                                                                             // width depends on max option len or 30 at min
 		height := MinMany(10, len(options))                                                                              // depends on min option len or 5 at min or how many rows to the end of e.Screen
-		atx := (e.Col - tabs) + e.LINES_WIDTH + tabs * (e.langTabWidth) + e.FilesPanelWidth; aty := e.Row - height - e.Y // Define the window  position and dimensions
+		atx := (e.Col - tabs) + e.LINES_WIDTH + tabs * (e.langTabWidth) + e.FilesPanelWidth; aty := e.screenRow(e.Row) - height // Define the window  position and dimensions
 		style := StyleDefault.Foreground(ColorWhite)
-		if len(options) > e.Row- e.Y { aty = e.Row + 1 }
+		if len(options) > e.screenRow(e.Row) { aty = e.screenRow(e.Row) + 1 }
+		if e.softWrap {
+			x, y := e.wrappedCursor()
+			atx, aty = x, y-height
+			if len(options) > y { aty = y + 1 }
+		}
 
 		var selectionEnd = false; var selected = 0; var selectedOffset = 0
 
@@ -92,7 +129,7 @@ func (e *Editor) OnHover() {
 			e.drawCompletion(atx,aty, height, width, options, selected, selectedOffset, style)
 			e.Screen.Show()
 
-			switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+			switch ev := e.PollEvent().(type) { // poll and handle event
 			case *EventKey:
 				key := ev.Key()
 				if key == KeyEscape || key == KeyEnter ||
@@ -146,9 +183,14 @@ func (e *Editor) OnSignatureHelp() {
 		tabs := CountTabsTo(e.Content[e.Row], e.Col)
 		width := Max(30, MaxString(options))                                                                           // width depends on max option len or 30 at min
 		height := MinMany(10, len(options))                                                                            // depends on min option len or 5 at min or how many rows to the end of e.Screen
-		atx := (e.Col - tabs) + e.LINES_WIDTH + tabs*(e.langTabWidth) + e.FilesPanelWidth; aty := e.Row - height - e.Y // Define the window  position and dimensions
+		atx := (e.Col - tabs) + e.LINES_WIDTH + tabs*(e.langTabWidth) + e.FilesPanelWidth; aty := e.screenRow(e.Row) - height // Define the window  position and dimensions
 		style := StyleDefault.Foreground(ColorWhite)
-		if len(options) > e.Row- e.Y { aty = e.Row + 1 }
+		if len(options) > e.screenRow(e.Row) { aty = e.screenRow(e.Row) + 1 }
+		if e.softWrap {
+			x, y := e.wrappedCursor()
+			atx, aty = x, y-height
+			if len(options) > y { aty = y + 1 }
+		}
 
 		var selectionEnd = false; var selected = 0; var selectedOffset = 0
 
@@ -159,7 +201,7 @@ func (e *Editor) OnSignatureHelp() {
 			e.drawCompletion(atx,aty, height, width, options, selected, selectedOffset, style)
 			e.Screen.Show()
 
-			switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+			switch ev := e.PollEvent().(type) { // poll and handle event
 			case *EventKey:
 				key := ev.Key()
 				if key == KeyEscape || key == KeyEnter ||
@@ -231,7 +273,7 @@ func (e *Editor) OnReferences() {
 		height := MinMany(3, len(options))                                                                           // depends on min option len or 5 at min or how many rows to the end of e.Screen
 		//atx := (e.Col -tabs) + e.LINES_WIDTH + tabs*(e.langTabWidth) + e.FilesPanelWidth;
 		atx := e.FilesPanelWidth;
-		//aty := e.Row - height - e.Y // Define the window  position and dimensions
+		//aty := e.screenRow(e.Row) - height // Define the window  position and dimensions
 		aty := 0 // Define the window  position and dimensions
 		style := StyleDefault.Foreground(ColorWhite)
 		//if len(options) > e.Row - e.Y { aty = e.Row + 1 }
@@ -250,7 +292,7 @@ func (e *Editor) OnReferences() {
 			e.Screen.HideCursor()
 			e.Screen.Show()
 
-			switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+			switch ev := e.PollEvent().(type) { // poll and handle event
 			case *EventKey:
 				key := ev.Key()
 				if key == KeyEscape || key == KeyEnter ||
@@ -319,12 +361,16 @@ func (e *Editor) OnCompletion() {
 		tabs := CountTabsTo(e.Content[e.Row], e.Col)
 		atx := (e.Col - tabs) + e.LINES_WIDTH + tabs*(e.langTabWidth) + e.FilesPanelWidth
 		if e.X != 0  { atx = (e.Col) + e.LINES_WIDTH + e.FilesPanelWidth - e.X }
-		aty := e.Row + 1 - e.Y // Define the window  position and dimensions
+		aty := e.screenRow(e.Row) + 1 // Define the window  position and dimensions
 		width := Max(30, MaxString(options))                            // width depends on Max option len or 30 at min
-		height := MinMany(5, len(options), e.ROWS - (e.Row- e.Y)) // depends on min option len or 5 at min or how many rows to the end of e.Screen
+		height := MinMany(5, len(options), e.ROWS - e.screenRow(e.Row)) // depends on min option len or 5 at min or how many rows to the end of e.Screen
+		if e.softWrap {
+			x, y := e.wrappedCursor()
+			atx, aty, height = x, y+1, MinMany(5, len(options), e.ROWS-y-1)
+		}
 		style := StyleDefault
 		// if completion on last two rows of the e.Screen - move window up
-		if e.Row - e.Y >= e.ROWS - 1 { aty -= Min(5, len(options)); aty--; height = Min(5, len(options)) }
+		if e.screenRow(e.Row) >= e.ROWS - 1 { aty -= Min(5, len(options)); aty--; height = Min(5, len(options)) }
 
 		var selectionEnd = false; var selected = 0; var selectedOffset = 0
 
@@ -336,7 +382,7 @@ func (e *Editor) OnCompletion() {
 			e.drawCompletion(atx,aty, height, width, options, selected, selectedOffset, style)
 			e.Screen.Show()
 
-			switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+			switch ev := e.PollEvent().(type) { // poll and handle event
 			case *EventKey:
 				key := ev.Key()
 				if key == KeyEscape || key == KeyCtrlSpace { selectionEnd = true; completionEnd = true }
@@ -441,6 +487,7 @@ func (e *Editor) completionApply(completion CompletionResponse, selected int) {
 		// move cursor to beginning
 		e.Col = int(from)
 		// remove chars between from and end
+		e.deleteText(e.Row, e.Col, string(e.Content[e.Row][e.Col:int(end)]))
 		e.Content[e.Row] = append(e.Content[e.Row][:e.Col], e.Content[e.Row][int(end):]...)
 		newText = item.TextEdit.NewText
 	}
@@ -454,6 +501,7 @@ func (e *Editor) completionApply(completion CompletionResponse, selected int) {
 		if len(newText) == 0 { newText = item.Label }
 		end = float64(next)
 		e.Col = prev
+		e.deleteText(e.Row, e.Col, string(e.Content[e.Row][e.Col:int(end)]))
 		e.Content[e.Row] = append(e.Content[e.Row][:e.Col], e.Content[e.Row][int(end) :]...)
 	}
 
@@ -463,7 +511,7 @@ func (e *Editor) completionApply(completion CompletionResponse, selected int) {
 
 	e.Update = true
 	e.IsContentChanged = true
-	if len(e.Content) <= 10000 { go e.WriteFile() }
+	e.AutoSave()
 }
 
 func (e *Editor) OnRename() {
@@ -504,7 +552,7 @@ func (e *Editor) OnRename() {
 		e.Screen.Show()
 
 
-		switch ev := e.Screen.PollEvent().(type) { // poll and handle event
+		switch ev := e.PollEvent().(type) { // poll and handle event
 		case *EventResize:
 			e.COLUMNS, e.ROWS = e.Screen.Size()
 
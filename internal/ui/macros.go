@@ -0,0 +1,165 @@
+package ui
+
+import (
+	. "edgo/internal/config"
+	"edgo/internal/keymap"
+	. "edgo/internal/logger"
+	"edgo/internal/macro"
+	. "edgo/internal/utils"
+	. "github.com/gdamore/tcell"
+	"path/filepath"
+	"strconv"
+)
+
+/*
+	Macro is a sequence of keys recorded into a one char register.
+	Keys are recorded in PollEvent, so keys typed into dialogs (search, go to line) are recorded too.
+	Playing puts keys into macroQueue which PollEvent reads before the screen.
+	Play prompt takes register with optional count: "a" plays once, "5a" five times, "*a" until it fails.
+	Macro fails if a movement key can not move the cursor, like down on the last line, or if it changes nothing.
+*/
+
+// playing handles keys as editor commands do, so it is registered here to avoid initialization cycle
+func init() {
+	editorCommands["macro-record"] = (*Editor).OnMacroRecord
+	editorCommands["macro-play"] = (*Editor).OnMacroPlay
+}
+
+const maxMacroRuns = 1000 // limit for playing until fail
+const maxMacroDepth = 100  // limit for macros playing themselves
+
+// next event, replayed keys go first, keys from screen are recorded while recording
+func (e *Editor) PollEvent() Event {
+	if len(e.macroQueue) > 0 {
+		ev := e.macroQueue[0]
+		e.macroQueue = e.macroQueue[1:]
+		return ev
+	}
+
+	ev := e.Screen.PollEvent()
+	if key, ok := ev.(*EventKey); ok && e.macroRecording != "" {
+		e.macroKeys = append(e.macroKeys, keymap.ChordOf(key))
+	}
+	return ev
+}
+
+// starts recording into register or stops recording and saves macros
+func (e *Editor) OnMacroRecord() {
+	if e.macroDepth > 0 { return }
+
+	if e.macroRecording != "" {
+		// keys of this command are not a part of macro
+		recorded := e.macroKeys[:Max(e.macroMark-len(e.lastKeys)+1, 0)]
+		e.loadMacros()
+		e.macros[e.macroRecording] = append([]keymap.Chord{}, recorded...)
+		e.macroRecording, e.macroKeys = "", nil
+		if err := e.macros.Save(macrosPath()); err != nil { Log.Error("macros save", err.Error()) }
+		return
+	}
+
+	input, ok := e.macroPrompt(" record macro: ", false)
+	if !ok { return }
+	e.macroRecording, e.macroKeys = input, nil
+}
+
+// plays macro once, count times or until it fails, as one undo step
+func (e *Editor) OnMacroPlay() {
+	input, ok := e.macroPrompt(" play macro: ", true)
+	if !ok { return }
+
+	register := input[len(input)-1:]
+	e.loadMacros()
+	keys := e.macros[register]
+	if len(keys) == 0 || e.macroDepth >= maxMacroDepth { return }
+
+	count, untilFail := 1, false
+	if prefix := input[:len(input)-1]; prefix == "*" {
+		count, untilFail = maxMacroRuns, true
+	} else if n, err := strconv.Atoi(prefix); err == nil && n > 0 {
+		count = n
+	}
+
+	e.macroDepth++
+	defer func() { e.macroDepth-- }()
+
+	file, undoStart := e.AbsoluteFilePath, e.UndoTree.Current
+	for i := 0; i < count; i++ {
+		before := e.macroState()
+		if !e.playMacroKeys(keys) { break }
+		if untilFail && e.macroState() == before { break }
+	}
+
+	// buffer can be switched by macro, its undo history is not merged then
+	if e.AbsoluteFilePath == file { e.UndoTree.MergeSince(undoStart) }
+	e.Update = true
+}
+
+// handles keys one by one as typed, returns false if macro failed
+func (e *Editor) playMacroKeys(keys []keymap.Chord) bool {
+	events := []Event{}
+	for _, chord := range keys { events = append(events, NewEventKey(chord.Key, chord.Rune, chord.Mod)) }
+
+	// nested macro keys go before the rest of the outer macro
+	rest := len(e.macroQueue)
+	e.macroQueue = append(events, e.macroQueue...)
+	for len(e.macroQueue) > rest {
+		ev, before := e.macroQueue[0], e.macroState()
+		e.HandleEvents()
+		if isMovementKey(ev) && e.macroState() == before {
+			e.macroQueue = e.macroQueue[len(e.macroQueue)-rest:]
+			return false
+		}
+	}
+	return true
+}
+
+func isMovementKey(ev Event) bool {
+	key, ok := ev.(*EventKey)
+	if !ok { return false }
+	switch key.Key() {
+	case KeyUp, KeyDown, KeyLeft, KeyRight, KeyHome, KeyEnd, KeyPgUp, KeyPgDn: return true
+	}
+	return false
+}
+
+type macroState struct { file string; row, col, undo int }
+
+// iteration failed if it changed nothing and did not move the cursor
+func (e *Editor) macroState() macroState {
+	return macroState{e.AbsoluteFilePath, e.Row, e.Col, e.UndoTree.Current}
+}
+
+// reads register name, with count or '*' before it if withCount
+func (e *Editor) macroPrompt(prompt string, withCount bool) (string, bool) {
+	var input = []rune{}
+	for {
+		e.DrawEverything()
+		atx := e.FilesPanelWidth + e.LINES_WIDTH
+		text := []rune(prompt + string(input))
+		for i, ch := range text { e.Screen.SetContent(atx+i, 0, ch, nil, StyleDefault) }
+		e.Screen.ShowCursor(atx+len(text), 0)
+		e.Screen.Show()
+
+		ev, ok := e.PollEvent().(*EventKey)
+		if !ok { continue }
+		key, ch := ev.Key(), ev.Rune()
+		if key == KeyEscape { return "", false }
+		if (key == KeyBackspace || key == KeyBackspace2) && len(input) > 0 { input = input[:len(input)-1] }
+		if key != KeyRune || ev.Modifiers()&(ModAlt|ModCtrl) != 0 { continue }
+
+		isCount := ch >= '0' && ch <= '9' && !(ch == '0' && len(input) == 0) || ch == '*' && len(input) == 0
+		if withCount && isCount && (len(input) == 0 || input[0] != '*') { input = append(input, ch); continue }
+		return string(append(input, ch)), true
+	}
+}
+
+func (e *Editor) loadMacros() {
+	if e.macros != nil { return }
+	macros, err := macro.Load(macrosPath())
+	if err != nil { Log.Error("macros load", err.Error()) }
+	e.macros = macros
+}
+
+func macrosPath() string {
+	return filepath.Join(StateDir(), "macros.json")
+}
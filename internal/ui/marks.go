@@ -0,0 +1,187 @@
+package ui
+
+import (
+	. "edgo/internal/config"
+	. "edgo/internal/highlighter"
+	. "edgo/internal/io"
+	. "edgo/internal/logger"
+	"edgo/internal/marks"
+	. "edgo/internal/operations"
+	"edgo/internal/search"
+	. "edgo/internal/utils"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"path/filepath"
+)
+
+/*
+	Bookmarks are toggled at the cursor line, named marks are set by a one char name and jumped to by it.
+	Marks of the project are saved in state dir when they change and when the file is saved.
+	Lines of marks of the opened file follow its edits, like folds do.
+	Jumps to marks are recorded into cursor history, so cursor back returns from them.
+*/
+
+func marksPath(cwd string) string {
+	return marks.Path(StateDir(), cwd)
+}
+
+func (e *Editor) loadMarks() {
+	if e.marks != nil { return }
+	loaded, err := marks.Load(marksPath(e.Cwd))
+	if err != nil { Log.Error("marks load", err.Error()) }
+	e.marks = loaded
+}
+
+func (e *Editor) saveMarks() {
+	if e.marks == nil { return }
+	if err := e.marks.Save(marksPath(e.Cwd)); err != nil { Log.Error("marks save", err.Error()) }
+}
+
+// moves marks of the file after count line breaks were inserted (or deleted) at row and col
+func (e *Editor) shiftMarks(row, col, count int) {
+	if count == 0 || e.AbsoluteFilePath == "" { return }
+	e.loadMarks()
+	e.marks.Shift(e.AbsoluteFilePath, row, col, count)
+}
+
+func (e *Editor) drawMarkSign(line, row int) {
+	e.loadMarks()
+	mark, found := e.marks.At(e.AbsoluteFilePath, line)
+	if !found { return }
+	sign := '◆'
+	if mark.Name != "" { sign = []rune(mark.Name)[0] }
+	e.Screen.SetContent(e.FilesPanelWidth, row, sign, nil, StyleDefault.Foreground(Color(AccentColor)))
+}
+
+func (e *Editor) OnToggleBookmark() {
+	if e.AbsoluteFilePath == "" || len(e.Content) == 0 { return }
+	e.loadMarks()
+	e.marks = e.marks.Toggle(e.AbsoluteFilePath, e.Row, e.Col)
+	e.saveMarks()
+	e.Update = true
+}
+
+func (e *Editor) OnNextBookmark() { e.nextMark(true) }
+func (e *Editor) OnPrevBookmark() { e.nextMark(false) }
+
+func (e *Editor) nextMark(forward bool) {
+	e.loadMarks()
+	if mark, found := e.marks.Next(e.AbsoluteFilePath, e.Row, forward); found { e.jumpToMark(mark) }
+}
+
+func (e *Editor) setMark(name string) {
+	if e.AbsoluteFilePath == "" || len(e.Content) == 0 { return }
+	e.loadMarks()
+	e.marks = e.marks.Set(name, e.AbsoluteFilePath, e.Row, e.Col)
+	e.saveMarks()
+	e.Update = true
+}
+
+func (e *Editor) OnSetMark() {
+	if name, ok := e.macroPrompt("set mark: ", false); ok { e.setMark(name) }
+}
+
+func (e *Editor) OnGoToMark() {
+	name, ok := e.macroPrompt("go to mark: ", false)
+	if !ok { return }
+	e.loadMarks()
+	if mark, found := e.marks.Named(name); found { e.jumpToMark(mark) }
+}
+
+// opens the file of the mark and puts the cursor at it, lines out of the file go to its end
+func (e *Editor) jumpToMark(mark marks.Mark) {
+	if mark.File != e.AbsoluteFilePath && !IsFileExists(mark.File) { return }
+	e.CursorHistory = append(e.CursorHistory, CursorMove{e.AbsoluteFilePath, e.Row, e.Col, e.Y, e.X})
+
+	if mark.File != e.AbsoluteFilePath {
+		e.InputFile = mark.File
+		if err := e.OpenFile(mark.File); err != nil { return }
+	}
+	if len(e.Content) == 0 { return }
+
+	e.Row = Min(mark.Line, len(e.Content)-1)
+	e.Col = Min(mark.Col, len(e.Content[e.Row]))
+	e.Selection.CleanSelection()
+	e.FocusCenter()
+	e.Update = true
+}
+
+// options and previews of the picker, in the order of marks
+func (e *Editor) markOptions(sorted marks.Marks) ([]string, []search.FileSearchResult) {
+	options, results := []string{}, []search.FileSearchResult{}
+	for i, mark := range sorted {
+		file, err := filepath.Rel(e.Cwd, mark.File)
+		if err != nil { file = mark.File }
+		name := ""
+		if mark.Name != "" { name = "'" + mark.Name + " " }
+
+		options = append(options, fmt.Sprintf("%d/%d %s%s:%d ", i+1, len(sorted), name, file, mark.Line+1))
+		results = append(results, search.FileSearchResult{File: mark.File,
+			Results: []search.SearchResult{{Line: mark.Line + 1, Position: mark.Col}},
+		})
+	}
+	return options, results
+}
+
+// lists marks of every file with previews of their lines, enter jumps to the selected one, delete removes it
+func (e *Editor) OnBookmarks() {
+	e.loadMarks()
+	if len(e.marks) == 0 { return }
+
+	e.IsOverlay = true
+	defer e.OverlayFalse()
+
+	initialLang := e.treeSitterHighlighter.GetLangStr()
+	restoreLang := func() {
+		if e.treeSitterHighlighter.GetLangStr() == initialLang { return }
+		e.treeSitterHighlighter.SetLang(initialLang)
+		e.parseText()
+	}
+
+	var selected = 0
+	var selectedOffset = 0
+
+	for len(e.marks) > 0 {
+		sorted := e.marks.Sorted()
+		options, results := e.markOptions(sorted)
+		selected = Min(selected, len(options)-1)
+		height := MinMany(5, len(options))
+		if selected < selectedOffset { selectedOffset = selected } // calculate offsets for scrolling
+		if selected >= selectedOffset+height { selectedOffset = selected - height + 1 }
+
+		e.DrawCodePreview(e.FilesPanelWidth, 0, height, options, selectedOffset, selected, StyleDefault, results,
+			fmt.Sprintf("bookmarks: %d, enter jumps, delete removes", len(options)))
+		e.Screen.HideCursor()
+		e.Screen.Show()
+
+		switch ev := e.PollEvent().(type) { // poll and handle event
+		case *EventResize:
+			e.COLUMNS, e.ROWS = e.Screen.Size()
+			e.Screen.Sync()
+			e.Screen.Clear()
+
+		case *EventKey:
+			key := ev.Key()
+			if key == KeyEscape || key == KeyBackspace || key == KeyBackspace2 {
+				e.Screen.Clear()
+				restoreLang()
+				return
+			}
+			if key == KeyDown { selected = Min(len(options)-1, selected+1) }
+			if key == KeyUp { selected = Max(0, selected-1) }
+			if key == KeyDelete {
+				e.marks = e.marks.Remove(sorted[selected])
+				e.saveMarks()
+				e.Screen.Clear()
+			}
+			if key == KeyEnter {
+				e.Screen.Clear()
+				restoreLang()
+				e.jumpToMark(sorted[selected])
+				return
+			}
+		}
+	}
+	e.Screen.Clear()
+	restoreLang()
+}
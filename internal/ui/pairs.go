@@ -0,0 +1,285 @@
+package ui
+
+import (
+	. "edgo/internal/operations"
+	. "edgo/internal/utils"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+/*
+	Typed opening bracket or quote gets its closing one by rules of the language: not inside strings and comments,
+	not before a word, quotes not after a word (it's, rust lifetimes). Typed closing char steps over the one
+	of a pair, backspace in an empty pair deletes both. Pairs are checked by the tree-sitter tree if the language has it.
+	Surround wraps the selection into brackets, quotes or tags; change and delete act on the innermost ones with the cursor.
+*/
+
+type pairRules struct {
+	pairs        map[rune]rune
+	noQuoteAfter string   // chars after which ' is not a quote, like &'a in rust
+	noQuoteIn    []string // node types before the cursor where ' is not a quote
+	prefixes     []string // words before opening quotes of strings, like f"" in python
+}
+
+var defaultPairs = map[rune]rune{'(': ')', '[': ']', '{': '}', '"': '"', '\'': '\'', '`': '`'}
+
+// pairs of prose, where ' is an apostrophe
+var textPairs = map[rune]rune{'(': ')', '[': ']', '{': '}', '"': '"', '`': '`'}
+
+var langPairs = map[string]pairRules{
+	"rust":     {noQuoteAfter: "&<", noQuoteIn: []string{"type_parameters", "type_arguments", "trait_bounds", "lifetime"}},
+	"python":   {prefixes: []string{"f", "r", "b", "u", "rb", "br", "fr", "rf"}},
+	"text":     {pairs: textPairs},
+	"markdown": {pairs: textPairs},
+}
+
+func (e *Editor) pairs() map[rune]rune {
+	if rules := langPairs[e.Lang]; rules.pairs != nil { return rules.pairs }
+	return defaultPairs
+}
+
+// opening char of the closing one, or the quote itself
+func (e *Editor) openerOf(ch rune) (rune, bool) {
+	for open, close := range e.pairs() {
+		if close == ch { return open, true }
+	}
+	return 0, false
+}
+
+func isWordChar(ch rune) bool { return ch == '_' || unicode.IsLetter(ch) || unicode.IsDigit(ch) }
+
+// closing char to insert after ch typed at the cursor, checked before ch is inserted
+func (e *Editor) autoPair(ch rune) (rune, bool) {
+	close, found := e.pairs()[ch]
+	if !found { return 0, false }
+	line := e.Content[e.Row]
+	if e.Col < len(line) && !unicode.IsSpace(line[e.Col]) && !strings.ContainsRune(")]},;:", line[e.Col]) { return 0, false }
+
+	offset := e.Text.Offset(e.Row, e.Col)
+	if e.hasSyntaxTree() && e.treeSitterHighlighter.SyntaxAt(offset, e.Text) != "" { return 0, false }
+	if ch != close { return close, true }
+
+	// quotes
+	rules := langPairs[e.Lang]
+	if e.Col > 0 && isWordChar(line[e.Col-1]) {
+		start := e.Col
+		for start > 0 && isWordChar(line[start-1]) { start-- }
+		if !slices.Contains(rules.prefixes, strings.ToLower(string(line[start:e.Col]))) { return 0, false }
+	}
+	if ch == '\'' && e.Col > 0 && strings.ContainsRune(rules.noQuoteAfter, line[e.Col-1]) { return 0, false }
+	if ch == '\'' && e.Col > 0 && e.hasSyntaxTree() {
+		for _, kind := range e.treeSitterHighlighter.NodeTypesAt(offset - 1) {
+			if slices.Contains(rules.noQuoteIn, kind) { return 0, false }
+		}
+	}
+	return close, true
+}
+
+// typed closing char is the next one and closes a pair, so the cursor steps over it
+func (e *Editor) skipsCloser(ch rune) bool {
+	line := e.Content[e.Row]
+	if e.Col >= len(line) || line[e.Col] != ch { return false }
+	open, found := e.openerOf(ch)
+	if !found { return false }
+	if !e.hasSyntaxTree() { return true }
+
+	offset := e.Text.Offset(e.Row, e.Col)
+	if open != ch {
+		b, found := e.treeSitterHighlighter.MatchBracket(offset)
+		return found && b.Match >= 0 && !b.IsOpen()
+	}
+	start, end, found := e.treeSitterHighlighter.StringAround(offset)
+	return found && start < offset && end == offset+len(string(ch))
+}
+
+// the cursor is between an empty pair
+func (e *Editor) inEmptyPair() bool {
+	line := e.Content[e.Row]
+	if e.Col == 0 || e.Col >= len(line) { return false }
+	open := line[e.Col-1]
+	if close, found := e.pairs()[open]; !found || line[e.Col] != close { return false }
+	if !e.hasSyntaxTree() { return true }
+
+	offset := e.Text.Offset(e.Row, e.Col-1)
+	if open != line[e.Col] {
+		b, found := e.treeSitterHighlighter.MatchBracket(offset)
+		return found && b.Match == e.Text.Offset(e.Row, e.Col)
+	}
+	start, end, found := e.treeSitterHighlighter.StringAround(offset)
+	return found && start == offset && end == e.Text.Offset(e.Row, e.Col+1)
+}
+
+// deletes both chars around the cursor as one undo step
+func (e *Editor) deletePair() {
+	open, close := e.Content[e.Row][e.Col-1], e.Content[e.Row][e.Col]
+	e.UndoTree.Push(EditOperation{
+		{MoveCursor, open, e.Row, e.Col},
+		{Delete, close, e.Row, e.Col},
+		{Delete, open, e.Row, e.Col - 1},
+	})
+	e.Col--
+	e.deleteText(e.Row, e.Col, string([]rune{open, close}))
+	e.Content[e.Row] = slices.Delete(e.Content[e.Row], e.Col, e.Col+2)
+}
+
+// inserts runes without line breaks into the line
+func (e *Editor) insertRunes(row, col int, s []rune, ops *EditOperation) {
+	for i, ch := range s { *ops = append(*ops, Operation{Insert, ch, row, col + i}) }
+	e.Content[row] = slices.Insert(e.Content[row], col, s...)
+	e.insertText(row, col, string(s))
+}
+
+func (e *Editor) deleteRunes(row, col, count int, ops *EditOperation) {
+	deleted := string(e.Content[row][col : col+count])
+	for i := col + count - 1; i >= col; i-- { *ops = append(*ops, Operation{Delete, e.Content[row][i], row, i}) }
+	e.deleteText(row, col, deleted)
+	e.Content[row] = slices.Delete(e.Content[row], col, col+count)
+}
+
+// opening and closing parts of a surround: a bracket, a quote, or a tag like <div class="a">
+func surroundPair(input string) ([]rune, []rune, bool) {
+	input = strings.TrimSpace(input)
+	if input == "" || input == "<" || input == ">" { return []rune("<"), []rune(">"), input != "" }
+
+	runes := []rune(input)
+	if len(runes) == 1 {
+		ch := runes[0]
+		if close, found := defaultPairs[ch]; found { return runes, []rune{close}, true }
+		for open, close := range defaultPairs {
+			if close == ch { return []rune{open}, runes, true }
+		}
+		return runes, runes, true
+	}
+
+	tag := strings.TrimSuffix(strings.TrimPrefix(input, "<"), ">")
+	fields := strings.Fields(tag)
+	if len(fields) == 0 { return nil, nil, false }
+	return []rune("<" + tag + ">"), []rune("</" + fields[0] + ">"), true
+}
+
+// ordered selection, or the word under the cursor
+func (e *Editor) surroundTarget() (int, int, int, int, bool) {
+	if e.Selection.IsSelectionNonEmpty() && !e.Selection.IsBlock {
+		sy, sx, ey, ex := e.Selection.Ssy, e.Selection.Ssx, e.Selection.Sey, e.Selection.Sex
+		if ey < sy || ey == sy && ex < sx { sy, sx, ey, ex = ey, ex, sy, sx }
+		if ey >= len(e.Content) { ey, ex = len(e.Content)-1, len(e.Content[len(e.Content)-1]) }
+		return sy, Min(sx, len(e.Content[sy])), ey, Min(ex, len(e.Content[ey])), true
+	}
+
+	line := e.Content[e.Row]
+	start, end := e.Col, e.Col
+	for start > 0 && isWordChar(line[start-1]) { start-- }
+	for end < len(line) && isWordChar(line[end]) { end++ }
+	return e.Row, start, e.Row, end, start < end
+}
+
+// wraps the selection or the word, selection is kept on the wrapped text
+func (e *Editor) surround(open, close []rune) bool {
+	sy, sx, ey, ex, found := e.surroundTarget()
+	if !found { return false }
+
+	ops := EditOperation{}
+	e.insertRunes(ey, ex, close, &ops)
+	e.insertRunes(sy, sx, open, &ops)
+	if sy == ey { ex += len(open) }
+
+	e.Selection.Ssy, e.Selection.Ssx = sy, sx+len(open)
+	e.Selection.Sey, e.Selection.Sex = ey, ex
+	e.Selection.IsSelected = true
+	e.Row, e.Col = ey, ex
+	e.finishEdit(ops)
+	return true
+}
+
+// typed opening char around the selection surrounds it
+func (e *Editor) surroundSelection(ch rune) bool {
+	close, found := e.pairs()[ch]
+	if !found || !e.Selection.IsSelectionNonEmpty() || e.Selection.IsBlock { return false }
+	return e.surround([]rune{ch}, []rune{close})
+}
+
+func (e *Editor) OnSurround() {
+	input, ok := e.inputPrompt(" surround with: ")
+	if !ok { return }
+	open, close, ok := surroundPair(input)
+	if ok { e.surround(open, close) }
+}
+
+// byte ranges of the innermost brackets, quotes of a string, or tags of an element with the cursor
+func (e *Editor) surroundingAt() ([2]int, [2]int, bool) {
+	if !e.hasSyntaxTree() { return [2]int{}, [2]int{}, false }
+	offset := e.Text.Offset(e.Row, e.Col)
+	open, close, found := [2]int{}, [2]int{}, false
+	inner := func(o, c [2]int) {
+		if !found || o[0] > open[0] { open, close, found = o, c, true }
+	}
+
+	if o, c, ok := e.bracketPair(); ok { inner([2]int{o, o + 1}, [2]int{c, c + 1}) }
+	if start, end, ok := e.treeSitterHighlighter.StringAround(offset); ok {
+		if opening, closing := stringQuotes(string(e.Text.Slice(start, end))); opening > 0 {
+			inner([2]int{start, start + opening}, [2]int{end - closing, end})
+		}
+	}
+	if o, c, ok := e.treeSitterHighlighter.TagsAround(offset); ok { inner(o, c) }
+	return open, close, found
+}
+
+// byte lengths of the opening delimiter of a string literal with its prefix, like f" in f"a", and the closing one,
+// 0 if the literal is not quoted the same at both ends
+func stringQuotes(literal string) (int, int) {
+	prefix := 0
+	for prefix < len(literal) && unicode.IsLetter(rune(literal[prefix])) { prefix++ }
+	if prefix >= len(literal) || !strings.ContainsRune("\"'`", rune(literal[prefix])) { return 0, 0 }
+
+	quote := literal[prefix : prefix+1]
+	count := 1
+	if strings.HasPrefix(literal[prefix:], strings.Repeat(quote, 3)) && len(literal) >= prefix+6 { count = 3 }
+	if len(literal) < prefix+2*count || !strings.HasSuffix(literal, strings.Repeat(quote, count)) { return 0, 0 }
+	return prefix + count, count
+}
+
+// replaces the surrounding delimiters, or deletes them if open and close are empty
+func (e *Editor) replaceSurrounding(open, close []rune) {
+	openRange, closeRange, found := e.surroundingAt()
+	if !found { return }
+	oy, ox := e.Text.Position(openRange[0])
+	oey, oex := e.Text.Position(openRange[1])
+	cy, cx := e.Text.Position(closeRange[0])
+	cey, cex := e.Text.Position(closeRange[1])
+	if oy != oey || cy != cey { return } // multiline tags
+
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+	e.deleteRunes(cy, cx, cex-cx, &ops)
+	e.insertRunes(cy, cx, close, &ops)
+	e.deleteRunes(oy, ox, oex-ox, &ops)
+	e.insertRunes(oy, ox, open, &ops)
+
+	col := e.Col
+	if e.Row == oy && col >= oex { e.Col += len(open) - (oex - ox) }
+	if e.Row == cy && col >= cex { e.Col += len(close) - (cex - cx) }
+	e.Col = Min(Max(e.Col, 0), len(e.Content[e.Row]))
+	e.Selection.CleanSelection()
+	e.finishEdit(ops)
+}
+
+func (e *Editor) OnChangeSurround() {
+	if _, _, found := e.surroundingAt(); !found { return }
+	input, ok := e.inputPrompt(" change surrounding to: ")
+	if !ok { return }
+	open, close, ok := surroundPair(input)
+	if ok { e.replaceSurrounding(open, close) }
+}
+
+func (e *Editor) OnDeleteSurround() {
+	e.replaceSurrounding(nil, nil)
+}
+
+// pushes ops as one undo step, the text and the tree are already updated
+func (e *Editor) finishEdit(ops EditOperation) {
+	e.UndoTree.Push(ops)
+	e.Update = true
+	e.IsContentChanged = true
+	e.FindTests()
+	e.AutoSave()
+}
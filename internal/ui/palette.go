@@ -0,0 +1,97 @@
+package ui
+
+import (
+	"edgo/internal/keymap"
+	. "edgo/internal/utils"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"sort"
+)
+
+// palette lists editorCommands, so it is registered here to avoid initialization cycle
+func init() { editorCommands["command-palette"] = (*Editor).OnCommandPalette }
+
+// runs command chosen from fuzzy-searchable list of every editor command
+func (e *Editor) OnCommandPalette() {
+	command := e.selectCommand()
+	if command == "" { return }
+	editorCommands[command](e)
+	e.Update = true
+}
+
+func (e *Editor) selectCommand() string {
+	e.IsOverlay = true
+	defer e.OverlayFalse()
+
+	var pattern = []rune{}
+	var selected = 0
+	var selectedOffset = 0
+
+	for {
+		matches := e.filterCommands(string(pattern))
+
+		var options = []string{}
+		longest := MaxString(matches)
+		for _, command := range matches {
+			keys := ""
+			if e.Keymap != nil { keys = keymap.FormatSequence(e.Keymap.KeysOf(keymap.Editor, command)) }
+			options = append(options, fmt.Sprintf("%-*s  %s", longest, command, keys))
+		}
+
+		height := MinMany(10, len(options), e.ROWS-2)
+		if selected >= len(options) { selected = len(options) - 1 }
+		if selected < 0 { selected = 0 }
+		if selected < selectedOffset { selectedOffset = selected }
+		if selected >= selectedOffset+height { selectedOffset = selected - height + 1 }
+
+		atx := e.FilesPanelWidth + e.LINES_WIDTH
+		width := Max(40, MaxString(options)+2)
+		e.DrawEverything()
+		e.drawCompletion(atx, 1, height, width, options, selected, selectedOffset, StyleDefault)
+
+		prefix := " command: " + string(pattern)
+		for i, ch := range []rune(prefix) { e.Screen.SetContent(atx+i, 0, ch, nil, StyleDefault) }
+		for i := atx + len([]rune(prefix)); i < atx+width; i++ { e.Screen.SetContent(i, 0, ' ', nil, StyleDefault) }
+		e.Screen.ShowCursor(atx+len([]rune(prefix)), 0)
+		e.Screen.Show()
+
+		switch ev := e.PollEvent().(type) {
+		case *EventResize:
+			e.COLUMNS, e.ROWS = e.Screen.Size()
+			e.ROWS -= e.ProcessPanelHeight
+
+		case *EventKey:
+			key := ev.Key()
+			if key == KeyEscape { return "" }
+			if key == KeyDown { selected = Min(len(options)-1, selected+1) }
+			if key == KeyUp { selected = Max(0, selected-1) }
+			if key == KeyRune { pattern = append(pattern, ev.Rune()); selected = 0 }
+			if (key == KeyBackspace || key == KeyBackspace2) && len(pattern) > 0 {
+				pattern = pattern[:len(pattern)-1]; selected = 0
+			}
+			if key == KeyEnter && len(matches) > 0 { return matches[selected] }
+		}
+	}
+}
+
+// returns names of commands available now matching the pattern, best matches first
+func (e *Editor) filterCommands(pattern string) []string {
+	type match struct { command string; score int }
+	var matches = []match{}
+
+	for _, command := range commandNames(keymap.Editor) {
+		if command == "command-palette" { continue }
+		if e.Filename == "" && !noFileCommands[command] { continue }
+		if score, ok := FuzzyMatch(command, pattern); ok {
+			matches = append(matches, match{command, score})
+		}
+	}
+
+	if len(pattern) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	}
+
+	commands := make([]string, len(matches))
+	for i, m := range matches { commands[i] = m.command }
+	return commands
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	. "edgo/internal/utils"
+	. "github.com/gdamore/tcell"
+	"sort"
+	"testing"
+)
+
+func TestFilterCommands(t *testing.T) {
+	e := testEditor("")
+	all := e.filterCommands("")
+	if !sort.StringsAreSorted(all) { t.Errorf("commands without pattern should be sorted by name") }
+	for _, command := range all {
+		if command == "command-palette" { t.Errorf("palette should not list itself") }
+	}
+	if len(all) != len(editorCommands)-1 { t.Errorf("expected every command but the palette, got %d of %d", len(all), len(editorCommands)) }
+
+	matches := e.filterCommands("undo")
+	if len(matches) == 0 || matches[0] != "undo" { t.Fatalf("exact name should be the first, got %v", matches) }
+	for i := 1; i < len(matches); i++ {
+		previous, _ := FuzzyMatch(matches[i-1], "undo")
+		score, _ := FuzzyMatch(matches[i], "undo")
+		if score > previous { t.Errorf("%q should be before %q", matches[i], matches[i-1]) }
+	}
+	if len(e.filterCommands("zzzz")) != 0 { t.Errorf("nothing should match") }
+
+	e.Filename = ""
+	for _, command := range e.filterCommands("") {
+		if !noFileCommands[command] { t.Errorf("%q needs an opened file", command) }
+	}
+}
+
+func TestCommandPalette(t *testing.T) {
+	e := testEditor("abc")
+	e.Lang = "" // palette is drawn over the buffer, diagnostics need a language server
+	keys := func(text string, keys ...Key) {
+		e.macroQueue = nil
+		for _, ch := range text { e.macroQueue = append(e.macroQueue, NewEventKey(KeyRune, ch, ModNone)) }
+		for _, key := range keys { e.macroQueue = append(e.macroQueue, NewEventKey(key, 0, ModNone)) }
+	}
+
+	keys("duplic", KeyEscape)
+	e.OnCommandPalette()
+	if content(e) != "abc" { t.Errorf("escape should run nothing, got %q", content(e)) }
+
+	keys("duplx", KeyBackspace2, KeyEnter)
+	e.OnCommandPalette()
+	if content(e) != "abc\nabc" { t.Errorf("selected command should run, got %q", content(e)) }
+
+	// the second match of the pattern is selected by down
+	matches := e.filterCommands("go-")
+	keys("go-", KeyDown, KeyEnter)
+	e.Row = 0
+	e.OnCommandPalette()
+	if matches[1] != "go-bottom" || e.Row != 1 { t.Errorf("down should select %q, cursor at %d", matches[1], e.Row) }
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	. "edgo/internal/config"
+	. "edgo/internal/io"
+	. "edgo/internal/logger"
+	"edgo/internal/session"
+	. "edgo/internal/utils"
+	"strconv"
+)
+
+/*
+	Session of the project is saved on quit: opened files with cursors, files tree, panel sizes,
+	breakpoints and search history. Launched without a file in the same directory,
+	the editor restores it, `-no-session` flag starts from scratch.
+	Launched with `-no-session` or with a file, the editor leaves the saved session as it was.
+*/
+
+func sessionPath(cwd string) string {
+	return session.Path(StateDir(), cwd)
+}
+
+func (e *Editor) saveSession() {
+	if e.NoSession || e.isFileArg { return }
+	e.storeBuffer()
+	s := session.Session{
+		Active:             e.AbsoluteFilePath,
+		FilesPanelWidth:    e.FilesPanelWidth,
+		ProcessPanelHeight: e.processPanelSize,
+		Breakpoints:        e.Dap.Breakpoints,
+		Searches:           e.searchHistory,
+	}
+	if e.ProcessPanelHeight > 0 { s.ProcessPanelHeight = e.ProcessPanelHeight }
+
+	for _, b := range e.Buffers {
+		if b.AbsoluteFilePath == "" { continue }
+		s.Files = append(s.Files, session.File{Path: b.AbsoluteFilePath, Row: b.Row, Col: b.Col, Y: b.Y, X: b.X})
+	}
+
+	// filtered tree is not kept, its dirs are opened by the filter
+	if e.FilesPanelWidth > 0 && !e.IsFilesSearch {
+		s.TreeOpen = OpenDirs(e.Tree)
+		if found, selected := GetSelected(e.Tree, e.FileSelectedIndex); found && e.FileSelectedIndex >= 0 {
+			s.TreeSelected = selected.FullName
+		}
+	}
+
+	if err := s.Save(sessionPath(e.Cwd)); err != nil { Log.Error("session save", err.Error()) }
+}
+
+// restores the last session of the project, false if there was no file to open
+func (e *Editor) restoreSession() bool {
+	if e.NoSession { return false }
+	s, ok := session.Load(sessionPath(e.Cwd))
+	if !ok { return false }
+
+	for _, file := range s.Files {
+		if !IsFileExists(file.Path) { continue }
+		e.InputFile = file.Path
+		if err := e.OpenFile(file.Path); err != nil { Log.Error("session open", err.Error()); continue }
+		if len(e.Content) == 0 { continue }
+
+		e.Row = Min(file.Row, len(e.Content)-1)
+		e.Col = Min(file.Col, len(e.Content[e.Row]))
+		e.Y = Min(file.Y, e.Row)
+		e.X = file.X
+	}
+	if len(e.Buffers) == 0 { return false }
+
+	if index := e.FindBuffer(s.Active); index != -1 { e.SwitchBuffer(index) }
+
+	if s.FilesPanelWidth > 0 { e.restoreTree(s) }
+	e.processPanelSize = s.ProcessPanelHeight
+	if s.Breakpoints != nil { e.Dap.Breakpoints = s.Breakpoints }
+	e.searchHistory = s.Searches
+
+	Log.Info("session restored,", strconv.Itoa(len(e.Buffers)), "files")
+	e.Update = true
+	return true
+}
+
+func (e *Editor) restoreTree(s session.Session) {
+	tree, err := ReadDirTree(e.Cwd, "", false, 0)
+	if err != nil || len(tree.Childs) == 0 { return }
+	e.Tree = tree
+	e.Tree.IsDirOpen = true // root is always opened
+	for _, dir := range s.TreeOpen {
+		if node := FindByFullName(&e.Tree, dir); node != nil && node.IsDir { node.IsDirOpen = true }
+	}
+
+	e.FilesPanelWidth = Min(s.FilesPanelWidth, e.COLUMNS/2)
+	e.FileSelectedIndex = IndexOf(e.Tree, s.TreeSelected)
+	if e.FileSelectedIndex >= e.ROWS { e.FileScrollingOffset = e.FileSelectedIndex - e.ROWS/2 }
+}
+
+// height of process panel when it opens, the last dragged or restored one
+func (e *Editor) openedProcessPanelHeight() int {
+	if e.processPanelSize > 0 && e.processPanelSize < e.TERMINAL_HEIGHT-3 { return e.processPanelSize }
+	return 10
+}
@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"edgo/internal/session"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionKeptWithFileArg(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	dir := t.TempDir()
+	saved := session.Session{Files: []session.File{{Path: filepath.Join(dir, "a.go")}}}
+	saved.Save(sessionPath(dir))
+
+	quit := func(noSession, fileArg bool) string {
+		e := testEditor("")
+		e.Cwd, e.NoSession, e.isFileArg = dir, noSession, fileArg
+		e.Buffers = []*Buffer{{AbsoluteFilePath: filepath.Join(dir, "b.go")}}
+		e.saveSession()
+		s, _ := session.Load(sessionPath(dir))
+		if len(s.Files) != 1 { return "" }
+		return filepath.Base(s.Files[0].Path)
+	}
+
+	if file := quit(true, false); file != "a.go" { t.Errorf("-no-session should keep the saved session, got %q", file) }
+	if file := quit(false, true); file != "a.go" { t.Errorf("file argument should keep the saved session, got %q", file) }
+	if file := quit(false, false); file != "b.go" { t.Errorf("session should be saved, got %q", file) }
+}
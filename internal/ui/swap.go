@@ -0,0 +1,151 @@
+package ui
+
+import (
+	. "edgo/internal/config"
+	. "edgo/internal/logger"
+	"edgo/internal/swap"
+	"edgo/internal/undo"
+	. "edgo/internal/utils"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+/*
+	Not saved content of dirty buffers is written to swap files every swapInterval,
+	so a crash does not lose it, big files are not autosaved at all.
+	Swaps of clean buffers are removed. On start swaps left by crashed editors are offered to restore.
+*/
+
+const swapInterval = 5 * time.Second
+
+type swapTick struct{} // interrupt event data, swaps are written from the main loop
+
+// swap content version, swap is rewritten only if buffer changed
+type swapVersion struct { node, nodes int }
+
+func swapDir() string {
+	return filepath.Join(StateDir(), "swap")
+}
+
+func (e *Editor) startSwapTimer() {
+	go func() {
+		for range time.Tick(swapInterval) { e.Screen.PostEvent(NewEventInterrupt(swapTick{})) }
+	}()
+}
+
+// WriteSwaps writes swaps of dirty buffers and removes swaps of clean ones, it is called on crash too.
+// Returns true if some swap was written or removed
+func (e *Editor) WriteSwaps() (changed bool) {
+	defer func() {
+		if r := recover(); r != nil { Log.Error("swap write", fmt.Sprint(r)) }
+	}()
+	if e.swapped == nil { e.swapped = map[string]swapVersion{} }
+	e.storeBuffer()
+
+	for _, b := range e.Buffers {
+		written, found := e.swapped[b.AbsoluteFilePath]
+		if !b.IsContentChanged {
+			if found { e.removeSwap(b.AbsoluteFilePath); changed = true }
+			continue
+		}
+		if !b.IsFullyLoaded || b.UndoTree == nil { continue }
+
+		version := swapVersion{b.UndoTree.Current, len(b.UndoTree.Nodes)}
+		if found && written == version { continue }
+
+		s := swap.Swap{File: b.AbsoluteFilePath, Content: ConvertContentToString(b.Content), Time: time.Now(), Pid: os.Getpid()}
+		if err := swap.Write(swapDir(), s); err != nil { Log.Error("swap write", err.Error()); continue }
+		e.swapped[b.AbsoluteFilePath] = version
+		changed = true
+	}
+	return changed
+}
+
+// removes the swap written by this editor, leftovers of crashed editors stay
+func (e *Editor) removeSwap(file string) {
+	if _, found := e.swapped[file]; !found { return }
+	swap.Remove(swapDir(), file)
+	delete(e.swapped, file)
+}
+
+// offers to restore, diff or discard every swap left by crashed editor
+func (e *Editor) OnSwapRecovery() {
+	for _, s := range swap.Leftovers(swapDir()) {
+		// file is decoded, so its encoding and line endings do not count as changes
+		text, err := readText(s.File)
+		if err == nil && text == s.Content { swap.Remove(swapDir(), s.File); continue } // saved already
+
+		e.recoverSwap(s, text)
+	}
+}
+
+func (e *Editor) recoverSwap(s swap.Swap, fileContent string) {
+	for {
+		e.Screen.Clear()
+		age := time.Since(s.Time).Round(time.Second).String()
+		e.Drawtext("Not saved changes found, written "+age+" ago by crashed editor:", 1, 0)
+		e.Drawtext(s.File, 1, 1)
+		e.Drawtext("r - restore, d - diff, x - discard, s - skip (ask next time)", 1, 3)
+		e.Screen.Show()
+
+		ev, ok := e.PollEvent().(*EventKey)
+		if !ok || ev.Key() != KeyRune { continue }
+		switch ev.Rune() {
+		case 'r': e.restoreSwap(s); return
+		case 'd': e.showSwapDiff(s, fileContent)
+		case 'x': swap.Remove(swapDir(), s.File); return
+		case 's': return
+		}
+	}
+}
+
+// opens the file with swap content, it stays not saved until the next save
+func (e *Editor) restoreSwap(s swap.Swap) {
+	if err := e.OpenFile(s.File); err != nil { Log.Error("swap restore", err.Error()); return }
+	e.finishLoading() // lines of a large file read later would be appended to the swap content
+	if !e.IsFullyLoaded { Log.Error("swap restore", s.File, "is not read"); return }
+
+	e.Content = [][]rune{}
+	for _, line := range strings.Split(s.Content, "\n") { e.Content = append(e.Content, []rune(line)) }
+	e.UndoTree = undo.New()
+	e.Row, e.Col, e.Y, e.X = 0, 0, 0, 0
+	e.IsContentChanged = true
+	e.syncText() // piece table, syntax tree and language server get the swap content
+	e.FindTests()
+	e.storeBuffer()
+
+	swap.Remove(swapDir(), s.File)
+	e.WriteSwaps()
+}
+
+func (e *Editor) showSwapDiff(s swap.Swap, fileContent string) {
+	lines := swap.Diff(fileContent+"\n", s.Content+"\n")
+	offset := 0
+	for {
+		e.Screen.Clear()
+		e.Drawtext("Diff of file (-) and not saved changes (+), escape to return", 1, 0)
+		for i := 0; i+2 < e.TERMINAL_HEIGHT && offset+i < len(lines); i++ {
+			style := StyleDefault
+			line := lines[offset+i]
+			if strings.HasPrefix(line, "+") { style = style.Foreground(ColorGreen) }
+			if strings.HasPrefix(line, "-") { style = style.Foreground(ColorRed) }
+			for j, ch := range []rune(line) { e.Screen.SetContent(1+j, i+2, ch, nil, style) }
+		}
+		e.Screen.Show()
+
+		ev, ok := e.PollEvent().(*EventKey)
+		if !ok { continue }
+		page := Max(e.TERMINAL_HEIGHT-3, 1)
+		switch ev.Key() {
+		case KeyEscape: return
+		case KeyDown: offset = Min(offset+1, Max(len(lines)-1, 0))
+		case KeyUp: offset = Max(offset-1, 0)
+		case KeyPgDn: offset = Min(offset+page, Max(len(lines)-1, 0))
+		case KeyPgUp: offset = Max(offset-page, 0)
+		}
+	}
+}
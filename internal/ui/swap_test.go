@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"edgo/internal/swap"
+	. "github.com/gdamore/tcell"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSwapOfSavedFile(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	dir := t.TempDir()
+
+	saved := map[string]string{
+		"crlf.txt":  "one\r\ntwo\r\n",
+		"bom.txt":   "\xEF\xBB\xBFone\ntwo\n",
+		"noeol.txt": "one\ntwo",
+		"latin.txt": "caf\xe9\ntwo\n",
+	}
+	for name, data := range saved {
+		file := filepath.Join(dir, name)
+		os.WriteFile(file, []byte(data), 0644)
+		content := "one\ntwo"
+		if name == "latin.txt" { content = "café\ntwo" }
+		swap.Write(swapDir(), swap.Swap{File: file, Content: content})
+	}
+
+	e := testEditor("")
+	e.OnSwapRecovery() // asks nothing, every swap is saved already
+	if swaps := swap.Leftovers(swapDir()); len(swaps) != 0 { t.Errorf("swaps of saved files should be removed, got %d", len(swaps)) }
+
+	text, _ := readText(filepath.Join(dir, "crlf.txt"))
+	if text != "one\ntwo" { t.Errorf("unexpected decoded text %q", text) }
+}
+
+func TestRestoreSwapOfLargeFile(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	file := filepath.Join(t.TempDir(), "large.txt")
+	os.WriteFile(file, []byte(strings.Repeat("line\n", loadChunkLines*3)), 0644)
+
+	e := testEditor("")
+	e.Config.LargeFile.Size, e.Config.LargeFile.HighlightLines = 100, 100
+	e.restoreSwap(swap.Swap{File: file, Content: "restored\ntext"})
+
+	if content(e) != "restored\ntext" || e.Text.String() != "restored\ntext" { t.Errorf("swap content should replace the whole file, got %d lines", len(e.Content)) }
+	if !e.IsFullyLoaded || e.loader != nil || !e.IsContentChanged { t.Errorf("restored buffer should be loaded and not saved") }
+	e.applyLoadedLines()
+	if len(e.Content) != 2 { t.Errorf("lines read later should not be appended, got %d lines", len(e.Content)) }
+}
+
+func TestSwapOfClosedBuffer(t *testing.T) {
+	t.Setenv("EDGO_STATE", t.TempDir())
+	file := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(file, []byte("one\n"), 0644)
+
+	e := testEditor("")
+	e.Lang = ""
+	e.Config.LargeFile.Size = 1 << 20
+	e.OpenFile(file)
+	e.Content[0] = []rune("changed")
+	e.IsContentChanged = true
+	e.UndoTree.Push(nil)
+
+	if !e.WriteSwaps() { t.Errorf("swap of the changed buffer should be written") }
+	if e.WriteSwaps() { t.Errorf("not changed swap should not be written again") }
+	if _, err := os.Stat(swap.Path(swapDir(), file)); err != nil { t.Fatalf("swap should exist: %v", err) }
+
+	e.macroQueue = []Event{NewEventKey(KeyRune, 'n', ModNone), NewEventKey(KeyEnter, 0, ModNone)}
+	e.CloseBuffer(0)
+	if _, err := os.Stat(swap.Path(swapDir(), file)); err == nil { t.Errorf("swap of the closed buffer should be removed") }
+}
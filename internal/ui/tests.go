@@ -4,7 +4,6 @@ import (
 	. "edgo/internal/highlighter"
 	"edgo/internal/process"
 	. "edgo/internal/tests"
-	"edgo/internal/utils"
 	"github.com/gdamore/tcell"
 	sitter "github.com/smacker/go-tree-sitter"
 	"os"
@@ -25,10 +24,9 @@ func (e *Editor) FindTests() {
 		e.TestFinder.TestQuery = q
 	}
 
-	if e.Test == nil { return }
-	codeBytes := []byte(utils.ConvertContentToString(e.Content))
+	if e.Test == nil || e.NoHighlight { return }
 	rootNode := e.treeSitterHighlighter.GetTree().RootNode()
-	e.Tests = e.Test.Find(&e.TestFinder, rootNode, e.AbsoluteFilePath, codeBytes)
+	e.Tests = e.Test.Find(&e.TestFinder, rootNode, e.AbsoluteFilePath, e.Text)
 }
 
 
@@ -40,7 +38,7 @@ func (e *Editor) RunTest(test TestData) {
 	//if e.Lang == "" || e.langConf.Cmd == "" { return }
 
 	if e.ProcessPanelHeight == 0 {
-		e.ProcessPanelHeight = 10
+		e.ProcessPanelHeight = e.openedProcessPanelHeight()
 		e.COLUMNS, e.ROWS = e.Screen.Size()
 		e.ROWS -= e.ProcessPanelHeight
 	}
@@ -0,0 +1,119 @@
+package ui
+
+import (
+	. "edgo/internal/lsp"
+	"edgo/internal/text"
+	sitter "github.com/smacker/go-tree-sitter"
+	"strings"
+)
+
+/*
+	e.Content is what the editor operates on, e.Text is the same document kept as piece table.
+	Every small edit of e.Content goes through insertText/deleteText, so the piece table,
+	the syntax tree and the language server are updated incrementally, without serializing the whole file.
+	Bulk edits (paste, undo, refactoring) call UpdateColors, which replaces only the changed lines of the text.
+*/
+
+// mirrors inserting s at (row, col) of e.Content
+func (e *Editor) insertText(row, col int, s string) {
+	if e.Text == nil || len(s) == 0 { return }
+	e.shiftFolds(row, strings.Count(s, "\n"))
+	e.shiftMarks(row, col, strings.Count(s, "\n"))
+	start := e.Text.Offset(row, col)
+	startPoint := e.textPoint(start)
+
+	e.Text.Insert(start, []byte(s))
+	end := start + len(s)
+
+	position := Character{ Line: row, Character: col }
+	e.didChange(&ContentChange{ Range: &ChangeRange{ Start: position, End: position }, Text: s })
+	if e.NoHighlight { return }
+
+	e.treeSitterHighlighter.Edit(sitter.EditInput{
+		StartIndex: uint32(start), OldEndIndex: uint32(start), NewEndIndex: uint32(end),
+		StartPoint: startPoint, OldEndPoint: startPoint, NewEndPoint: e.textPoint(end),
+	})
+	e.treeSitterHighlighter.ParseSource(e.Text)
+}
+
+// mirrors deleting s from (row, col) of e.Content
+func (e *Editor) deleteText(row, col int, s string) {
+	if e.Text == nil || len(s) == 0 { return }
+	e.shiftFolds(row, -strings.Count(s, "\n"))
+	e.shiftMarks(row, col, -strings.Count(s, "\n"))
+	start := e.Text.Offset(row, col)
+	end := start + len(s)
+	startPoint, oldEndPoint := e.textPoint(start), e.textPoint(end)
+	endRow, endCol := e.Text.Position(end)
+
+	e.Text.Delete(start, len(s))
+
+	e.didChange(&ContentChange{ Range: &ChangeRange{
+		Start: Character{ Line: row, Character: col },
+		End:   Character{ Line: endRow, Character: endCol },
+	}})
+	if e.NoHighlight { return }
+
+	e.treeSitterHighlighter.Edit(sitter.EditInput{
+		StartIndex: uint32(start), OldEndIndex: uint32(end), NewEndIndex: uint32(start),
+		StartPoint: startPoint, OldEndPoint: oldEndPoint, NewEndPoint: startPoint,
+	})
+	e.treeSitterHighlighter.ParseSource(e.Text)
+}
+
+// builds text storage from e.Content of a just read file
+func (e *Editor) resetText() {
+	e.Text = text.NewFromLines(e.Content)
+	e.didChange(nil)
+}
+
+// mirrors bulk changes of e.Content, only the lines between equal beginning and ending lines are replaced
+func (e *Editor) syncText() {
+	if e.Text == nil { e.resetText(); return }
+	count := e.Text.LineCount()
+	first := 0
+	for first < len(e.Content) && first < count && e.Text.LineEquals(first, e.Content[first]) { first++ }
+	if first == len(e.Content) && first == count { return }
+	last, lastText := len(e.Content)-1, count-1
+	for last >= first && lastText >= first && e.Text.LineEquals(lastText, e.Content[last]) { last--; lastText-- }
+
+	// lines first..lastText of the text become lines first..last of the content
+	lines := []string{}
+	for _, line := range e.Content[first : last+1] { lines = append(lines, string(line)) }
+	replaced := strings.Join(lines, "\n")
+	start, end := e.Text.LineStart(first), e.Text.LineStart(lastText+1)
+	switch {
+	case lastText+1 < count: if last >= first { replaced += "\n" } // lines are followed by a line break
+	case last < first && first > 0: start-- // last lines are deleted with the line break before them
+	case lastText < first && first > 0: replaced = "\n" + replaced // lines are added at the end
+	}
+
+	startRow, startCol := e.Text.Position(start)
+	endRow, endCol := e.Text.Position(end)
+	startPoint, oldEndPoint := e.textPoint(start), e.textPoint(end)
+	e.Text.Delete(start, end-start)
+	e.Text.Insert(start, []byte(replaced))
+
+	e.didChange(&ContentChange{ Range: &ChangeRange{
+		Start: Character{ Line: startRow, Character: startCol },
+		End:   Character{ Line: endRow, Character: endCol },
+	}, Text: replaced })
+	if e.NoHighlight { return }
+
+	e.treeSitterHighlighter.Edit(sitter.EditInput{
+		StartIndex: uint32(start), OldEndIndex: uint32(end), NewEndIndex: uint32(start + len(replaced)),
+		StartPoint: startPoint, OldEndPoint: oldEndPoint, NewEndPoint: e.textPoint(start + len(replaced)),
+	})
+	e.treeSitterHighlighter.ParseSource(e.Text)
+}
+
+// full parse of the text, or of nothing if highlighting is off
+func (e *Editor) parseText() {
+	if e.NoHighlight { e.treeSitterHighlighter.ReParseSource(text.New(nil)); return }
+	e.treeSitterHighlighter.ReParseSource(e.Text)
+}
+
+func (e *Editor) textPoint(offset int) sitter.Point {
+	row, col := e.Text.Point(offset)
+	return sitter.Point{Row: uint32(row), Column: uint32(col)}
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	. "edgo/internal/highlighter"
+	. "edgo/internal/io"
+	. "edgo/internal/lsp"
+	"edgo/internal/text"
+	"edgo/internal/undo"
+	. "github.com/gdamore/tcell"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// editor with go code on a simulation screen
+func testEditor(code string) *Editor {
+	s := NewSimulationScreen("")
+	s.Init()
+	s.SetSize(60, 12)
+	e := &Editor{Screen: s}
+	e.FileWatcher = NewFileWatcher(100000)
+	e.UndoTree = undo.New()
+	e.lsp2lang = map[string]*LspClient{}
+	e.Keymap, _ = NewKeymap(nil)
+	e.COLUMNS, e.ROWS, e.TERMINAL_HEIGHT = 60, 12, 12
+	e.BufferIndex = -1
+	e.LINES_WIDTH = 6
+	e.Lang, e.Filename = "go", "a.go"
+	e.langConf.Comment = "//"
+	e.langTabWidth = 4
+	e.Selection = emptySelection()
+	for _, line := range strings.Split(code, "\n") { e.Content = append(e.Content, []rune(line)) }
+	e.Text = text.NewFromLines(e.Content)
+	e.treeSitterHighlighter = NewTreeSitter()
+	e.treeSitterHighlighter.SetLang("go")
+	e.parseText()
+	e.IsFullyLoaded = true
+	return e
+}
+
+func content(e *Editor) string {
+	lines := []string{}
+	for _, line := range e.Content { lines = append(lines, string(line)) }
+	return strings.Join(lines, "\n")
+}
+
+func TestSyncText(t *testing.T) {
+	e := testEditor("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}")
+	random := rand.New(rand.NewSource(1))
+	words := []string{"", "x", "func f() {}", "// comment", "\"str", "}", "{", "var ы = 1"}
+
+	for i := 0; i < 300; i++ {
+		row := random.Intn(len(e.Content))
+		word := []rune(words[random.Intn(len(words))])
+		switch random.Intn(5) {
+		case 0: e.Content = append(e.Content[:row], append([][]rune{word}, e.Content[row:]...)...)
+		case 1: e.Content = append(e.Content, word)
+		case 2: if len(e.Content) > 1 { e.Content = append(e.Content[:row], e.Content[row+1:]...) }
+		case 3: if len(e.Content) > 1 { e.Content = e.Content[:len(e.Content)-1] }
+		case 4: e.Content[row] = append(append([]rune{}, e.Content[row]...), word...)
+		}
+		e.syncText()
+
+		if e.Text.String() != content(e) { t.Fatalf("step %d: text %q, expected %q", i, e.Text.String(), content(e)) }
+		parsed := NewTreeSitter()
+		parsed.SetLang("go")
+		parsed.ReParseSource(e.Text)
+		if e.treeSitterHighlighter.GetTree().RootNode().String() != parsed.GetTree().RootNode().String() { t.Fatalf("step %d: syntax tree differs from the full parse", i) }
+	}
+}
+
+func TestTrimTrailingWhitespace(t *testing.T) {
+	e := testEditor("package main \n\nfunc main() {\t\n\tprintln(\"hi\")  \n}")
+	e.editorConfig = map[string]string{"trim_trailing_whitespace": "true"}
+	e.Row, e.Col = 3, 16
+	e.trimTrailingWhitespace()
+
+	expected := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}"
+	if content(e) != expected || e.Text.String() != expected { t.Fatalf("content %q, text %q, expected %q", content(e), e.Text.String(), expected) }
+	if e.Row != 3 || e.Col != 14 { t.Errorf("cursor should move to the line end, got %d:%d", e.Row, e.Col) }
+
+	e.OnUndo()
+	if content(e) != "package main \n\nfunc main() {\t\n\tprintln(\"hi\")  \n}" { t.Errorf("one undo should restore all lines, got %q", content(e)) }
+}
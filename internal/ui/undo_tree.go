@@ -0,0 +1,162 @@
+package ui
+
+import (
+	. "edgo/internal/operations"
+	. "edgo/internal/utils"
+	"fmt"
+	. "github.com/gdamore/tcell"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	Undo tree navigation. Earlier and later take steps in time ("10") or duration ("5m", "1h30m"),
+	so states from undone branches are reached too. Tree panel previews the buffer at the selected state,
+	enter keeps it, escape returns to the state it was opened at.
+*/
+
+func (e *Editor) OnUndoEarlier() { e.undoTimeTravel(" earlier (steps or 5m): ", -1) }
+func (e *Editor) OnUndoLater()   { e.undoTimeTravel(" later (steps or 5m): ", 1) }
+
+func (e *Editor) undoTimeTravel(prompt string, direction int) {
+	input, ok := e.inputPrompt(prompt)
+	if !ok || input == "" { return }
+
+	tree := e.UndoTree
+	target := tree.Current
+	if steps, err := strconv.Atoi(input); err == nil {
+		target = Max(Min(tree.Current+direction*steps, len(tree.Nodes)-1), 0)
+	} else if duration, err := time.ParseDuration(input); err == nil {
+		target = tree.AtTime(tree.Nodes[tree.Current].Time.Add(time.Duration(direction) * duration))
+	} else {
+		return
+	}
+
+	e.gotoUndoState(target)
+	e.UpdateNeeded()
+}
+
+// moves buffer to the state of node by undoing and redoing operations on the way
+func (e *Editor) gotoUndoState(node int) {
+	if node == e.UndoTree.Current { return }
+	undoOps, redoOps := e.UndoTree.Route(node)
+	for _, ops := range undoOps { e.revertOperation(ops) }
+	for _, ops := range redoOps { e.applyOperation(ops) }
+	e.UndoTree.Goto(node)
+
+	if e.Row >= len(e.Content) { e.Row = len(e.Content) - 1 }
+	if e.Col > len(e.Content[e.Row]) { e.Col = len(e.Content[e.Row]) }
+	e.Selection.CleanSelection()
+	e.Focus()
+	e.UpdateColors()
+}
+
+func (e *Editor) OnUndoTree() {
+	e.IsOverlay = true
+	defer e.OverlayFalse()
+
+	start := e.UndoTree.Current
+	redo := []int{} // redo ways of the opened state, previews change them
+	for _, node := range e.UndoTree.Nodes { redo = append(redo, node.Redo) }
+
+	nodes, options := e.undoTreeRows()
+	selected, selectedOffset := 0, 0
+	for i, node := range nodes {
+		if node == start { selected = i }
+	}
+
+	for {
+		height := MinMany(20, len(options), e.ROWS-2)
+		if selected < selectedOffset { selectedOffset = selected }
+		if selected >= selectedOffset+height { selectedOffset = selected - height + 1 }
+
+		width := MaxString(options) + 2
+		atx := Max(e.COLUMNS-width-1, e.FilesPanelWidth+e.LINES_WIDTH)
+		e.DrawEverything()
+		e.drawCompletion(atx, 1, height, width, options, selected, selectedOffset, StyleDefault)
+		prefix := []rune(" undo tree, enter to keep")
+		for i := 0; i < width; i++ {
+			ch := ' '
+			if i < len(prefix) { ch = prefix[i] }
+			e.Screen.SetContent(atx+i, 0, ch, nil, StyleDefault)
+		}
+		e.Screen.Show()
+
+		switch ev := e.PollEvent().(type) {
+		case *EventResize:
+			e.COLUMNS, e.ROWS = e.Screen.Size()
+			e.ROWS -= e.ProcessPanelHeight
+
+		case *EventKey:
+			switch ev.Key() {
+			case KeyEscape:
+				e.gotoUndoState(start)
+				for i, r := range redo { e.UndoTree.Nodes[i].Redo = r }
+				return
+			case KeyEnter:
+				if e.UndoTree.Current != start { e.UpdateNeeded() }
+				return
+			case KeyDown: selected = Min(len(options)-1, selected+1)
+			case KeyUp: selected = Max(0, selected-1)
+			default: continue
+			}
+			e.gotoUndoState(nodes[selected])
+			nodes, options = e.undoTreeRows() // the current state mark follows the preview
+		}
+	}
+}
+
+// nodes in tree order with their descriptions, branch is indented from its parent
+func (e *Editor) undoTreeRows() ([]int, []string) {
+	tree := e.UndoTree
+	nodes, options := []int{}, []string{}
+
+	var walk func(node, level int)
+	walk = func(node, level int) {
+		n := tree.Nodes[node]
+		mark := "o"
+		if node == tree.Current { mark = "@" }
+
+		description := "original"
+		if node != 0 {
+			inserted, deleted := 0, 0
+			for _, op := range n.Ops {
+				if op.Action == Insert || op.Action == Enter { inserted++ }
+				if op.Action == Delete || op.Action == DeleteLine { deleted++ }
+			}
+			description = fmt.Sprintf("#%d +%d -%d", node, inserted, deleted)
+		}
+		age := time.Since(n.Time).Round(time.Second).String() + " ago"
+
+		nodes = append(nodes, node)
+		options = append(options, fmt.Sprintf("%s%s %s  %s", strings.Repeat("  ", level), mark, description, age))
+
+		for i, child := range n.Children {
+			if i == 0 { walk(child, level) } else { walk(child, level+1) }
+		}
+	}
+	walk(0, 0)
+	return nodes, options
+}
+
+// reads a line of input at the top of the editor
+func (e *Editor) inputPrompt(prompt string) (string, bool) {
+	var input = []rune{}
+	for {
+		e.DrawEverything()
+		atx := e.FilesPanelWidth + e.LINES_WIDTH
+		text := []rune(prompt + string(input))
+		for i, ch := range text { e.Screen.SetContent(atx+i, 0, ch, nil, StyleDefault) }
+		e.Screen.ShowCursor(atx+len(text), 0)
+		e.Screen.Show()
+
+		ev, ok := e.PollEvent().(*EventKey)
+		if !ok { continue }
+		key := ev.Key()
+		if key == KeyEscape { return "", false }
+		if key == KeyEnter { return string(input), true }
+		if (key == KeyBackspace || key == KeyBackspace2) && len(input) > 0 { input = input[:len(input)-1] }
+		if key == KeyRune { input = append(input, ev.Rune()) }
+	}
+}
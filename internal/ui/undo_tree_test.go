@@ -0,0 +1,36 @@
+package ui
+
+import (
+	. "github.com/gdamore/tcell"
+	"testing"
+)
+
+func TestUndoTreePreview(t *testing.T) {
+	e := testEditor("")
+	e.Lang = "" // the tree is drawn over the buffer, diagnostics need a language server
+	e.AddChar('a')
+	e.AddChar('b')
+	e.OnUndo()
+	e.AddChar('c')
+	if content(e) != "ac" || len(e.UndoTree.Nodes) != 4 { t.Fatalf("expected branch with ac, got %q and %d states", content(e), len(e.UndoTree.Nodes)) }
+	current := e.UndoTree.Current
+	e.IsContentChanged = false
+	keys := func(keys ...*EventKey) {
+		e.macroQueue = nil
+		for _, key := range keys { e.macroQueue = append(e.macroQueue, key) }
+	}
+	up, enter, escape := NewEventKey(KeyUp, 0, ModNone), NewEventKey(KeyEnter, 0, ModNone), NewEventKey(KeyEscape, 0, ModNone)
+
+	// escape returns to the opened state, typed keys do not move
+	keys(up, NewEventKey(KeyRune, 'x', ModNone), NewEventKey(KeyPgDn, 0, ModNone), escape)
+	e.OnUndoTree()
+	if content(e) != "ac" || e.UndoTree.Current != current { t.Errorf("escape should cancel the preview, got %q at %d", content(e), e.UndoTree.Current) }
+	if e.UndoTree.Nodes[1].Redo != current { t.Errorf("redo should follow the opened state, got %d", e.UndoTree.Nodes[1].Redo) }
+	if e.IsContentChanged { t.Errorf("cancelled preview should not change content") }
+
+	// enter keeps the previewed state
+	keys(up, up, NewEventKey(KeyRune, 'x', ModNone), enter)
+	e.OnUndoTree()
+	if content(e) != "a" || e.UndoTree.Current != 1 { t.Errorf("enter should keep the selected state, got %q at %d", content(e), e.UndoTree.Current) }
+	if !e.IsContentChanged { t.Errorf("kept state should change content") }
+}
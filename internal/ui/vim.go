@@ -0,0 +1,481 @@
+package ui
+
+import (
+	. "edgo/internal/operations"
+	. "edgo/internal/utils"
+	. "github.com/gdamore/tcell"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+	Optional modal editing, enabled by `vim: true` in config.
+	Normal and visual modes parse keys into commands: [count] operator [count] motion,
+	[count] command or text object after an operator. Insert mode is the regular editor,
+	all typed text of one insert is merged into one undo step together with the command started it.
+	Every change is recorded as the keys pressed, `.` replays them.
+*/
+
+type VimMode int
+
+const (
+	VimNormal VimMode = iota
+	VimInsert
+	VimVisual
+	VimVisualLine
+)
+
+type vimState struct {
+	mode       VimMode
+	pending    []rune     // keys of unfinished command, like "2d" or "ci"
+	register   string     // last deleted or yanked text
+	linewise   bool       // register holds whole lines
+	anchor     vimPos     // visual mode start
+	undoStart  int        // undo stack size when the change started
+	isChange   bool       // current command changes content and has to be recorded for repeat
+	keys       []*EventKey // keys of the current command
+	lastChange []*EventKey // keys of the last change, replayed by `.`
+	replaying  bool
+}
+
+type vimPos struct {
+	row int
+	col int
+}
+
+// range of operator, end is exclusive, linewise ranges take whole rows start.row..end.row
+type vimRange struct {
+	start    vimPos
+	end      vimPos
+	linewise bool
+}
+
+// handles keys in vim mode, returns false if the key has to be processed by regular editor
+func (e *Editor) HandleVimKeyboard(key Key, ev *EventKey, modifiers ModMask) bool {
+	if !e.Config.Vim || len(e.Content) == 0 { return false }
+
+	if !e.vim.replaying {
+		if e.vim.mode == VimNormal && len(e.vim.pending) == 0 { e.vim.keys = nil; e.vim.isChange = false }
+		e.vim.keys = append(e.vim.keys, ev)
+	}
+
+	if e.vim.mode == VimInsert {
+		if key != KeyEscape { return false }
+		e.vimNormalMode()
+		if e.Col > 0 { e.Col-- }
+		e.vimCommandDone()
+		return true
+	}
+
+	ch := rune(0)
+	switch {
+	case key == KeyRune && modifiers&(ModCtrl|ModAlt) == 0: ch = ev.Rune()
+	case key == KeyLeft && modifiers == 0, key == KeyBackspace, key == KeyBackspace2: ch = 'h'
+	case key == KeyRight && modifiers == 0: ch = 'l'
+	case key == KeyUp && modifiers == 0: ch = 'k'
+	case key == KeyDown && modifiers == 0, key == KeyEnter: ch = 'j'
+	case key == KeyCtrlR: e.OnRedo(); e.vimCommandDone(); return true
+	case key == KeyEscape:
+		if len(e.vim.pending) == 0 && e.vim.mode != VimNormal { e.vimNormalMode() }
+		e.vim.pending = nil
+		e.vimCommandDone()
+		return true
+	default:
+		// not a vim key, regular editor shortcuts still work
+		if len(e.vim.pending) == 0 && e.vim.mode == VimNormal { e.vim.keys = nil }
+		return false
+	}
+
+	e.vim.pending = append(e.vim.pending, ch)
+	e.vimExecute()
+	e.vimCommandDone()
+	return true
+}
+
+func (e *Editor) vimNormalMode() {
+	if e.vim.mode == VimInsert { e.UndoTree.MergeSince(e.vim.undoStart) } // the whole insert is one undo step
+	if e.vim.mode == VimVisual || e.vim.mode == VimVisualLine { e.Selection.CleanSelection() }
+	e.vim.mode = VimNormal
+}
+
+func (e *Editor) vimInsertMode() {
+	e.vim.mode = VimInsert
+	e.vim.isChange = true
+	e.Selection.CleanSelection()
+}
+
+// called after every key, remembers finished change and keeps the cursor valid
+func (e *Editor) vimCommandDone() {
+	if e.vim.mode == VimNormal && len(e.vim.pending) == 0 {
+		if e.vim.isChange && !e.vim.replaying { e.vim.lastChange = e.vim.keys }
+		e.vim.isChange = false
+	}
+
+	if e.Row >= len(e.Content) { e.Row = len(e.Content) - 1 }
+	if e.Row < 0 { e.Row = 0 }
+	if e.vim.mode != VimInsert && e.Col >= len(e.Content[e.Row]) { e.Col = Max(len(e.Content[e.Row])-1, 0) }
+	if e.vim.mode == VimVisual || e.vim.mode == VimVisualLine { e.vimUpdateVisual() }
+
+	if e.Row < e.Y { e.Y = e.Row }
+	if e.Row >= e.Y+e.ROWS { e.Y = e.Row - e.ROWS + 1 }
+	e.Update = true
+}
+
+// parses pending keys and executes the command if it is complete
+func (e *Editor) vimExecute() {
+	keys := e.vim.pending
+	count, keys := vimCount(keys)
+	if len(keys) == 0 { return }
+
+	done := true
+	isVisual := e.vim.mode == VimVisual || e.vim.mode == VimVisualLine
+
+	switch cmd := keys[0]; {
+	case strings.ContainsRune("dcy", cmd) && !isVisual:
+		done = e.vimOperator(cmd, count, keys[1:])
+	case strings.ContainsRune("dxcsyY", cmd) && isVisual:
+		e.vimVisualOperator(cmd)
+	case cmd == 'r':
+		if len(keys) < 2 { return }
+		e.vimReplace(keys[1], count)
+	default:
+		done = e.vimCommand(cmd, count, keys[1:])
+	}
+	if done { e.vim.pending = nil }
+}
+
+// splits leading count, "0" alone is a motion
+func vimCount(keys []rune) (int, []rune) {
+	i := 0
+	for i < len(keys) && unicode.IsDigit(keys[i]) && !(i == 0 && keys[i] == '0') { i++ }
+	if i == 0 { return 0, keys }
+	count, _ := strconv.Atoi(string(keys[:i]))
+	return count, keys[i:]
+}
+
+// operator with motion or text object, returns false if keys are incomplete
+func (e *Editor) vimOperator(operator rune, count int, keys []rune) bool {
+	motionCount, keys := vimCount(keys)
+	if len(keys) == 0 { return false }
+	if count > 0 || motionCount > 0 { count = Max(count, 1) * Max(motionCount, 1) } // no count is not 1 for G
+
+	cursor := vimPos{e.Row, e.Col}
+	var r vimRange
+
+	if keys[0] == operator { // dd, cc, yy
+		r = vimRange{cursor, vimPos{Min(e.Row+Max(count, 1)-1, len(e.Content)-1), 0}, true}
+	} else if keys[0] == 'i' || keys[0] == 'a' {
+		if len(keys) < 2 { return false }
+		var ok bool
+		if r, ok = e.vimTextObject(keys[0], keys[1], cursor); !ok { return true }
+	} else {
+		motion := keys[0]
+		if operator == 'c' && (motion == 'w' || motion == 'W') { // cw works as ce
+			motion += 'e' - 'w'
+		}
+		target, kind, complete := e.vimMotion(motion, keys[1:], count, true)
+		if !complete { return false }
+		if kind == vimNoMotion { return true }
+
+		if (motion == 'w' || motion == 'W') && target.row > cursor.row { // dw stops at the end of line
+			target = vimPos{cursor.row, len(e.Content[cursor.row])}
+		}
+		r = vimRange{cursor, target, kind == vimLinewise}
+		if vimLess(target, cursor) { r.start, r.end = target, cursor }
+		if kind == vimInclusive { r.end.col++ }
+	}
+
+	e.vimApply(operator, r)
+	return true
+}
+
+func (e *Editor) vimApply(operator rune, r vimRange) {
+	if r.linewise && r.start.row > r.end.row { r.start.row, r.end.row = r.end.row, r.start.row }
+	e.vimYank(r)
+	if operator == 'y' {
+		e.Row, e.Col = r.start.row, r.start.col
+		if r.linewise { e.Col = 0 }
+		return
+	}
+
+	e.vim.isChange = true
+	e.vim.undoStart = e.UndoTree.Current
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+
+	if r.linewise && operator == 'c' { // keep an empty line to type into
+		e.vimDelete(vimPos{r.start.row, 0}, vimPos{r.end.row, len(e.Content[r.end.row])}, &ops)
+	} else if r.linewise {
+		e.vimDeleteLines(r.start.row, r.end.row, &ops)
+	} else {
+		e.vimDelete(r.start, r.end, &ops)
+	}
+	e.vimFinishEdit(ops)
+
+	if operator == 'c' { e.vimInsertMode() }
+}
+
+// stores text of the range into the register and the clipboard
+func (e *Editor) vimYank(r vimRange) {
+	if r.linewise {
+		lines := []string{}
+		for row := r.start.row; row <= r.end.row && row < len(e.Content); row++ { lines = append(lines, string(e.Content[row])) }
+		e.vim.register = strings.Join(lines, "\n")
+	} else {
+		e.vim.register = e.vimText(r.start, r.end)
+	}
+	e.vim.linewise = r.linewise
+	e.copyText(e.vim.register)
+}
+
+func (e *Editor) vimText(start, end vimPos) string {
+	var sb strings.Builder
+	for row := start.row; row <= end.row && row < len(e.Content); row++ {
+		line := e.Content[row]
+		from, to := 0, len(line)
+		if row == start.row { from = Min(start.col, len(line)) }
+		if row == end.row { to = Min(end.col, len(line)) }
+		if from < to { sb.WriteString(string(line[from:to])) }
+		if row != end.row { sb.WriteRune('\n') }
+	}
+	return sb.String()
+}
+
+// deletes text from start to end going backward, as backspace does
+func (e *Editor) vimDelete(start, end vimPos, ops *EditOperation) {
+	end.col = Min(end.col, len(e.Content[end.row]))
+	row, col := end.row, end.col
+	for vimLess(vimPos{start.row, start.col}, vimPos{row, col}) {
+		if col > 0 {
+			*ops = append(*ops, Operation{Delete, e.Content[row][col-1], row, col - 1})
+			e.Content[row] = append(e.Content[row][:col-1], e.Content[row][col:]...)
+			col--
+		} else {
+			prevLen := len(e.Content[row-1])
+			*ops = append(*ops, Operation{DeleteLine, '\n', row - 1, prevLen})
+			e.shiftFolds(row-1, -1)
+			e.shiftMarks(row-1, prevLen, -1)
+			e.Content[row-1] = append(e.Content[row-1], e.Content[row]...)
+			e.Content = append(e.Content[:row], e.Content[row+1:]...)
+			row, col = row-1, prevLen
+		}
+	}
+	e.Row, e.Col = start.row, start.col
+}
+
+func (e *Editor) vimDeleteLines(top, bottom int, ops *EditOperation) {
+	bottom = Min(bottom, len(e.Content)-1)
+	if bottom+1 < len(e.Content) {
+		e.vimDelete(vimPos{top, 0}, vimPos{bottom + 1, 0}, ops)
+	} else if top > 0 {
+		e.vimDelete(vimPos{top - 1, len(e.Content[top-1])}, vimPos{bottom, len(e.Content[bottom])}, ops)
+		top--
+	} else {
+		e.vimDelete(vimPos{0, 0}, vimPos{bottom, len(e.Content[bottom])}, ops)
+	}
+	e.Row, e.Col = top, e.vimFirstNonBlank(top)
+}
+
+// inserts text at position, returns the position after it
+func (e *Editor) vimInsert(p vimPos, s string, ops *EditOperation) vimPos {
+	for _, ch := range s {
+		if ch == '\n' {
+			*ops = append(*ops, Operation{Enter, '\n', p.row, p.col})
+			e.shiftFolds(p.row, 1)
+			e.shiftMarks(p.row, p.col, 1)
+			after := append([]rune{}, e.Content[p.row][p.col:]...)
+			e.Content[p.row] = e.Content[p.row][:p.col]
+			e.Content = InsertTo(e.Content, p.row+1, after)
+			p = vimPos{p.row + 1, 0}
+		} else {
+			*ops = append(*ops, Operation{Insert, ch, p.row, p.col})
+			e.Content[p.row] = InsertTo(e.Content[p.row], p.col, ch)
+			p.col++
+		}
+	}
+	return p
+}
+
+func (e *Editor) vimFinishEdit(ops EditOperation) {
+	if len(ops) <= 1 { return }
+	e.UndoTree.Push(ops)
+	e.UpdateNeeded()
+}
+
+// commands without operator, returns false if keys are incomplete
+func (e *Editor) vimCommand(cmd rune, count int, rest []rune) bool {
+	n := Max(count, 1)
+	line := e.Content[e.Row]
+
+	if e.vim.mode == VimVisual || e.vim.mode == VimVisualLine {
+		switch cmd {
+		case 'o': e.vim.anchor, e.Row, e.Col = vimPos{e.Row, e.Col}, e.vim.anchor.row, e.vim.anchor.col
+		case 'v', 'V': e.vimVisualMode(cmd)
+		default: return e.vimMove(cmd, count, rest)
+		}
+		return true
+	}
+
+	switch cmd {
+	case 'i': e.vimStartInsert(e.Col)
+	case 'a': e.vimStartInsert(Min(e.Col+1, len(line)))
+	case 'I': e.vimStartInsert(e.vimFirstNonBlank(e.Row))
+	case 'A': e.vimStartInsert(len(line))
+	case 'o', 'O':
+		e.vim.undoStart = e.UndoTree.Current
+		ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+		if cmd == 'o' {
+			e.vimInsert(vimPos{e.Row, len(line)}, "\n", &ops)
+			e.Row++
+		} else {
+			e.vimInsert(vimPos{e.Row, 0}, "\n", &ops)
+		}
+		e.Col = 0
+		e.vimFinishEdit(ops)
+		e.vimInsertMode()
+	case 'x': e.vimOperator('d', n, []rune{'l'})
+	case 'X': if e.Col > 0 { e.vimOperator('d', n, []rune{'h'}) }
+	case 'D': e.vimOperator('d', n, []rune{'$'})
+	case 'C': e.vimOperator('c', n, []rune{'$'})
+	case 's': e.vimOperator('c', n, []rune{'l'})
+	case 'Y': e.vimOperator('y', n, []rune{'y'})
+	case 'p', 'P': e.vimPaste(cmd == 'p', n)
+	case 'J': e.vimJoin(n)
+	case 'u': for i := 0; i < n; i++ { e.OnUndo() }
+	case '.': e.vimRepeat(n)
+	case 'v', 'V': e.vimVisualMode(cmd)
+	case 'm':
+		if len(rest) == 0 { return false }
+		e.setMark(string(rest[0]))
+	case '\'', '`':
+		if len(rest) == 0 { return false }
+		e.loadMarks()
+		if mark, found := e.marks.Named(string(rest[0])); found && mark.File != e.AbsoluteFilePath { e.jumpToMark(mark); return true }
+		return e.vimMove(cmd, count, rest)
+	default: return e.vimMove(cmd, count, rest)
+	}
+	return true
+}
+
+// moves cursor by motion, returns false if keys are incomplete
+func (e *Editor) vimMove(motion rune, count int, rest []rune) bool {
+	target, kind, complete := e.vimMotion(motion, rest, count, false)
+	if !complete { return false }
+	if kind == vimNoMotion { return true }
+	e.Row, e.Col = target.row, target.col
+	e.OnCursorChanged()
+	return true
+}
+
+// enters visual mode, or leaves it if it is the same mode
+func (e *Editor) vimVisualMode(cmd rune) {
+	mode := VimVisual
+	if cmd == 'V' { mode = VimVisualLine }
+	if e.vim.mode == mode { e.vimNormalMode(); return }
+	if e.vim.mode == VimNormal { e.vim.anchor = vimPos{e.Row, e.Col} }
+	e.vim.mode = mode
+}
+
+func (e *Editor) vimStartInsert(col int) {
+	e.Col = col
+	e.vim.undoStart = e.UndoTree.Current
+	e.vimInsertMode()
+}
+
+func (e *Editor) vimReplace(ch rune, count int) {
+	n := Max(count, 1)
+	if e.Col+n > len(e.Content[e.Row]) { return }
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+	e.vimDelete(vimPos{e.Row, e.Col}, vimPos{e.Row, e.Col + n}, &ops)
+	end := e.vimInsert(vimPos{e.Row, e.Col}, strings.Repeat(string(ch), n), &ops)
+	e.Row, e.Col = end.row, end.col-1
+	e.vim.isChange = true
+	e.vimFinishEdit(ops)
+}
+
+func (e *Editor) vimPaste(after bool, count int) {
+	if e.vim.register == "" && !e.vim.linewise { return } // an empty line is still pasted
+	text := strings.Repeat(e.vim.register+"\n", count)
+	text = text[:len(text)-1]
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+
+	if e.vim.linewise {
+		if after {
+			e.vimInsert(vimPos{e.Row, len(e.Content[e.Row])}, "\n"+text, &ops)
+			e.Row++
+		} else {
+			e.vimInsert(vimPos{e.Row, 0}, text+"\n", &ops)
+		}
+		e.Col = e.vimFirstNonBlank(e.Row)
+	} else {
+		col := e.Col
+		if after && len(e.Content[e.Row]) > 0 { col++ }
+		end := e.vimInsert(vimPos{e.Row, col}, text, &ops)
+		e.Row, e.Col = end.row, Max(end.col-1, 0)
+	}
+	e.vim.isChange = true
+	e.vimFinishEdit(ops)
+}
+
+// joins count lines with the next one, separated by a space
+func (e *Editor) vimJoin(count int) {
+	ops := EditOperation{{MoveCursor, ' ', e.Row, e.Col}}
+	for i := 0; i < Max(count-1, 1) && e.Row+1 < len(e.Content); i++ {
+		end := len(e.Content[e.Row])
+		next := e.vimFirstNonBlank(e.Row + 1)
+		e.vimDelete(vimPos{e.Row, end}, vimPos{e.Row + 1, next}, &ops)
+		if end > 0 && len(e.Content[e.Row]) > end { e.vimInsert(vimPos{e.Row, end}, " ", &ops) }
+		e.Col = end
+	}
+	e.vim.isChange = true
+	e.vimFinishEdit(ops)
+}
+
+func (e *Editor) vimRepeat(count int) {
+	keys := e.vim.lastChange
+	if len(keys) == 0 { return }
+	e.vim.pending = nil
+	e.vim.replaying = true
+	for i := 0; i < count; i++ {
+		for _, ev := range keys { e.HandleKeyboard(ev.Key(), ev, ev.Modifiers()) }
+	}
+	e.vim.replaying = false
+}
+
+func (e *Editor) vimVisualOperator(cmd rune) {
+	start, end := e.vim.anchor, vimPos{e.Row, e.Col}
+	if vimLess(end, start) { start, end = end, start }
+	r := vimRange{start, vimPos{end.row, end.col + 1}, e.vim.mode == VimVisualLine || cmd == 'Y'}
+
+	operator := map[rune]rune{'d': 'd', 'x': 'd', 'c': 'c', 's': 'c', 'y': 'y', 'Y': 'y'}[cmd]
+	e.vimNormalMode()
+	e.vimApply(operator, r)
+}
+
+func (e *Editor) vimUpdateVisual() {
+	start, end := e.vim.anchor, vimPos{e.Row, e.Col}
+	if vimLess(end, start) { start, end = end, start }
+	if e.vim.mode == VimVisualLine {
+		start.col, end.col = 0, len(e.Content[end.row])-1
+	}
+	e.Selection.Ssx, e.Selection.Ssy = start.col, start.row
+	e.Selection.Sex, e.Selection.Sey = end.col+1, end.row
+	e.Selection.IsSelected = true
+}
+
+func (e *Editor) vimStatus() string {
+	if !e.Config.Vim { return "" }
+	mode := map[VimMode]string{VimNormal: "NORMAL", VimInsert: "INSERT", VimVisual: "VISUAL", VimVisualLine: "V-LINE"}[e.vim.mode]
+	return " " + mode + " " + string(e.vim.pending)
+}
+
+func vimLess(a, b vimPos) bool {
+	return a.row < b.row || a.row == b.row && a.col < b.col
+}
+
+func (e *Editor) vimFirstNonBlank(row int) int {
+	for i, ch := range e.Content[row] {
+		if ch != ' ' && ch != '\t' { return i }
+	}
+	return 0
+}
@@ -0,0 +1,257 @@
+package ui
+
+import (
+	. "edgo/internal/utils"
+	"unicode"
+)
+
+type vimMotionKind int
+
+const (
+	vimNoMotion vimMotionKind = iota // unknown motion or target not found
+	vimExclusive
+	vimInclusive
+	vimLinewise
+)
+
+// returns motion target, its kind and false if more keys are needed (like char for f)
+func (e *Editor) vimMotion(motion rune, rest []rune, count int, forOperator bool) (vimPos, vimMotionKind, bool) {
+	n := Max(count, 1)
+	p := vimPos{e.Row, e.Col}
+	line := e.Content[e.Row]
+
+	switch motion {
+	case 'h':
+		return vimPos{p.row, Max(p.col-n, 0)}, vimExclusive, true
+	case 'l':
+		limit := len(line) - 1
+		if forOperator { limit = len(line) }
+		return vimPos{p.row, Max(Min(p.col+n, limit), 0)}, vimExclusive, true
+	case 'j':
+		return vimPos{Min(p.row+n, len(e.Content)-1), p.col}, vimLinewise, true
+	case 'k':
+		return vimPos{Max(p.row-n, 0), p.col}, vimLinewise, true
+	case '0':
+		return vimPos{p.row, 0}, vimExclusive, true
+	case '^':
+		return vimPos{p.row, e.vimFirstNonBlank(p.row)}, vimExclusive, true
+	case '$':
+		row := Min(p.row+n-1, len(e.Content)-1)
+		return vimPos{row, Max(len(e.Content[row])-1, 0)}, vimInclusive, true
+	case 'w', 'W':
+		for i := 0; i < n; i++ { p = e.vimWordForward(p, motion == 'W') }
+		return p, vimExclusive, true
+	case 'b', 'B':
+		for i := 0; i < n; i++ { p = e.vimWordBackward(p, motion == 'B') }
+		return p, vimExclusive, true
+	case 'e', 'E':
+		for i := 0; i < n; i++ { p = e.vimWordEnd(p, motion == 'E') }
+		return p, vimInclusive, true
+	case 'G':
+		row := len(e.Content) - 1
+		if count > 0 { row = Min(count-1, row) }
+		return vimPos{row, e.vimFirstNonBlank(row)}, vimLinewise, true
+	case 'g':
+		if len(rest) == 0 { return p, vimNoMotion, false }
+		if rest[0] != 'g' { return p, vimNoMotion, true }
+		row := Min(n-1, len(e.Content)-1)
+		return vimPos{row, e.vimFirstNonBlank(row)}, vimLinewise, true
+	case '{', '}': // to the empty line before or after the paragraph
+		row := p.row
+		for i := 0; i < n; i++ {
+			if motion == '}' {
+				for row < len(e.Content)-1 && len(e.Content[row]) == 0 { row++ }
+				for row < len(e.Content)-1 && len(e.Content[row]) > 0 { row++ }
+			} else {
+				for row > 0 && len(e.Content[row]) == 0 { row-- }
+				for row > 0 && len(e.Content[row]) > 0 { row-- }
+			}
+		}
+		col := 0
+		if motion == '}' && len(e.Content[row]) > 0 { col = len(e.Content[row]) } // no empty line after, to the end
+		return vimPos{row, col}, vimExclusive, true
+	case '%':
+		b, found := e.cursorBracket()
+		if !found { return p, vimNoMotion, true }
+		row, col := e.Text.Position(b.Match)
+		return vimPos{row, col}, vimInclusive, true
+	case '\'', '`': // to the line or the position of a mark in this file
+		if len(rest) == 0 { return p, vimNoMotion, false }
+		e.loadMarks()
+		mark, found := e.marks.Named(string(rest[0]))
+		if !found || mark.File != e.AbsoluteFilePath || mark.Line >= len(e.Content) { return p, vimNoMotion, true }
+		if motion == '\'' { return vimPos{mark.Line, e.vimFirstNonBlank(mark.Line)}, vimLinewise, true }
+		return vimPos{mark.Line, Min(mark.Col, len(e.Content[mark.Line]))}, vimExclusive, true
+	case 'f', 'F', 't', 'T':
+		if len(rest) == 0 { return p, vimNoMotion, false }
+		col, found := vimFind(line, p.col, rest[0], n, motion == 'f' || motion == 't')
+		if !found { return p, vimNoMotion, true }
+		switch motion {
+		case 'f': return vimPos{p.row, col}, vimInclusive, true
+		case 't': return vimPos{p.row, col - 1}, vimInclusive, true
+		case 'F': return vimPos{p.row, col}, vimExclusive, true
+		default: return vimPos{p.row, col + 1}, vimExclusive, true
+		}
+	}
+	return p, vimNoMotion, true
+}
+
+// column of the count-th ch after or before col in line
+func vimFind(line []rune, col int, ch rune, count int, forward bool) (int, bool) {
+	step := 1
+	if !forward { step = -1 }
+	for i := col + step; i >= 0 && i < len(line); i += step {
+		if line[i] != ch { continue }
+		count--
+		if count == 0 { return i, true }
+	}
+	return col, false
+}
+
+// 0 blank, 1 word, 2 punctuation, 3 empty line
+func (e *Editor) vimClass(p vimPos, bigWord bool) int {
+	line := e.Content[p.row]
+	if len(line) == 0 { return 3 }
+	if p.col >= len(line) { return 0 }
+	ch := line[p.col]
+	if unicode.IsSpace(ch) { return 0 }
+	if bigWord || unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '_' { return 1 }
+	return 2
+}
+
+// next char position, empty line is one position
+func (e *Editor) vimNextPos(p vimPos) (vimPos, bool) {
+	if p.col+1 < len(e.Content[p.row]) { return vimPos{p.row, p.col + 1}, true }
+	if p.row+1 < len(e.Content) { return vimPos{p.row + 1, 0}, true }
+	return p, false
+}
+
+func (e *Editor) vimPrevPos(p vimPos) (vimPos, bool) {
+	if p.col > 0 { return vimPos{p.row, Min(p.col-1, Max(len(e.Content[p.row])-1, 0))}, true }
+	if p.row > 0 { return vimPos{p.row - 1, Max(len(e.Content[p.row-1])-1, 0)}, true }
+	return p, false
+}
+
+// start of the next word, line break separates words
+func (e *Editor) vimWordForward(p vimPos, bigWord bool) vimPos {
+	class := e.vimClass(p, bigWord)
+	for {
+		next, ok := e.vimNextPos(p)
+		if !ok { return vimPos{p.row, len(e.Content[p.row])} }
+		crossed := next.row != p.row
+		p = next
+		c := e.vimClass(p, bigWord)
+		if c != 0 && (crossed || c != class) { return p }
+		if c == 0 { class = 0 }
+	}
+}
+
+func (e *Editor) vimWordEnd(p vimPos, bigWord bool) vimPos {
+	next, ok := e.vimNextPos(p)
+	if !ok { return p }
+	p = next
+	for c := e.vimClass(p, bigWord); c == 0 || c == 3; c = e.vimClass(p, bigWord) {
+		if next, ok = e.vimNextPos(p); !ok { return p }
+		p = next
+	}
+	class := e.vimClass(p, bigWord)
+	for {
+		next, ok = e.vimNextPos(p)
+		if !ok || next.row != p.row || e.vimClass(next, bigWord) != class { return p }
+		p = next
+	}
+}
+
+func (e *Editor) vimWordBackward(p vimPos, bigWord bool) vimPos {
+	prev, ok := e.vimPrevPos(p)
+	if !ok { return p }
+	p = prev
+	for e.vimClass(p, bigWord) == 0 {
+		if prev, ok = e.vimPrevPos(p); !ok { return p }
+		p = prev
+	}
+	class := e.vimClass(p, bigWord)
+	if class == 3 { return p }
+	for {
+		prev, ok = e.vimPrevPos(p)
+		if !ok || prev.row != p.row || e.vimClass(prev, bigWord) != class { return p }
+		p = prev
+	}
+}
+
+var vimPairs = map[rune][2]rune{
+	'(': {'(', ')'}, ')': {'(', ')'}, 'b': {'(', ')'},
+	'{': {'{', '}'}, '}': {'{', '}'}, 'B': {'{', '}'},
+	'[': {'[', ']'}, ']': {'[', ']'},
+	'<': {'<', '>'}, '>': {'<', '>'},
+}
+
+// range of text object: iw aw iW aW, i( a( i{ a{ i[ a[ i< a<, i" a" i' a' i` a`
+func (e *Editor) vimTextObject(kind rune, object rune, p vimPos) (vimRange, bool) {
+	line := e.Content[p.row]
+	switch object {
+	case 'w', 'W':
+		if p.col >= len(line) { return vimRange{}, false }
+		bigWord := object == 'W'
+		class := e.vimClass(p, bigWord)
+		start, end := p.col, p.col+1
+		for start > 0 && e.vimClass(vimPos{p.row, start - 1}, bigWord) == class { start-- }
+		for end < len(line) && e.vimClass(vimPos{p.row, end}, bigWord) == class { end++ }
+		if kind == 'a' {
+			trailing := end
+			for trailing < len(line) && e.vimClass(vimPos{p.row, trailing}, bigWord) == 0 { trailing++ }
+			if trailing > end {
+				end = trailing
+			} else {
+				for start > 0 && e.vimClass(vimPos{p.row, start - 1}, bigWord) == 0 { start-- }
+			}
+		}
+		return vimRange{vimPos{p.row, start}, vimPos{p.row, end}, false}, true
+	case '"', '\'', '`':
+		quotes := []int{}
+		for i, ch := range line {
+			if ch == object && (i == 0 || line[i-1] != '\\') { quotes = append(quotes, i) }
+		}
+		for i := 0; i+1 < len(quotes); i += 2 {
+			open, close := quotes[i], quotes[i+1]
+			if p.col > close { continue }
+			if kind == 'a' { return vimRange{vimPos{p.row, open}, vimPos{p.row, close + 1}, false}, true }
+			return vimRange{vimPos{p.row, open + 1}, vimPos{p.row, close}, false}, true
+		}
+		return vimRange{}, false
+	}
+
+	pair, found := vimPairs[object]
+	if !found { return vimRange{}, false }
+	open, ok := e.vimFindUnmatched(p, pair[0], pair[1], false)
+	if !ok { return vimRange{}, false }
+	close, ok := e.vimFindUnmatched(open, pair[1], pair[0], true)
+	if !ok { return vimRange{}, false }
+
+	if kind == 'a' { return vimRange{open, vimPos{close.row, close.col + 1}, false}, true }
+	start, _ := e.vimNextPos(open)
+	if open.col+1 >= len(e.Content[open.row]) && open.row < close.row { start = vimPos{open.row + 1, 0} }
+	return vimRange{start, close, false}, true
+}
+
+// searches for ch not balanced by other, starting at p itself if it is ch
+func (e *Editor) vimFindUnmatched(p vimPos, ch, other rune, forward bool) (vimPos, bool) {
+	depth := 0
+	move := e.vimPrevPos
+	if forward { move = e.vimNextPos; p, _ = e.vimNextPos(p) }
+
+	for {
+		if line := e.Content[p.row]; p.col < len(line) {
+			switch line[p.col] {
+			case ch:
+				if depth == 0 { return p, true }
+				depth--
+			case other:
+				if !(p.row == e.Row && p.col == e.Col) || forward { depth++ }
+			}
+		}
+		next, ok := move(p)
+		if !ok { return p, false }
+		p = next
+	}
+}
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"edgo/internal/clipboard"
+	. "github.com/gdamore/tcell"
+	"testing"
+)
+
+const vimText = "foo bar.baz\n  qux quux\n\nlast line"
+
+// editor in vim normal mode with the cursor at the position
+func vimEditor(code string, row, col int) *Editor {
+	e := testEditor(code)
+	e.Config.Vim = true
+	e.Config.Save.AutoSave = "off"
+	e.clipboard = &clipboard.Clipboard{Backend: &testBackend{}}
+	e.Row, e.Col = row, col
+	return e
+}
+
+// presses keys as typed, escape is \x1b
+func vimKeys(e *Editor, keys string) {
+	for _, ch := range keys {
+		if ch == '\x1b' { e.HandleKeyboard(KeyEscape, NewEventKey(KeyEscape, 0, ModNone), ModNone); continue }
+		e.HandleKeyboard(KeyRune, NewEventKey(KeyRune, ch, ModNone), ModNone)
+	}
+}
+
+func TestVimMotions(t *testing.T) {
+	tests := []struct {
+		keys     string
+		row, col int
+		expected [2]int
+	}{
+		{"w", 0, 0, [2]int{0, 4}},
+		{"W", 0, 0, [2]int{0, 4}},
+		{"2W", 0, 0, [2]int{1, 2}},
+		{"2w", 0, 0, [2]int{0, 7}},
+		{"e", 0, 0, [2]int{0, 2}},
+		{"b", 0, 8, [2]int{0, 7}},
+		{"B", 0, 8, [2]int{0, 4}},
+		{"$", 0, 0, [2]int{0, 10}},
+		{"0", 1, 5, [2]int{1, 0}},
+		{"^", 1, 7, [2]int{1, 2}},
+		{"fz", 0, 0, [2]int{0, 10}},
+		{"tz", 0, 0, [2]int{0, 9}},
+		{"j", 0, 5, [2]int{1, 5}},
+		{"3j", 0, 0, [2]int{3, 0}},
+		{"G", 0, 0, [2]int{3, 0}},
+		{"gg", 3, 4, [2]int{0, 0}},
+		{"2G", 0, 0, [2]int{1, 2}},
+		{"}", 0, 0, [2]int{2, 0}},
+		{"{", 3, 2, [2]int{2, 0}},
+		{"2}", 0, 0, [2]int{3, 8}},
+		{"2{", 3, 2, [2]int{0, 0}},
+	}
+	for _, test := range tests {
+		e := vimEditor(vimText, test.row, test.col)
+		vimKeys(e, test.keys)
+		if [2]int{e.Row, e.Col} != test.expected { t.Errorf("%q: cursor at %d:%d, expected %v", test.keys, e.Row, e.Col, test.expected) }
+		if content(e) != vimText { t.Errorf("%q: motion changed content to %q", test.keys, content(e)) }
+	}
+}
+
+func TestVimEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     string
+		row, col int
+		expected string
+		cursor   [2]int
+	}{
+		{"delete word", "dw", 0, 0, "bar.baz\n  qux quux\n\nlast line", [2]int{0, 0}},
+		{"delete words with count", "d2w", 0, 0, ".baz\n  qux quux\n\nlast line", [2]int{0, 0}},
+		{"count before operator", "2dw", 0, 0, ".baz\n  qux quux\n\nlast line", [2]int{0, 0}},
+		{"delete word at the line end", "dw", 0, 8, "foo bar.\n  qux quux\n\nlast line", [2]int{0, 7}},
+		{"delete to the end", "d$", 0, 4, "foo \n  qux quux\n\nlast line", [2]int{0, 3}},
+		{"delete to found char", "dfz", 0, 4, "foo \n  qux quux\n\nlast line", [2]int{0, 3}},
+		{"delete line", "dd", 1, 3, "foo bar.baz\n\nlast line", [2]int{1, 0}},
+		{"delete lines with count", "2dd", 0, 0, "\nlast line", [2]int{0, 0}},
+		{"delete the last line", "dd", 3, 0, "foo bar.baz\n  qux quux\n", [2]int{2, 0}},
+		{"delete down", "dj", 0, 0, "\nlast line", [2]int{0, 0}},
+		{"delete to paragraph end", "d}", 0, 0, "\nlast line", [2]int{0, 0}},
+		{"delete to the end of the last paragraph", "d}", 3, 5, "foo bar.baz\n  qux quux\n\nlast ", [2]int{3, 4}},
+		{"change line", "ccnew\x1b", 1, 5, "foo bar.baz\nnew\n\nlast line", [2]int{1, 2}},
+		{"change word", "cwnew\x1b", 0, 4, "foo new.baz\n  qux quux\n\nlast line", [2]int{0, 6}},
+		{"change inside word", "ciwx\x1b", 0, 5, "foo x.baz\n  qux quux\n\nlast line", [2]int{0, 4}},
+		{"delete char with count", "3x", 0, 0, " bar.baz\n  qux quux\n\nlast line", [2]int{0, 0}},
+		{"replace", "2rx", 0, 0, "xxo bar.baz\n  qux quux\n\nlast line", [2]int{0, 1}},
+		{"open line below", "oab\x1b", 0, 0, "foo bar.baz\nab\n  qux quux\n\nlast line", [2]int{1, 1}},
+		{"join", "J", 0, 0, "foo bar.baz qux quux\n\nlast line", [2]int{0, 11}},
+		{"join with count", "3J", 0, 0, "foo bar.baz qux quux\nlast line", [2]int{0, 19}},
+		{"visual delete", "vlld", 0, 4, "foo .baz\n  qux quux\n\nlast line", [2]int{0, 4}},
+		{"visual change", "vecx\x1b", 0, 0, "x bar.baz\n  qux quux\n\nlast line", [2]int{0, 0}},
+		{"visual line delete", "Vjd", 0, 3, "\nlast line", [2]int{0, 0}},
+		{"visual yank and paste", "veyP", 0, 0, "foofoo bar.baz\n  qux quux\n\nlast line", [2]int{0, 2}},
+		{"paste line after", "yyp", 3, 2, "foo bar.baz\n  qux quux\n\nlast line\nlast line", [2]int{4, 0}},
+		{"paste line before", "yyP", 1, 5, "foo bar.baz\n  qux quux\n  qux quux\n\nlast line", [2]int{1, 2}},
+		{"paste lines with count", "yy2p", 2, 0, "foo bar.baz\n  qux quux\n\n\n\nlast line", [2]int{3, 0}},
+		{"swap lines", "ddp", 0, 0, "  qux quux\nfoo bar.baz\n\nlast line", [2]int{1, 0}},
+		{"paste word", "dwwP", 0, 0, "barfoo .baz\n  qux quux\n\nlast line", [2]int{0, 6}},
+		{"repeat delete", "x..", 0, 0, " bar.baz\n  qux quux\n\nlast line", [2]int{0, 0}},
+		{"repeat with count", "dw2.", 0, 0, "baz\n  qux quux\n\nlast line", [2]int{0, 0}},
+		{"repeat change", "cwab\x1bj^.", 0, 0, "ab bar.baz\n  ab quux\n\nlast line", [2]int{1, 3}},
+		{"repeat line delete", "dd.", 0, 0, "\nlast line", [2]int{0, 0}},
+	}
+	for _, test := range tests {
+		e := vimEditor(vimText, test.row, test.col)
+		vimKeys(e, test.keys)
+		if content(e) != test.expected { t.Errorf("%s %q: content %q, expected %q", test.name, test.keys, content(e), test.expected) }
+		if e.Text.String() != content(e) { t.Errorf("%s %q: text is not synced, %q", test.name, test.keys, e.Text.String()) }
+		if [2]int{e.Row, e.Col} != test.cursor { t.Errorf("%s %q: cursor at %d:%d, expected %v", test.name, test.keys, e.Row, e.Col, test.cursor) }
+		if e.vim.mode != VimNormal || len(e.vim.pending) != 0 { t.Errorf("%s %q: should end in normal mode, pending %q", test.name, test.keys, string(e.vim.pending)) }
+	}
+}
+
+func TestVimUndo(t *testing.T) {
+	e := vimEditor(vimText, 0, 0)
+	vimKeys(e, "cwnew\x1b")
+	vimKeys(e, "u")
+	if content(e) != vimText { t.Errorf("change with the typed text should be one undo step, got %q", content(e)) }
+}
+
+func TestVimEditsShiftFolds(t *testing.T) {
+	e := vimEditor("a\nb\nfunc {\n\tc\n}\nd", 0, 0)
+	e.folds, e.foldLines = []fold{{2, 4}}, 6
+
+	vimKeys(e, "dd")
+	e.fitFolds()
+	if len(e.folds) != 1 || e.folds[0] != (fold{1, 3}) { t.Fatalf("fold should move up with deleted line, got %v", e.folds) }
+
+	vimKeys(e, "yyP")
+	e.fitFolds()
+	if len(e.folds) != 1 || e.folds[0] != (fold{2, 4}) { t.Fatalf("fold should move down with pasted line, got %v", e.folds) }
+
+	vimKeys(e, "GkJ")
+	e.fitFolds()
+	if len(e.folds) != 0 { t.Errorf("joined fold should be opened, got %v", e.folds) }
+}
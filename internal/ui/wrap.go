@@ -0,0 +1,286 @@
+package ui
+
+import (
+	. "edgo/internal/highlighter"
+	. "edgo/internal/utils"
+	. "github.com/gdamore/tcell"
+	"github.com/mattn/go-runewidth"
+	"unicode/utf8"
+)
+
+/*
+	Soft wrap shows a long line on several screen rows, content is not changed.
+	Line is cut into segments by the text area width or configured column, at spaces if words are set,
+	continuation segments may be indented as the line. Scrolling is by lines with wrapTop segments
+	of the first line hidden, the view follows the cursor only when it moves, so wheel can scroll away.
+	Screen rows drawn last are kept in wrapRows for mouse, cursors and diagnostics.
+*/
+
+type wrapSegment struct {
+	start, end int // runes of the line
+	indent     int // screen columns before the segment
+}
+
+// screen row of wrapped text
+type wrapRow struct {
+	line    int
+	segment wrapSegment
+	last    bool // the last segment of the line
+}
+
+func (e *Editor) OnToggleWrap() {
+	e.softWrap = !e.softWrap
+	e.X = 0
+	e.resetWrap()
+	e.Update = true
+}
+
+// forgets scroll and moves state of the previous buffer
+func (e *Editor) resetWrap() {
+	e.wrapTop, e.wrapY, e.wrapGoal = 0, e.Y, -1
+	e.wrapCursor = [2]int{-1, -1}
+}
+
+// the widest segment in screen columns
+func (e *Editor) wrapWidth() int {
+	width := e.COLUMNS - e.LINES_WIDTH - e.FilesPanelWidth - 1 // the last column is for the cursor at the end
+	if e.Config.Wrap.Column > 0 { width = Min(width, e.Config.Wrap.Column) }
+	return Max(width, 1)
+}
+
+// screen columns of the rune, wide runes take two
+func (e *Editor) runeWidth(ch rune) int {
+	if ch == '\t' { return e.langTabWidth }
+	return Max(runewidth.RuneWidth(ch), 1)
+}
+
+func (e *Editor) wrapSegments(row int) []wrapSegment {
+	line := e.Content[row]
+	width := e.wrapWidth()
+
+	indent := 0
+	if e.Config.Wrap.Indent {
+		for _, ch := range line {
+			if ch != ' ' && ch != '\t' { break }
+			indent += e.runeWidth(ch)
+		}
+		if indent > width/2 { indent = 0 }
+	}
+
+	segments := []wrapSegment{}
+	start, x, available, segmentIndent := 0, 0, width, 0
+	for i := 0; i < len(line); i++ {
+		w := e.runeWidth(line[i])
+		if x+w > available && i > start {
+			cut := i
+			if e.Config.Wrap.Words {
+				for j := i - 1; j > start; j-- {
+					if line[j] == ' ' || line[j] == '\t' { cut = j + 1; break }
+				}
+			}
+			segments = append(segments, wrapSegment{start, cut, segmentIndent})
+			start, segmentIndent, available = cut, indent, width-indent
+			x = 0
+			for j := cut; j < i; j++ { x += e.runeWidth(line[j]) }
+		}
+		x += w
+	}
+	return append(segments, wrapSegment{start, len(line), segmentIndent})
+}
+
+// segment with the column, the end of segment belongs to the next one
+func segmentOf(segments []wrapSegment, col int) int {
+	for i := len(segments) - 1; i > 0; i-- {
+		if segments[i].start <= col { return i }
+	}
+	return 0
+}
+
+// screen column of col inside the segment, counted from the text area start
+func (e *Editor) wrapX(row int, segment wrapSegment, col int) int {
+	x := segment.indent
+	for i := segment.start; i < col && i < len(e.Content[row]); i++ { x += e.runeWidth(e.Content[row][i]) }
+	return x
+}
+
+// column of the segment shown at screen column x, not past the segment end unless it is the last one
+func (e *Editor) wrapCol(row int, segment wrapSegment, x int, last bool) int {
+	position := segment.indent
+	col := segment.start
+	for ; col < segment.end; col++ {
+		if position >= x { break }
+		position += e.runeWidth(e.Content[row][col])
+	}
+	if col == segment.end && !last && col > segment.start { col-- }
+	return col
+}
+
+// moves the cursor by screen rows, keeping its screen column
+func (e *Editor) moveWrapped(direction int) {
+	segments := e.wrapSegments(e.Row)
+	current := segmentOf(segments, e.Col)
+	x := e.wrapX(e.Row, segments[current], e.Col)
+	if e.wrapGoal >= 0 { x = e.wrapGoal }
+
+	target := current + direction
+	switch {
+	case target >= 0 && target < len(segments):
+	case direction > 0 && e.nextVisibleLine(e.Row) < len(e.Content):
+		e.Row = e.nextVisibleLine(e.Row)
+		segments = e.wrapSegments(e.Row)
+		target = 0
+	case direction < 0 && e.Row > 0:
+		e.Row = e.prevVisibleLine(e.Row)
+		segments = e.wrapSegments(e.Row)
+		target = len(segments) - 1
+	default: return
+	}
+
+	e.Col = e.wrapCol(e.Row, segments[target], x, target == len(segments)-1)
+	e.wrapGoal, e.wrapKeepGoal = x, true
+	e.Update = true
+	clear(e.HighlightElements)
+}
+
+// scrolls to show the cursor if it moved since the last draw
+func (e *Editor) fitWrapped() {
+	if e.Y != e.wrapY { e.wrapTop = 0 }
+	if e.wrapCursor != [2]int{e.Row, e.Col} {
+		if !e.wrapKeepGoal { e.wrapGoal = -1 } // moved not by rows
+		e.scrollToWrappedCursor()
+	}
+	e.wrapCursor, e.wrapY, e.wrapKeepGoal = [2]int{e.Row, e.Col}, e.Y, false
+}
+
+func (e *Editor) scrollToWrappedCursor() {
+	cursor := segmentOf(e.wrapSegments(e.Row), e.Col)
+	if e.Row < e.Y || e.Row == e.Y && cursor < e.wrapTop { e.Y, e.wrapTop = e.Row, cursor; return }
+	if e.screenRow(e.Row) >= e.ROWS { e.Y, e.wrapTop = e.linesAbove(e.Row, e.ROWS-1), 0 }
+
+	// screen rows from the top to the cursor
+	rows := cursor + 1 - e.wrapTop
+	for line := e.Y; line < e.Row; line = e.nextVisibleLine(line) { rows += len(e.wrapSegments(line)) }
+
+	topSegments := len(e.wrapSegments(e.Y))
+	for rows > e.ROWS {
+		e.wrapTop++
+		if e.wrapTop >= topSegments {
+			e.Y, e.wrapTop = e.nextVisibleLine(e.Y), 0
+			topSegments = len(e.wrapSegments(e.Y))
+		}
+		rows--
+	}
+}
+
+func (e *Editor) drawWrapped(coloredByteRanges []ColoredByteRange) {
+	e.wrapRows = e.wrapRows[:0]
+	left := e.LINES_WIDTH + e.FilesPanelWidth
+	bytesCounter := e.Text.LineStart(e.Y)
+
+	row := 0
+	for ry := e.Y; ry < len(e.Content) && row < e.ROWS; ry = e.nextVisibleLine(ry) {
+		line := e.Content[ry]
+		segments := e.wrapSegments(ry)
+		first := 0
+		if ry == e.Y { first = Min(e.wrapTop, len(segments)-1) }
+
+		for s := 0; s < first; s++ {
+			for _, ch := range line[segments[s].start:segments[s].end] { bytesCounter += utf8.RuneLen(ch) }
+		}
+
+		for s := first; s < len(segments) && row < e.ROWS; s++ {
+			segment := segments[s]
+			if s == 0 {
+				e.DrawLineNumber(ry, row)
+				if _, found := e.Tests[ry]; found { e.DrawTest(ry, row) }
+			}
+
+			x := left + segment.indent
+			for col := segment.start; col < segment.end; col++ {
+				ch := line[col]
+				style := e.charStyle(ry, col, bytesCounter, coloredByteRanges)
+				if ch == '\t' {
+					if ry == e.Row && col == e.Col { style = StyleDefault.Background(Color(AccentColor)) } // big cursor for tab
+					for i := 0; i < e.langTabWidth; i++ { e.Screen.SetContent(x+i, row, ' ', nil, style) }
+				} else {
+					e.Screen.SetContent(x, row, ch, nil, style)
+				}
+				x += e.runeWidth(ch)
+				bytesCounter += utf8.RuneLen(ch)
+			}
+
+			if s == len(segments)-1 { e.drawFoldTail(ry, x, row) }
+			e.wrapRows = append(e.wrapRows, wrapRow{ry, segment, s == len(segments)-1})
+			row++
+		}
+		bytesCounter += 1 // for '\n'
+		if next := e.nextVisibleLine(ry); next != ry+1 && next < len(e.Content) { bytesCounter = e.Text.LineStart(next) } // folded lines are skipped
+
+		for _, element := range e.HighlightElements[ry] {
+			for col := element.Ssx; col < element.Sex; col++ {
+				if e.isUnderSelection(col, ry) { break }
+				x, y, visible := e.wrapPosition(ry, col)
+				if !visible { continue }
+				mainc, _, style, _ := e.Screen.GetContent(x, y)
+				e.Screen.SetContent(x, y, mainc, nil, style.Background(Color(HighlightColor)))
+			}
+		}
+	}
+}
+
+// screen position of the content position in the last drawn wrapped rows
+func (e *Editor) wrapPosition(row, col int) (int, int, bool) {
+	found := -1
+	for i, r := range e.wrapRows {
+		if r.line == row && (r.segment.start <= col || found < 0) { found = i }
+		if r.line > row { break }
+	}
+	if found < 0 { return 0, 0, false }
+	r := e.wrapRows[found]
+	if col > r.segment.end || r.segment.start > col { return 0, 0, false } // scrolled out
+	return e.LINES_WIDTH + e.FilesPanelWidth + e.wrapX(row, r.segment, col), found, true
+}
+
+// content line and column under the mouse, mx is counted from the text area start
+func (e *Editor) wrapMousePosition(mx, my int) (int, int) {
+	if len(e.wrapRows) == 0 { return e.Row, e.Col }
+	if my >= len(e.wrapRows) {
+		r := e.wrapRows[len(e.wrapRows)-1]
+		return r.line, len(e.Content[r.line])
+	}
+	r := e.wrapRows[my]
+	return r.line, e.wrapCol(r.line, r.segment, mx, r.last)
+}
+
+// content line shown at the screen row
+func (e *Editor) screenLine(my int) int {
+	if !e.softWrap { return e.lineAtRow(my) }
+	if my >= 0 && my < len(e.wrapRows) { return e.wrapRows[my].line }
+	return len(e.Content)
+}
+
+// screen position of the cursor, popups are shown next to it
+func (e *Editor) wrappedCursor() (int, int) {
+	x, y, _ := e.wrapPosition(e.Row, e.Col)
+	return x, y
+}
+
+func (e *Editor) showWrappedCursor() {
+	x, y, visible := e.wrapPosition(e.Row, e.Col)
+	onTab := e.Col < len(e.Content[e.Row]) && e.Content[e.Row][e.Col] == '\t' // drawn as big cursor
+	if !visible || onTab { e.Screen.HideCursor(); return }
+	e.Screen.ShowCursor(x, y)
+}
+
+// diagnostic message after the last segment of the line, cut by the screen width
+func (e *Editor) drawWrappedDiagnostic(line int, message string, style Style) {
+	for i, r := range e.wrapRows {
+		if r.line != line || !r.last { continue }
+		x := e.LINES_WIDTH + e.FilesPanelWidth + e.wrapX(line, r.segment, r.segment.end) + 5
+		for _, ch := range message {
+			if x >= e.COLUMNS { break }
+			e.Screen.SetContent(x, i, ch, nil, style)
+			x++
+		}
+	}
+}
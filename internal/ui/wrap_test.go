@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// editor with soft wrap at the column
+func wrapEditor(code string, column int) *Editor {
+	e := testEditor(code)
+	e.Lang = "" // diagnostics need a language server
+	e.softWrap = true
+	e.Config.Wrap.Column = column
+	e.resetWrap()
+	return e
+}
+
+func TestWrapSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		words    bool
+		indent   bool
+		expected string
+	}{
+		{"short line", "abc", false, false, "[{0 3 0}]"},
+		{"empty line", "", false, false, "[{0 0 0}]"},
+		{"by width", "aaaa bbbb cccc", false, false, "[{0 10 0} {10 14 0}]"},
+		{"space at the width", "aaaa bbbb cccc", true, false, "[{0 10 0} {10 14 0}]"},
+		{"no space to cut at", "aaaaaaaaaaaa", true, false, "[{0 10 0} {10 12 0}]"},
+		{"by words", "aaaa bbbbbb cc", true, false, "[{0 5 0} {5 14 0}]"},
+		{"exact width", "aaaaaaaaaa", false, false, "[{0 10 0}]"},
+		{"tabs", "\t\tabcd", false, false, "[{0 4 0} {4 6 0}]"},
+		{"wide runes", "日本語日本語", false, false, "[{0 5 0} {5 6 0}]"},
+		{"wide rune does not fit", "abcdefghi日本", false, false, "[{0 9 0} {9 11 0}]"},
+		{"indented", "  aaaaaaaaaaaaa", false, true, "[{0 10 0} {10 15 2}]"},
+		{"indented continuations", "  aaaaaaaaaaaaaaaaaaaa", false, true, "[{0 10 0} {10 18 2} {18 22 2}]"},
+	}
+	for _, test := range tests {
+		e := wrapEditor(test.line, 10)
+		e.Config.Wrap.Words, e.Config.Wrap.Indent = test.words, test.indent
+		if segments := fmt.Sprint(e.wrapSegments(0)); segments != test.expected { t.Errorf("%s: segments %s, expected %s", test.name, segments, test.expected) }
+	}
+}
+
+func TestWrappedCursor(t *testing.T) {
+	e := wrapEditor("short\n"+strings.Repeat("a", 25)+"\n日本語日本語\nend", 10)
+	left := e.LINES_WIDTH
+
+	tests := []struct {
+		name     string
+		row, col int
+		x, y     int
+	}{
+		{"first line", 0, 2, 2, 0},
+		{"the first segment", 1, 3, 3, 1},
+		{"segment start belongs to the next segment", 1, 10, 0, 2},
+		{"the last segment", 1, 22, 2, 3},
+		{"end of the line on the last segment", 1, 25, 5, 3},
+		{"wide runes", 2, 2, 4, 4},
+		{"wide runes on the next segment", 2, 5, 0, 5},
+		{"line after wrapped lines", 3, 1, 1, 6},
+	}
+	for _, test := range tests {
+		e.Row, e.Col = test.row, test.col
+		e.DrawEverything()
+		x, y, visible := e.wrapPosition(test.row, test.col)
+		if !visible || x != left+test.x || y != test.y { t.Errorf("%s: cursor at %d:%d visible %v, expected %d:%d", test.name, x-left, y, visible, test.x, test.y) }
+		if line := e.screenLine(y); line != test.row { t.Errorf("%s: screen row %d shows line %d", test.name, y, line) }
+	}
+
+	// mouse position maps back, the end of the last segment is the line end
+	if row, col := e.wrapMousePosition(8, 3); row != 1 || col != 25 { t.Errorf("mouse after the last segment: %d:%d, expected 1:25", row, col) }
+	if row, col := e.wrapMousePosition(20, 1); row != 1 || col != 9 { t.Errorf("mouse after a middle segment: %d:%d, expected 1:9", row, col) }
+}
+
+func TestMoveWrapped(t *testing.T) {
+	e := wrapEditor(strings.Repeat("a", 25)+"\nabc", 10)
+	e.Row, e.Col = 0, 4
+
+	for i, expected := range [][2]int{{0, 14}, {0, 24}, {1, 3}} {
+		e.moveWrapped(1)
+		if [2]int{e.Row, e.Col} != expected { t.Errorf("down %d: cursor at %d:%d, expected %v", i+1, e.Row, e.Col, expected) }
+	}
+	e.moveWrapped(-1)
+	if e.Row != 0 || e.Col != 24 { t.Errorf("up to the last segment: cursor at %d:%d, expected 0:24", e.Row, e.Col) }
+}
+
+func TestScrollToWrappedCursor(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < 10; i++ { lines = append(lines, strings.Repeat("a", 25)) } // 3 screen rows each
+	e := wrapEditor(strings.Join(lines, "\n"), 10)
+
+	// the cursor on the last segment of a line below the screen becomes the last screen row
+	e.Row, e.Col = 5, 22
+	e.DrawEverything()
+	_, y, visible := e.wrapPosition(e.Row, e.Col)
+	if !visible || y != e.ROWS-1 { t.Errorf("cursor should be on the last screen row, got %d visible %v", y, visible) }
+	if e.Y != 2 || e.wrapTop != 0 { t.Errorf("top should be line 2 without hidden segments, got %d and %d", e.Y, e.wrapTop) }
+
+	e.Row, e.Col = 6, 0
+	e.DrawEverything()
+	if _, y, _ := e.wrapPosition(e.Row, e.Col); y != e.ROWS-1 || e.Y != 2 || e.wrapTop != 1 { t.Errorf("one row scroll hides a segment of the top line, got row %d top %d:%d", y, e.Y, e.wrapTop) }
+}
@@ -0,0 +1,163 @@
+package undo
+
+import (
+	. "edgo/internal/operations"
+	"time"
+)
+
+/*
+	Tree keeps every state of the buffer. Each node is the state after its operations
+	applied to the parent state, the root is the state of the opened file.
+	Undo goes to the parent, redo goes to the child visited last,
+	a new edit after undo starts a new branch, so the undone states are still reachable.
+	Nodes are kept in creation order, the node index is its number in time.
+*/
+
+type Node struct {
+	Ops      EditOperation
+	Parent   int
+	Children []int
+	Redo     int // child followed by redo, -1 if none
+	Time     time.Time
+}
+
+type Tree struct {
+	Nodes   []Node
+	Current int
+}
+
+func New() *Tree {
+	return &Tree{Nodes: []Node{{Parent: -1, Redo: -1, Time: time.Now()}}}
+}
+
+// Push adds a new state after the current one
+func (t *Tree) Push(ops EditOperation) {
+	t.Nodes = append(t.Nodes, Node{Ops: ops, Parent: t.Current, Redo: -1, Time: time.Now()})
+	node := len(t.Nodes) - 1
+	parent := &t.Nodes[t.Current]
+	parent.Children = append(parent.Children, node)
+	parent.Redo = node
+	t.Current = node
+}
+
+// Undo returns operations to revert and moves to the parent state
+func (t *Tree) Undo() (EditOperation, bool) {
+	if t.Current == 0 { return nil, false }
+	node := t.Nodes[t.Current]
+	t.Nodes[node.Parent].Redo = t.Current
+	t.Current = node.Parent
+	return node.Ops, true
+}
+
+// Redo returns operations to apply and moves to the last visited child state
+func (t *Tree) Redo() (EditOperation, bool) {
+	next := t.Nodes[t.Current].Redo
+	if next < 0 { return nil, false }
+	t.Current = next
+	return t.Nodes[next].Ops, true
+}
+
+func (t *Tree) CanUndo() bool { return t.Current != 0 }
+func (t *Tree) CanRedo() bool { return t.Nodes[t.Current].Redo >= 0 }
+
+// Since returns operations of states from mark to the current one, mark must be its ancestor
+func (t *Tree) Since(mark int) []EditOperation {
+	path := t.pathFrom(mark, t.Current)
+	ops := make([]EditOperation, len(path))
+	for i, node := range path { ops[i] = t.Nodes[node].Ops }
+	return ops
+}
+
+// MergeSince replaces states created after mark by one state, so they are undone at once.
+// Nothing is merged if some of them were undone and branched meanwhile
+func (t *Tree) MergeSince(mark int) {
+	path := t.pathFrom(mark, t.Current)
+	if len(path) < 2 { return }
+
+	// the states have to be the latest ones, one after another
+	merged := EditOperation{}
+	for i, node := range path {
+		if node != len(t.Nodes)-len(path)+i { return }
+		merged = append(merged, t.Nodes[node].Ops...)
+	}
+
+	first := t.Nodes[path[0]]
+	t.Nodes = t.Nodes[:path[0]]
+	parent := &t.Nodes[mark]
+	parent.Children = parent.Children[:len(parent.Children)-1]
+	t.Current = mark
+	t.Push(merged)
+	t.Nodes[t.Current].Time = first.Time
+}
+
+// Route returns operations to undo and to redo, in order, to get from the current state to node
+func (t *Tree) Route(node int) (undo []EditOperation, redo []EditOperation) {
+	common := t.commonAncestor(t.Current, node)
+	for n := t.Current; n != common; n = t.Nodes[n].Parent { undo = append(undo, t.Nodes[n].Ops) }
+	for _, n := range t.pathFrom(common, node) { redo = append(redo, t.Nodes[n].Ops) }
+	return undo, redo
+}
+
+// Goto makes node current, its operations has to be applied with Route before,
+// redo from every state on the way follows the way
+func (t *Tree) Goto(node int) {
+	for n := node; t.Nodes[n].Parent >= 0; n = t.Nodes[n].Parent { t.Nodes[t.Nodes[n].Parent].Redo = n }
+	t.Current = node
+}
+
+// AtTime returns the last state created not later than time, the root if there is no such state
+func (t *Tree) AtTime(at time.Time) int {
+	for i := len(t.Nodes) - 1; i > 0; i-- {
+		if !t.Nodes[i].Time.After(at) { return i }
+	}
+	return 0
+}
+
+func (t *Tree) Depth(node int) int {
+	depth := 0
+	for n := node; n != 0; n = t.Nodes[n].Parent { depth++ }
+	return depth
+}
+
+// Prune keeps about max latest states together with the way from the current state to the new root,
+// the new root is the latest ancestor of the current state created before them
+func (t *Tree) Prune(max int) {
+	if len(t.Nodes) <= max+1 { return }
+	cut := len(t.Nodes) - max
+
+	root := t.Current
+	for root >= cut { root = t.Nodes[root].Parent }
+	onPath := map[int]bool{}
+	for n := t.Current; n != root; n = t.Nodes[n].Parent { onPath[n] = true }
+
+	index := map[int]int{root: 0}
+	nodes := []Node{{Parent: -1, Redo: -1, Time: t.Nodes[root].Time}}
+	for n := root + 1; n < len(t.Nodes); n++ {
+		parent, kept := index[t.Nodes[n].Parent]
+		if !kept || n < cut && !onPath[n] { continue }
+		index[n] = len(nodes)
+		nodes = append(nodes, Node{Ops: t.Nodes[n].Ops, Parent: parent, Redo: -1, Time: t.Nodes[n].Time})
+		nodes[parent].Children = append(nodes[parent].Children, index[n])
+	}
+
+	for old, n := range index {
+		if redo, kept := index[t.Nodes[old].Redo]; kept { nodes[n].Redo = redo }
+	}
+	t.Nodes, t.Current = nodes, index[t.Current]
+}
+
+// nodes on the way from ancestor (excluded) down to node
+func (t *Tree) pathFrom(ancestor, node int) []int {
+	path := []int{}
+	for n := node; n != ancestor && n >= 0; n = t.Nodes[n].Parent { path = append([]int{n}, path...) }
+	return path
+}
+
+func (t *Tree) commonAncestor(a, b int) int {
+	ancestors := map[int]bool{}
+	for n := a; n >= 0; n = t.Nodes[n].Parent { ancestors[n] = true }
+	for n := b; n >= 0; n = t.Nodes[n].Parent {
+		if ancestors[n] { return n }
+	}
+	return 0
+}
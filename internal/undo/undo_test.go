@@ -0,0 +1,91 @@
+package undo
+
+import (
+	. "edgo/internal/operations"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func op(ch rune) EditOperation { return EditOperation{{Insert, ch, 0, 0}} }
+
+func TestUndoRedo(t *testing.T) {
+	tree := New()
+	tree.Push(op('a'))
+	tree.Push(op('b'))
+
+	ops, ok := tree.Undo()
+	if !ok || ops[0].Char != 'b' { t.Errorf("expected undo of b, got %v", ops) }
+	ops, ok = tree.Redo()
+	if !ok || ops[0].Char != 'b' { t.Errorf("expected redo of b, got %v", ops) }
+	if _, ok = tree.Redo(); ok { t.Errorf("nothing to redo") }
+
+	tree.Undo(); tree.Undo()
+	if _, ok = tree.Undo(); ok { t.Errorf("nothing to undo at root") }
+}
+
+func TestBranch(t *testing.T) {
+	tree := New()
+	tree.Push(op('a'))
+	tree.Push(op('b'))
+	tree.Undo()
+	tree.Push(op('c')) // b is not lost, it is another branch
+
+	if len(tree.Nodes) != 4 || !reflect.DeepEqual(tree.Nodes[1].Children, []int{2, 3}) {
+		t.Fatalf("expected branch after a, got %+v", tree.Nodes)
+	}
+	if tree.CanRedo() { t.Errorf("new branch has nothing to redo") }
+
+	undo, redo := tree.Route(2)
+	if len(undo) != 1 || undo[0][0].Char != 'c' || len(redo) != 1 || redo[0][0].Char != 'b' {
+		t.Errorf("route to b should undo c and redo b, got %v %v", undo, redo)
+	}
+	tree.Goto(2)
+	tree.Undo()
+	if ops, _ := tree.Redo(); ops[0].Char != 'b' { t.Errorf("redo should follow the last visited branch") }
+}
+
+func TestMergeSince(t *testing.T) {
+	tree := New()
+	tree.Push(op('a'))
+	mark := tree.Current
+	tree.Push(op('b'))
+	tree.Push(op('c'))
+
+	if since := tree.Since(mark); len(since) != 2 { t.Errorf("expected 2 states since mark, got %v", since) }
+	tree.MergeSince(mark)
+
+	if len(tree.Nodes) != 3 || len(tree.Nodes[tree.Current].Ops) != 2 || len(tree.Nodes[mark].Children) != 1 {
+		t.Errorf("b and c should be one state, got %+v", tree.Nodes)
+	}
+	tree.Undo()
+	if tree.Current != mark { t.Errorf("merged state should be undone at once") }
+}
+
+func TestAtTime(t *testing.T) {
+	tree := New()
+	tree.Push(op('a'))
+	tree.Push(op('b'))
+	now := time.Now()
+	tree.Nodes[1].Time = now.Add(-10 * time.Minute)
+	tree.Nodes[2].Time = now.Add(-1 * time.Minute)
+
+	if n := tree.AtTime(now.Add(-5 * time.Minute)); n != 1 { t.Errorf("expected state a, got %d", n) }
+	if n := tree.AtTime(now.Add(-time.Hour)); n != 0 { t.Errorf("expected root, got %d", n) }
+}
+
+func TestPrune(t *testing.T) {
+	tree := New()
+	for i := 0; i < 10; i++ { tree.Push(op(rune('a' + i))) }
+	tree.Undo(); tree.Undo()
+	tree.Push(op('x')) // branch from h
+
+	tree.Prune(4)
+
+	if len(tree.Nodes) != 5 { t.Fatalf("expected 4 states and root, got %d", len(tree.Nodes)) }
+	if tree.Nodes[tree.Current].Ops[0].Char != 'x' || tree.Depth(tree.Current) != 2 {
+		t.Errorf("current state should be kept with its way, got %+v", tree.Nodes)
+	}
+	undo, redo := tree.Route(3)
+	if len(undo) != 1 || len(redo) != 2 || redo[1][0].Char != 'j' { t.Errorf("branch i j should be reachable, got %v %v", undo, redo) }
+}
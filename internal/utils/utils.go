@@ -359,4 +359,32 @@ func RemoveLeadingTabsSpaces(s string) string {
 		}
 	}
 	return ""
-}
\ No newline at end of file
+}
+
+// FuzzyMatch checks that all pattern characters appear in str in the same order (case-insensitive).
+// Returns a score, higher is better: consecutive matches and matches at word beginnings are preferred.
+func FuzzyMatch(str string, pattern string) (int, bool) {
+	if len(pattern) == 0 { return 0, true }
+
+	source := []rune(strings.ToLower(str))
+	search := []rune(strings.ToLower(pattern))
+
+	score := 0
+	prevMatch := -2
+	j := 0
+	for i := 0; i < len(source) && j < len(search); i++ {
+		if source[i] != search[j] { continue }
+
+		score += 10
+		if prevMatch == i-1 { score += 20 } // consecutive
+		if i == 0 || Contains(matched, source[i-1]) || source[i-1] == '_' { score += 10 } // word beginning
+
+		prevMatch = i
+		j++
+	}
+
+	if j != len(search) { return 0, false }
+
+	score -= len(source) // shorter strings are better
+	return score, true
+}
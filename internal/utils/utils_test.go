@@ -111,3 +111,20 @@ func TestDetectPythonLang(t *testing.T) {
 //		t.Errorf("GetSelectionString() =\ngot=%s \nwant= %s", got, want)
 //	}
 //}
+
+func TestFuzzyMatch(t *testing.T) {
+	_, ok := FuzzyMatch("internal/ui/editor.go", "uied")
+	if !ok { t.Error("uied must match internal/ui/editor.go") }
+
+	_, ok = FuzzyMatch("editor.go", "xyz")
+	if ok { t.Error("xyz must not match editor.go") }
+
+	_, ok = FuzzyMatch("editor.go", "")
+	if !ok { t.Error("empty pattern must match everything") }
+
+	consecutive, _ := FuzzyMatch("actions.go", "act")
+	scattered, _ := FuzzyMatch("a_cool_thing.go", "act")
+	if consecutive <= scattered {
+		t.Errorf("consecutive match must have bigger score, got %d <= %d", consecutive, scattered)
+	}
+}